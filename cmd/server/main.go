@@ -1,87 +1,230 @@
 package main
 
 import (
+	"context"
 	"cursach/internal/config"
 	"cursach/internal/database"
 	"cursach/internal/handlers"
 	wbs "cursach/internal/handlers/chat"
+	"cursach/internal/handlers/health"
+	"cursach/internal/handlers/openapi"
+	"cursach/internal/pkg/auth"
+	"cursach/internal/pkg/logger"
+	"cursach/internal/pkg/metrics"
+	"cursach/internal/pkg/ratelimit"
+	"cursach/internal/pkg/tracing"
 	"cursach/internal/repository"
 	"cursach/internal/server"
+	"cursach/internal/usecase/admin"
+	"cursach/internal/usecase/audit"
 	"cursach/internal/usecase/chat"
 	"cursach/internal/usecase/message"
 	"cursach/internal/usecase/user"
+	"errors"
+	"flag"
+	"fmt"
 	"github.com/joho/godotenv"
-	"log"
+	"log/slog"
+	"os"
+	"time"
 )
 
 func main() {
-	// Загружаем .env файл перед запуском приложения
-	if err := godotenv.Load(); err != nil {
-		log.Fatal("Error loading .env file: ", err)
+	configPath := flag.String("config", "", "path to YAML config file (environment variables override it)")
+	flag.Parse()
+
+	// Файл .env необязателен: переменные могут быть заданы окружением или файлом конфигурации
+	if err := godotenv.Load(); err != nil && !errors.Is(err, os.ErrNotExist) {
+		fatal("Error loading .env file", err)
+	}
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		fatal("Failed to load configuration", err)
+	}
+
+	l, err := logger.New(cfg.Log, os.Stderr)
+	if err != nil {
+		fatal("Failed to configure logger", err)
 	}
+	slog.SetDefault(l)
 
-	cfg, err := config.LoadConfig()
+	// Трассировка настраивается до подключения к БД, чтобы запросы репозиториев попадали в трассы
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing, os.Stdout)
 	if err != nil {
-		log.Fatalf("Failed to load configuration: %v", err)
+		fatal("Failed to configure tracing", err)
+	}
+
+	if cfg.Database.SSLMode == "disable" {
+		slog.Warn("SSL is disabled - not recommended for production", "sslmode", cfg.Database.SSLMode)
 	}
 
 	// Подключение к базе данных
 	adminDB, err := database.New(cfg.Database, true)
 	if err != nil {
-		log.Fatalf("Admin DB connection failed: %v", err)
+		fatal("Admin DB connection failed", err)
 	}
-	defer adminDB.Close()
 
-	if err := adminDB.InitSchema(); err != nil {
-		log.Fatalf("Schema initialization failed: %v", err)
+	// Роли создаются до миграций, так как миграции выдают им права
+	if cfg.Database.ProvisionRoles {
+		provisionCtx, cancelProvision := context.WithTimeout(context.Background(), 10*time.Second)
+		err := adminDB.ProvisionRoles(provisionCtx, cfg.Database.Roles)
+		cancelProvision()
+		if err != nil {
+			fatal("Database role provisioning failed", err)
+		}
 	}
 
+	// Подкоманда migrate выполняет миграции и завершает процесс
+	if flag.Arg(0) == "migrate" {
+		if err := runMigrate(adminDB, flag.Args()[1:]); err != nil {
+			fatal("Migration failed", err)
+		}
+		adminDB.Close()
+		return
+	}
+
+	// Приложение не стартует на схеме, которую не удалось привести к актуальной версии
+	migrateCtx, cancelMigrate := context.WithTimeout(context.Background(), migrateTimeout)
+	applied, err := adminDB.MigrateUp(migrateCtx)
+	if err == nil {
+		err = adminDB.ApplyGrants(migrateCtx)
+	}
+	cancelMigrate()
+	if err != nil {
+		fatal("Database migration failed", err)
+	}
+	slog.Info("Database schema is up to date", "applied", applied)
+
+	// Административное подключение нужно только для подготовки схемы: приложение работает под ограниченной ролью
+	adminDB.Close()
+
 	// Подключение для обычных операций
 	userDB, err := database.New(cfg.Database, false)
 	if err != nil {
-		log.Fatalf("User DB connection failed: %v", err)
+		fatal("User DB connection failed", err)
 	}
 	defer userDB.Close()
 
-	log.Println("Database connection established")
-
-	// Инициализация схемы БД
-	if err := userDB.InitSchema(); err != nil {
-		log.Printf("Warning: schema initialization: %v", err)
+	// Все репозитории работают через это подключение под ролью messenger_user (отдельных ролей для
+	// репозиториев нет), поэтому ее прав должно хватать для всей карты доступа database.RepositoryAccessMap
+	verifyCtx, cancelVerify := context.WithTimeout(context.Background(), 10*time.Second)
+	err = userDB.VerifyPrivileges(verifyCtx, config.DBRoleUser)
+	cancelVerify()
+	if err != nil {
+		fatal("Database privilege check failed", err)
 	}
 
+	slog.Info("Database connection established")
+	metrics.RegisterDBStats(userDB.DB)
+
 	// Инициализация репозиториев
 	chatRepo := repository.NewChatRepository(userDB.DB)
 	userRepo := repository.NewUserRepository(userDB.DB)
 	tokenRepo := repository.NewTokenRepository(userDB.DB)
-	messageRepo := repository.NewMessageRepository(adminDB.DB)
+	mfaRepo := repository.NewMFARepository(userDB.DB)
+	auditRepo := repository.NewAuditRepository(userDB.DB)
+	messageRepo := repository.NewMessageRepository(userDB.DB)
+	wsTicketRepo := repository.NewWSTicketRepository(userDB.DB)
+
+	// Хранилище попыток входа: in-memory для одного экземпляра, PostgreSQL для нескольких
+	loginAttemptRepo := repository.NewMemoryLoginAttemptRepository()
+	if cfg.LoginLimit.Store == "postgres" {
+		loginAttemptRepo = repository.NewLoginAttemptRepository(userDB.DB)
+	}
 
 	// Конфигурация аутентификации
-	salt := cfg.Auth.Salt
-	jwtSecret := cfg.Auth.JWTSecret
+	salt := cfg.Auth.Salt.Value()
+	jwtKeys, err := auth.LoadKeySet(
+		cfg.Auth.JWTKeysDir,
+		cfg.Auth.JWTActiveKeyID,
+		cfg.Auth.JWTSecret.Value(),
+		cfg.Auth.JWTHS256Until,
+	)
+	if err != nil {
+		fatal("Failed to load JWT keys", err)
+	}
+	if cfg.Auth.JWTKeysDir == "" {
+		slog.Warn("JWT_KEYS_DIR is not set, tokens are signed with the HS256 shared secret")
+	}
 
 	// Инициализация use cases
+	auditRecorder := audit.NewRecorder(auditRepo)
+	auditReader := audit.NewEventReader(auditRepo)
 	chatCreator := chat.NewChatCreator(chatRepo, userRepo)
-	chatDeleter := chat.NewChatDeleter(chatRepo)
+	chatDeleter := chat.NewChatDeleter(chatRepo, auditRecorder)
 	chatLister := chat.NewChatLister(chatRepo)
 	userManager := user.NewUserManager(userRepo, salt)
-	userDeleter := user.NewUserDeleter(userRepo)
+	userDeleter := user.NewUserDeleter(userRepo, tokenRepo, auditRecorder)
 	userSearcher := user.NewUserSearcher(userRepo)
-	authUC := user.NewAuthenticator(userRepo, salt)
-	logoutUC := user.NewLogouter(tokenRepo)
-	loginUpdater := user.NewLoginUpdater(userRepo)
+	authUC := user.NewAuthenticator(userRepo, mfaRepo, salt, auditRecorder)
+	loginGuard := user.NewLoginGuard(loginAttemptRepo, cfg.LoginLimit)
+	mfaUC := user.NewMFAManager(mfaRepo, userRepo, auditRecorder, salt, cfg.Auth.MFAIssuer)
+	logoutUC := user.NewLogouter(tokenRepo, auditRecorder)
+	loginUpdater := user.NewLoginUpdater(userRepo, auditRecorder)
 	messageUC := message.NewSender(chatRepo, messageRepo)
+	wsTicketUC := user.NewWSTicketManager(wsTicketRepo, tokenRepo, cfg.Auth.WSTicketTTL)
+
+	apiSpec, err := openapi.Load()
+	if err != nil {
+		fatal("Failed to load OpenAPI specification", err)
+	}
+
+	// Ограничение частоты запросов: правила маршрутов задаются по operationId из спецификации
+	var limiter *ratelimit.Limiter
+	if cfg.RateLimit.Enabled {
+		rules := map[string]ratelimit.Rule{
+			wbs.MessageRateLimit: {Rate: cfg.RateLimit.WebSocketMessages.RequestsPerSecond, Burst: cfg.RateLimit.WebSocketMessages.Burst},
+		}
+		for id, rule := range cfg.RateLimit.Routes {
+			if !apiSpec.HasOperation(id) {
+				fatal("Invalid rate limit configuration", fmt.Errorf("rate_limit.routes: unknown operationId %q", id))
+			}
+			rules[id] = ratelimit.Rule{Rate: rule.RequestsPerSecond, Burst: rule.Burst}
+		}
+		limiter = ratelimit.New(ratelimit.NewMemoryStore(),
+			ratelimit.Rule{Rate: cfg.RateLimit.RequestsPerSecond, Burst: cfg.RateLimit.Burst}, rules)
+	}
+
+	// Прокси, которым доверяется адрес клиента из X-Forwarded-For и X-Real-IP
+	proxyPolicy, err := server.NewProxyPolicy(cfg.Server.TrustedProxies)
+	if err != nil {
+		fatal("Invalid trusted proxies", err)
+	}
+
+	// Разрешенные origin: общие для CORS и подключений к WebSocket
+	originPolicy := server.NewOriginPolicy(cfg.CORS)
+	if cfg.CORS.DevMode {
+		slog.Warn("CORS dev mode is enabled, localhost origins are allowed")
+	}
 
 	// WebSocket Handler
 	wsHandler := wbs.NewWSHandler(
-		jwtSecret,
+		jwtKeys,
 		tokenRepo,
 		chatRepo,
 		userRepo,
 		messageRepo,
 		messageUC,
+		wsTicketUC,
+		cfg.Auth.WSQueryToken,
+		originPolicy,
+		limiter,
 	)
 
+	// Пробы для оркестратора и балансировщика: готовность зависит от БД, версии схемы и состояния WebSocket
+	healthHandler := health.NewHandler(map[string]health.Check{
+		"database": userDB.PingContext,
+		"migrations": func(ctx context.Context) error {
+			_, err := userDB.CheckSchemaVersion(ctx)
+			return err
+		},
+		"websocket": wsHandler.Ready,
+	})
+
+	// Администрирование пользователей (закрывает WebSocket-соединения через wsHandler)
+	userAdmin := admin.NewUserAdministrator(userRepo, chatRepo, tokenRepo, wsHandler, auditRecorder)
+
 	// Настройка маршрутов
 	router := handlers.SetupRouter(
 		chatCreator,
@@ -89,15 +232,53 @@ func main() {
 		userManager,
 		userDeleter,
 		authUC,
-		jwtSecret,
+		loginGuard,
+		mfaUC,
+		jwtKeys,
+		cfg.Auth.JWTExpiry,
 		tokenRepo,
 		logoutUC,
 		loginUpdater,
 		wsHandler,
+		wsTicketUC,
 		chatLister,
 		userSearcher,
+		userAdmin,
+		auditReader,
+		healthHandler,
+		apiSpec,
+		limiter,
+		proxyPolicy,
 	)
+	if err := apiSpec.VerifyRoutes(router); err != nil {
+		fatal("Routes do not match the OpenAPI specification", err)
+	}
 
 	// Запуск сервера
-	server.Start(router, ":8080")
+	srv, err := server.New(server.CORSMiddleware(cfg.CORS, originPolicy)(router), cfg.Server)
+	if err != nil {
+		fatal("Server setup failed", err)
+	}
+	// Сначала /readyz начинает отвечать 503, чтобы балансировщик перестал направлять трафик
+	srv.RegisterOnDrain(healthHandler.SetDraining)
+	// WebSocket-соединения не отслеживаются http.Server, поэтому закрываются отдельно
+	srv.RegisterOnShutdown(wsHandler.Shutdown)
+	runErr := srv.Run()
+
+	// Спаны, накопленные за время остановки, отправляются после завершения всех соединений
+	flushCtx, cancelFlush := context.WithTimeout(context.Background(), 5*time.Second)
+	if err := shutdownTracing(flushCtx); err != nil {
+		slog.Error("Failed to flush traces", "error", err)
+	}
+	cancelFlush()
+
+	if runErr != nil {
+		fatal("Server stopped with error", runErr)
+	}
+}
+
+// fatal логирует ошибку запуска и завершает процесс
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
 }
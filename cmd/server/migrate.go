@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"cursach/internal/database"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+// migrateTimeout - общее ограничение времени на выполнение команды migrate
+const migrateTimeout = 5 * time.Minute
+
+// runMigrate выполняет подкоманду migrate: up, down [N] или status
+func runMigrate(db *database.DB, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: migrate up | down [N] | status")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), migrateTimeout)
+	defer cancel()
+
+	switch args[0] {
+	case "up":
+		applied, err := db.MigrateUp(ctx)
+		if err != nil {
+			return err
+		}
+		if err := db.ApplyGrants(ctx); err != nil {
+			return err
+		}
+		fmt.Printf("Applied %d migration(s)\n", applied)
+		return nil
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				return fmt.Errorf("invalid number of steps: %s", args[1])
+			}
+			steps = n
+		}
+		rolledBack, err := db.MigrateDown(ctx, steps)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Rolled back %d migration(s)\n", rolledBack)
+		return nil
+
+	case "status":
+		statuses, err := db.MigrationStatus(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+		for _, s := range statuses {
+			status, appliedAt := "pending", ""
+			if s.Applied {
+				status = "applied"
+				appliedAt = s.AppliedAt.Time.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\t%s\n", s.Version, s.Name, status, appliedAt)
+		}
+		return w.Flush()
+
+	default:
+		return fmt.Errorf("unknown migrate command %q", args[0])
+	}
+}
@@ -3,10 +3,11 @@ package config
 import (
 	"errors"
 	"fmt"
+	"log/slog"
 	"os"
-	"strconv"
-	"strings"
 	"time"
+
+	"gopkg.in/yaml.v3"
 )
 
 var (
@@ -15,132 +16,317 @@ var (
 
 // Config - корневая структура конфигурации приложения
 type Config struct {
-	Database DatabaseConfig
-	Auth     AuthConfig
+	Log        LogConfig        `yaml:"log"`
+	Tracing    TracingConfig    `yaml:"tracing"`
+	Server     ServerConfig     `yaml:"server"`
+	Database   DatabaseConfig   `yaml:"database"`
+	Auth       AuthConfig       `yaml:"auth"`
+	LoginLimit LoginLimitConfig `yaml:"login_limit"`
+	CORS       CORSConfig       `yaml:"cors"`
+	RateLimit  RateLimitConfig  `yaml:"rate_limit"`
+}
+
+// LogConfig - параметры логирования
+type LogConfig struct {
+	Level  string `yaml:"level"`  // debug, info, warn, error
+	Format string `yaml:"format"` // json или text
+}
+
+// TracingConfig - параметры трассировки OpenTelemetry
+type TracingConfig struct {
+	Exporter    string  `yaml:"exporter"`     // none, otlp или stdout (для локальной отладки)
+	Endpoint    string  `yaml:"endpoint"`     // Адрес OTLP/HTTP коллектора host:port (пусто - из OTEL_EXPORTER_OTLP_ENDPOINT)
+	Insecure    bool    `yaml:"insecure"`     // OTLP без TLS
+	ServiceName string  `yaml:"service_name"` // Имя сервиса в трассах
+	SampleRatio float64 `yaml:"sample_ratio"` // Доля трасс, начинаемых этим сервисом (0..1)
+}
+
+// ServerConfig - параметры HTTP сервера
+type ServerConfig struct {
+	Addr              string        `yaml:"addr"`
+	ReadTimeout       time.Duration `yaml:"read_timeout"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout"`
+	WriteTimeout      time.Duration `yaml:"write_timeout"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout"`
+	ShutdownTimeout   time.Duration `yaml:"shutdown_timeout"` // Сколько ждать завершения активных запросов при остановке
+	DrainDelay        time.Duration `yaml:"drain_delay"`      // Сколько отвечать not-ready на /readyz перед закрытием слушателей
+
+	UnixSocket string          `yaml:"unix_socket"` // Дополнительный слушатель для локального обратного прокси (без TLS)
+	TLS        ServerTLSConfig `yaml:"tls"`
+
+	// Обратные прокси (CIDR, IP или unix - соединения через unix_socket), от которых принимается адрес клиента
+	// в X-Forwarded-For и X-Real-IP; пусто - адрес клиента берется только из соединения
+	TrustedProxies []string `yaml:"trusted_proxies"`
+}
+
+// ServerTLSConfig - параметры HTTPS
+type ServerTLSConfig struct {
+	CertFile       string        `yaml:"cert_file"`
+	KeyFile        string        `yaml:"key_file"`
+	ReloadInterval time.Duration `yaml:"reload_interval"` // Как часто проверять файлы сертификата на изменение
+	RedirectAddr   string        `yaml:"redirect_addr"`   // Адрес HTTP сервера, перенаправляющего на HTTPS (пусто - выключен)
+}
+
+// Enabled сообщает, включен ли HTTPS
+func (c ServerTLSConfig) Enabled() bool {
+	return c.CertFile != "" || c.KeyFile != ""
 }
 
+// Роли PostgreSQL, на которые ссылаются миграции
+const (
+	DBRoleAdmin = "messenger_admin"
+	DBRoleUser  = "messenger_user"
+)
+
 // DatabaseConfig - параметры подключения к БД
 type DatabaseConfig struct {
 	Host          string `yaml:"host"`
 	Port          int    `yaml:"port"`
 	User          string `yaml:"user"`
-	Password      string `yaml:"password"`
+	Password      Secret `yaml:"password"`
 	AdminUser     string `yaml:"admin_user"`
-	AdminPassword string `yaml:"admin_password"`
+	AdminPassword Secret `yaml:"admin_password"`
 	DBName        string `yaml:"dbname"`
 	SSLMode       string `yaml:"sslmode"`
+
+	Pool PoolConfig `yaml:"pool"`
+
+	ProvisionRoles bool           `yaml:"provision_roles"` // Создавать роли при запуске от имени AdminUser
+	Roles          []DBRoleConfig `yaml:"roles"`
 }
 
-// AuthConfig - параметры аутентификации
-type AuthConfig struct {
-	Salt      string
-	JWTSecret string
-	JWTExpiry time.Duration
+// PoolConfig - параметры пула соединений с БД
+type PoolConfig struct {
+	MaxOpenConns    int           `yaml:"max_open_conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
 }
 
-// LoadConfig - загрузка конфигурации из переменных окружения
-func LoadConfig() (*Config, error) {
-	// Получаем все переменные окружения с проверкой ошибок
-	host, err := getEnv("PGHOST")
-	if err != nil {
-		return nil, fmt.Errorf("%w: %s", err, "PGHOST")
-	}
+// DBRoleConfig - роль PostgreSQL, которую создает приложение
+// Роль без пароля создается с NOLOGIN: на нее только выдаются права
+type DBRoleConfig struct {
+	Name     string `yaml:"name"`
+	Password Secret `yaml:"password"`
+}
 
-	portStr, err := getEnv("PGPORT")
-	if err != nil {
-		return nil, fmt.Errorf("%w: %s", err, "PGPORT")
-	}
-	port, err := strconv.Atoi(portStr)
-	if err != nil {
-		return nil, fmt.Errorf("invalid PGPORT: %w", err)
-	}
+// String выводит параметры подключения без паролей
+func (c DatabaseConfig) String() string {
+	return fmt.Sprintf("host=%s port=%d user=%s password=%s admin_user=%s admin_password=%s dbname=%s sslmode=%s",
+		c.Host, c.Port, c.User, c.Password, c.AdminUser, c.AdminPassword, c.DBName, c.SSLMode)
+}
 
-	user, err := getEnv("PGUSER")
-	if err != nil {
-		return nil, fmt.Errorf("%w: %s", err, "PGUSER")
-	}
+// LogValue выводит параметры подключения в slog без паролей
+func (c DatabaseConfig) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("host", c.Host),
+		slog.Int("port", c.Port),
+		slog.String("user", c.User),
+		slog.Any("password", c.Password),
+		slog.String("admin_user", c.AdminUser),
+		slog.Any("admin_password", c.AdminPassword),
+		slog.String("dbname", c.DBName),
+		slog.String("sslmode", c.SSLMode),
+		slog.Bool("provision_roles", c.ProvisionRoles),
+	)
+}
 
-	password, err := getEnv("PGPASSWORD")
-	if err != nil {
-		return nil, fmt.Errorf("%w: %s", err, "PGPASSWORD")
-	}
+// AuthConfig - параметры аутентификации
+type AuthConfig struct {
+	Salt      Secret        `yaml:"salt"`
+	JWTSecret Secret        `yaml:"jwt_secret"` // Общий секрет HS256 (используется до перехода на асимметричные ключи)
+	JWTExpiry time.Duration `yaml:"jwt_expiry"`
+	MFAIssuer string        `yaml:"mfa_issuer"` // Название сервиса в приложении-аутентификаторе
 
-	adminUser, err := getEnv("PGADMIN_USER")
-	if err != nil {
-		return nil, fmt.Errorf("%w: %s", err, "PGADMIN_USER")
-	}
-	adminPassword, err := getEnv("PGADMIN_PASSWORD")
-	if err != nil {
-		return nil, fmt.Errorf("%w: %s", err, "PGADMIN_PASSWORD")
-	}
+	JWTKeysDir     string    `yaml:"jwt_keys_dir"`           // Каталог с PEM-ключами RS256/EdDSA (kid = имя файла)
+	JWTActiveKeyID string    `yaml:"jwt_active_key_id"`      // kid ключа, которым подписываются новые токены
+	JWTHS256Until  time.Time `yaml:"jwt_hs256_accept_until"` // До какого момента принимаются старые токены HS256
 
-	dbName, err := getEnv("PGDATABASE")
-	if err != nil {
-		return nil, fmt.Errorf("%w: %s", err, "PGDATABASE")
-	}
+	WSTicketTTL  time.Duration `yaml:"ws_ticket_ttl"`  // Время жизни одноразового билета для подключения к WebSocket
+	WSQueryToken bool          `yaml:"ws_query_token"` // Принимать JWT в ?token= при подключении к WebSocket (устарело)
+}
 
-	sslModeRaw, err := getEnv("PGSSLMODE")
-	if err != nil {
-		return nil, fmt.Errorf("%w: %s", err, "PGSSLMODE")
-	}
-	sslMode := strings.ToLower(sslModeRaw)
+// String выводит параметры аутентификации без секретов
+func (c AuthConfig) String() string {
+	return fmt.Sprintf("salt=%s jwt_secret=%s jwt_expiry=%s mfa_issuer=%s jwt_keys_dir=%s jwt_active_key_id=%s ws_ticket_ttl=%s ws_query_token=%t",
+		c.Salt, c.JWTSecret, c.JWTExpiry, c.MFAIssuer, c.JWTKeysDir, c.JWTActiveKeyID, c.WSTicketTTL, c.WSQueryToken)
+}
 
-	if err := validateSSLMode(sslMode); err != nil {
-		return nil, err
-	}
+// LogValue выводит параметры аутентификации в slog без секретов
+func (c AuthConfig) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Any("salt", c.Salt),
+		slog.Any("jwt_secret", c.JWTSecret),
+		slog.Duration("jwt_expiry", c.JWTExpiry),
+		slog.String("mfa_issuer", c.MFAIssuer),
+		slog.String("jwt_keys_dir", c.JWTKeysDir),
+		slog.String("jwt_active_key_id", c.JWTActiveKeyID),
+		slog.Duration("ws_ticket_ttl", c.WSTicketTTL),
+		slog.Bool("ws_query_token", c.WSQueryToken),
+	)
+}
 
-	if sslMode == "disable" {
-		fmt.Println("WARNING: SSL is disabled - not recommended for production!")
-	}
+// LoginLimitConfig - параметры защиты от перебора паролей
+type LoginLimitConfig struct {
+	MaxFailuresPerLogin int           `yaml:"max_failures"`        // Неудачных попыток на логин до блокировки (0 - без ограничения)
+	MaxFailuresPerIP    int           `yaml:"max_failures_per_ip"` // Неудачных попыток с одного IP до блокировки (0 - без ограничения)
+	FailureWindow       time.Duration `yaml:"failure_window"`      // Окно, в течение которого считаются неудачные попытки
+	BaseLockout         time.Duration `yaml:"base_lockout"`        // Длительность первой блокировки, далее удваивается
+	MaxLockout          time.Duration `yaml:"max_lockout"`         // Максимальная длительность блокировки
+	Store               string        `yaml:"store"`               // Хранилище счетчиков: memory или postgres
+}
 
-	salt := os.Getenv("AUTH_SALT") // Поменять на getnv
-	if salt == "" {
-		return nil, fmt.Errorf("AUTH_SALT is not set")
-	}
+// CORSConfig - политика CORS для браузерных клиентов с других origin
+// Тот же список origin ограничивает подключения к WebSocket
+type CORSConfig struct {
+	AllowedOrigins   []string      `yaml:"allowed_origins"` // Пусто - кросс-доменные запросы запрещены
+	AllowedMethods   []string      `yaml:"allowed_methods"`
+	AllowedHeaders   []string      `yaml:"allowed_headers"`
+	ExposedHeaders   []string      `yaml:"exposed_headers"` // Заголовки ответа, доступные скрипту
+	AllowCredentials bool          `yaml:"allow_credentials"`
+	MaxAge           time.Duration `yaml:"max_age"`  // Время кеширования preflight-ответа
+	DevMode          bool          `yaml:"dev_mode"` // Дополнительно разрешить http(s)://localhost, 127.0.0.1 и [::1] с любым портом
+}
 
-	jwtSecret := os.Getenv("JWT_SECRET")
-	if jwtSecret == "" {
-		return nil, fmt.Errorf("JWT_SECRET is not set")
-	}
+// RateLimitConfig - ограничение частоты запросов к API (token bucket по пользователю, для анонимных запросов - по IP)
+type RateLimitConfig struct {
+	Enabled           bool                     `yaml:"enabled"`
+	RequestsPerSecond float64                  `yaml:"requests_per_second"` // Общее ограничение для маршрутов без своего правила
+	Burst             int                      `yaml:"burst"`
+	Routes            map[string]RateLimitRule `yaml:"routes"`             // operationId из openapi.json -> отдельное ограничение
+	WebSocketMessages RateLimitRule            `yaml:"websocket_messages"` // Отправка сообщений: общая корзина для WebSocket и POST .../messages (sendMessage)
+}
+
+// RateLimitRule - ограничение для одного вида запросов
+type RateLimitRule struct {
+	RequestsPerSecond float64 `yaml:"requests_per_second"`
+	Burst             int     `yaml:"burst"`
+}
 
+// Default возвращает конфигурацию со значениями по умолчанию
+func Default() *Config {
 	return &Config{
+		Log: LogConfig{
+			Level:  "info",
+			Format: "json",
+		},
+		Tracing: TracingConfig{
+			Exporter:    "none",
+			ServiceName: "cursach",
+			SampleRatio: 1,
+		},
+		Server: ServerConfig{
+			Addr:              ":8080",
+			ReadTimeout:       60 * time.Second,
+			ReadHeaderTimeout: 5 * time.Second,
+			WriteTimeout:      60 * time.Second,
+			IdleTimeout:       120 * time.Second,
+			ShutdownTimeout:   15 * time.Second,
+			DrainDelay:        5 * time.Second,
+			TLS: ServerTLSConfig{
+				ReloadInterval: time.Minute,
+			},
+		},
 		Database: DatabaseConfig{
-			Host:          host,
-			Port:          port,
-			User:          user,
-			Password:      password,
-			AdminUser:     adminUser,
-			AdminPassword: adminPassword,
-			DBName:        dbName,
-			SSLMode:       sslMode,
+			Port: 5432,
+			Pool: PoolConfig{
+				MaxOpenConns:    25,
+				MaxIdleConns:    5,
+				ConnMaxLifetime: 5 * time.Minute,
+			},
+			ProvisionRoles: true,
 		},
 		Auth: AuthConfig{
-			Salt:      salt,
-			JWTSecret: jwtSecret,
-			JWTExpiry: 24 * time.Hour,
+			JWTExpiry:   24 * time.Hour,
+			MFAIssuer:   "cursach",
+			WSTicketTTL: 30 * time.Second,
+			// WSQueryToken выключен: JWT в ?token= попадает в журналы прокси; для старых клиентов WS_QUERY_TOKEN=true
+		},
+		LoginLimit: LoginLimitConfig{
+			MaxFailuresPerLogin: 5,
+			MaxFailuresPerIP:    20,
+			FailureWindow:       15 * time.Minute,
+			BaseLockout:         30 * time.Second,
+			MaxLockout:          time.Hour,
+			Store:               "memory",
 		},
-	}, nil
+		CORS: CORSConfig{
+			AllowedMethods: []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+			AllowedHeaders: []string{"Authorization", "Content-Type", "X-Request-ID"},
+			ExposedHeaders: []string{"X-Request-ID", "Retry-After", "Deprecation", "Sunset", "Link"},
+			MaxAge:         10 * time.Minute,
+		},
+		RateLimit: RateLimitConfig{
+			Enabled:           true,
+			RequestsPerSecond: 10,
+			Burst:             20,
+			// Каждый запрос - несколько обращений к БД
+			Routes: map[string]RateLimitRule{
+				"createChat":  {RequestsPerSecond: 0.5, Burst: 5},
+				"searchUsers": {RequestsPerSecond: 2, Burst: 10},
+			},
+			WebSocketMessages: RateLimitRule{RequestsPerSecond: 5, Burst: 10},
+		},
+	}
 }
 
-func validateSSLMode(mode string) error {
-	validModes := map[string]bool{
-		"disable":     true,
-		"allow":       true,
-		"prefer":      true,
-		"require":     true,
-		"verify-ca":   true,
-		"verify-full": true,
+// Load собирает конфигурацию: значения по умолчанию, затем файл path (если задан), затем переменные окружения
+// Все ошибки разбора и проверки возвращаются вместе, а не по одной
+func Load(path string) (*Config, error) {
+	cfg := Default()
+
+	if path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return nil, err
+		}
 	}
-	if !validModes[mode] {
-		return fmt.Errorf("invalid SSL mode: %s (allowed: disable, allow, prefer, require, verify-ca, verify-full)", mode)
+
+	envErr := cfg.applyEnv()
+	cfg.completeRoles()
+
+	if err := errors.Join(envErr, cfg.Validate()); err != nil {
+		return nil, fmt.Errorf("invalid configuration:\n%w", err)
+	}
+
+	return cfg, nil
+}
+
+// loadFile накладывает на конфигурацию значения из YAML файла
+// Неизвестные ключи считаются ошибкой, чтобы опечатка не превращалась в молча проигнорированную настройку
+func (c *Config) loadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open config file: %w", err)
+	}
+	defer f.Close()
+
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+	if err := dec.Decode(c); err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
 	}
 	return nil
 }
 
-func getEnv(key string) (string, error) {
-	value := os.Getenv(key)
-	if value == "" {
-		return "", fmt.Errorf("%w: %s is not set", ErrWithEnv, key)
+// completeRoles дополняет список ролей обязательными ролями приложения
+// Если приложение само подключается под ролью messenger_user, ее паролем по умолчанию служит пароль подключения
+func (c *Config) completeRoles() {
+	for _, name := range []string{DBRoleAdmin, DBRoleUser} {
+		if c.Database.role(name) == nil {
+			c.Database.Roles = append(c.Database.Roles, DBRoleConfig{Name: name})
+		}
+	}
+
+	if userRole := c.Database.role(DBRoleUser); !userRole.Password.IsSet() && c.Database.User == DBRoleUser {
+		userRole.Password = c.Database.Password
 	}
-	return value, nil
+}
+
+// role возвращает роль с именем name из списка или nil
+func (c *DatabaseConfig) role(name string) *DBRoleConfig {
+	for i := range c.Roles {
+		if c.Roles[i].Name == name {
+			return &c.Roles[i]
+		}
+	}
+	return nil
 }
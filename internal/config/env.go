@@ -0,0 +1,212 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// applyEnv накладывает на конфигурацию переменные окружения (они приоритетнее файла)
+// Незаданные переменные не меняют значения; ошибки разбора собираются вместе
+func (c *Config) applyEnv() error {
+	e := &envReader{}
+
+	e.string("LOG_LEVEL", &c.Log.Level)
+	e.string("LOG_FORMAT", &c.Log.Format)
+	c.Log.Format = strings.ToLower(c.Log.Format)
+
+	e.string("TRACING_EXPORTER", &c.Tracing.Exporter)
+	c.Tracing.Exporter = strings.ToLower(c.Tracing.Exporter)
+	e.string("TRACING_OTLP_ENDPOINT", &c.Tracing.Endpoint)
+	e.bool("TRACING_OTLP_INSECURE", &c.Tracing.Insecure)
+	e.string("TRACING_SERVICE_NAME", &c.Tracing.ServiceName)
+	e.float("TRACING_SAMPLE_RATIO", &c.Tracing.SampleRatio)
+
+	e.string("SERVER_ADDR", &c.Server.Addr)
+	e.duration("SERVER_READ_TIMEOUT", &c.Server.ReadTimeout)
+	e.duration("SERVER_READ_HEADER_TIMEOUT", &c.Server.ReadHeaderTimeout)
+	e.duration("SERVER_WRITE_TIMEOUT", &c.Server.WriteTimeout)
+	e.duration("SERVER_IDLE_TIMEOUT", &c.Server.IdleTimeout)
+	e.duration("SERVER_SHUTDOWN_TIMEOUT", &c.Server.ShutdownTimeout)
+	e.duration("SERVER_DRAIN_DELAY", &c.Server.DrainDelay)
+	e.string("SERVER_UNIX_SOCKET", &c.Server.UnixSocket)
+	e.string("SERVER_TLS_CERT_FILE", &c.Server.TLS.CertFile)
+	e.string("SERVER_TLS_KEY_FILE", &c.Server.TLS.KeyFile)
+	e.duration("SERVER_TLS_RELOAD_INTERVAL", &c.Server.TLS.ReloadInterval)
+	e.string("SERVER_HTTP_REDIRECT_ADDR", &c.Server.TLS.RedirectAddr)
+	e.list("SERVER_TRUSTED_PROXIES", &c.Server.TrustedProxies)
+
+	e.string("PGHOST", &c.Database.Host)
+	e.int("PGPORT", &c.Database.Port)
+	e.string("PGUSER", &c.Database.User)
+	e.secret("PGPASSWORD", &c.Database.Password)
+	e.string("PGADMIN_USER", &c.Database.AdminUser)
+	e.secret("PGADMIN_PASSWORD", &c.Database.AdminPassword)
+	e.string("PGDATABASE", &c.Database.DBName)
+	e.string("PGSSLMODE", &c.Database.SSLMode)
+	c.Database.SSLMode = strings.ToLower(c.Database.SSLMode)
+	e.int("PG_MAX_OPEN_CONNS", &c.Database.Pool.MaxOpenConns)
+	e.int("PG_MAX_IDLE_CONNS", &c.Database.Pool.MaxIdleConns)
+	e.duration("PG_CONN_MAX_LIFETIME", &c.Database.Pool.ConnMaxLifetime)
+	e.bool("PG_PROVISION_ROLES", &c.Database.ProvisionRoles)
+	e.rolePassword("PG_ADMIN_ROLE_PASSWORD", &c.Database, DBRoleAdmin)
+	e.rolePassword("PG_USER_ROLE_PASSWORD", &c.Database, DBRoleUser)
+
+	e.secret("AUTH_SALT", &c.Auth.Salt)
+	e.secret("JWT_SECRET", &c.Auth.JWTSecret)
+	e.duration("JWT_EXPIRY", &c.Auth.JWTExpiry)
+	e.string("MFA_ISSUER", &c.Auth.MFAIssuer)
+	e.string("JWT_KEYS_DIR", &c.Auth.JWTKeysDir)
+	e.string("JWT_ACTIVE_KEY_ID", &c.Auth.JWTActiveKeyID)
+	// Окно миграции: до этого момента продолжают приниматься токены HS256
+	e.time("JWT_HS256_ACCEPT_UNTIL", &c.Auth.JWTHS256Until)
+	e.duration("WS_TICKET_TTL", &c.Auth.WSTicketTTL)
+	e.bool("WS_QUERY_TOKEN", &c.Auth.WSQueryToken)
+
+	e.int("LOGIN_MAX_FAILURES", &c.LoginLimit.MaxFailuresPerLogin)
+	e.int("LOGIN_MAX_FAILURES_PER_IP", &c.LoginLimit.MaxFailuresPerIP)
+	e.duration("LOGIN_FAILURE_WINDOW", &c.LoginLimit.FailureWindow)
+	e.duration("LOGIN_BASE_LOCKOUT", &c.LoginLimit.BaseLockout)
+	e.duration("LOGIN_MAX_LOCKOUT", &c.LoginLimit.MaxLockout)
+	e.string("LOGIN_LIMIT_STORE", &c.LoginLimit.Store)
+	c.LoginLimit.Store = strings.ToLower(c.LoginLimit.Store)
+
+	e.list("CORS_ALLOWED_ORIGINS", &c.CORS.AllowedOrigins)
+	e.list("CORS_ALLOWED_METHODS", &c.CORS.AllowedMethods)
+	e.list("CORS_ALLOWED_HEADERS", &c.CORS.AllowedHeaders)
+	e.list("CORS_EXPOSED_HEADERS", &c.CORS.ExposedHeaders)
+	e.bool("CORS_ALLOW_CREDENTIALS", &c.CORS.AllowCredentials)
+	e.duration("CORS_MAX_AGE", &c.CORS.MaxAge)
+	e.bool("CORS_DEV_MODE", &c.CORS.DevMode)
+
+	e.bool("RATE_LIMIT_ENABLED", &c.RateLimit.Enabled)
+	e.float("RATE_LIMIT_RPS", &c.RateLimit.RequestsPerSecond)
+	e.int("RATE_LIMIT_BURST", &c.RateLimit.Burst)
+	e.float("RATE_LIMIT_WS_RPS", &c.RateLimit.WebSocketMessages.RequestsPerSecond)
+	e.int("RATE_LIMIT_WS_BURST", &c.RateLimit.WebSocketMessages.Burst)
+
+	return errors.Join(e.errs...)
+}
+
+// envReader читает переменные окружения в поля конфигурации, накапливая ошибки
+type envReader struct {
+	errs []error
+}
+
+func (e *envReader) fail(key string, err error) {
+	e.errs = append(e.errs, fmt.Errorf("%w: invalid %s: %v", ErrWithEnv, key, err))
+}
+
+func (e *envReader) string(key string, dst *string) {
+	if value := os.Getenv(key); value != "" {
+		*dst = value
+	}
+}
+
+func (e *envReader) int(key string, dst *int) {
+	value := os.Getenv(key)
+	if value == "" {
+		return
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		e.fail(key, err)
+		return
+	}
+	*dst = n
+}
+
+func (e *envReader) float(key string, dst *float64) {
+	value := os.Getenv(key)
+	if value == "" {
+		return
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		e.fail(key, err)
+		return
+	}
+	*dst = f
+}
+
+func (e *envReader) bool(key string, dst *bool) {
+	value := os.Getenv(key)
+	if value == "" {
+		return
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		e.fail(key, err)
+		return
+	}
+	*dst = b
+}
+
+func (e *envReader) duration(key string, dst *time.Duration) {
+	value := os.Getenv(key)
+	if value == "" {
+		return
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		e.fail(key, err)
+		return
+	}
+	*dst = d
+}
+
+func (e *envReader) time(key string, dst *time.Time) {
+	value := os.Getenv(key)
+	if value == "" {
+		return
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		e.fail(key, err)
+		return
+	}
+	*dst = t
+}
+
+// list читает список через запятую; пробелы вокруг элементов отбрасываются
+func (e *envReader) list(key string, dst *[]string) {
+	value := os.Getenv(key)
+	if value == "" {
+		return
+	}
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	*dst = items
+}
+
+func (e *envReader) secret(key string, dst *Secret) {
+	s, err := getSecret(key)
+	if err != nil {
+		e.errs = append(e.errs, err)
+		return
+	}
+	if s.IsSet() {
+		*dst = s
+	}
+}
+
+// rolePassword задает пароль роли name, добавляя роль в список при необходимости
+func (e *envReader) rolePassword(key string, db *DatabaseConfig, name string) {
+	var password Secret
+	e.secret(key, &password)
+	if !password.IsSet() {
+		return
+	}
+	if role := db.role(name); role != nil {
+		role.Password = password
+		return
+	}
+	db.Roles = append(db.Roles, DBRoleConfig{Name: name, Password: password})
+}
@@ -0,0 +1,69 @@
+package config
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// redacted - то, что выводится вместо непустого секрета
+const redacted = "******"
+
+// Secret - строка с секретом (пароль, соль, ключ), которая не попадает в логи
+// Получить значение можно только явно через Value
+type Secret string
+
+// Value возвращает значение секрета
+func (s Secret) Value() string {
+	return string(s)
+}
+
+// IsSet сообщает, задан ли секрет
+func (s Secret) IsSet() bool {
+	return s != ""
+}
+
+// String скрывает значение секрета при форматировании через fmt и log
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return redacted
+}
+
+// GoString скрывает значение секрета при форматировании через %#v
+func (s Secret) GoString() string {
+	return fmt.Sprintf("config.Secret(%q)", s.String())
+}
+
+// LogValue скрывает значение секрета в slog
+func (s Secret) LogValue() slog.Value {
+	return slog.StringValue(s.String())
+}
+
+// MarshalText скрывает значение секрета при сериализации (JSON, YAML)
+func (s Secret) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// getSecret читает секрет из переменной key или из файла, путь к которому задан в key_FILE
+// Файл удобен для Docker/Kubernetes secrets; одновременно задавать оба варианта нельзя
+func getSecret(key string) (Secret, error) {
+	value := os.Getenv(key)
+	file := os.Getenv(key + "_FILE")
+
+	if value != "" && file != "" {
+		return "", fmt.Errorf("only one of %s and %s_FILE may be set", key, key)
+	}
+	if file == "" {
+		return Secret(value), nil
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s_FILE: %w", key, err)
+	}
+	// Завершающий перевод строки почти всегда добавлен редактором, а не является частью пароля
+	return Secret(strings.TrimRight(string(data), "\r\n")), nil
+}
@@ -0,0 +1,176 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Validate проверяет конфигурацию целиком и возвращает все найденные ошибки сразу
+func (c *Config) Validate() error {
+	v := &validator{}
+
+	var level slog.Level
+	v.check(level.UnmarshalText([]byte(c.Log.Level)) == nil,
+		"invalid log.level (LOG_LEVEL): %q (allowed: debug, info, warn, error)", c.Log.Level)
+	v.check(c.Log.Format == "json" || c.Log.Format == "text",
+		"invalid log.format (LOG_FORMAT): %q (allowed: json, text)", c.Log.Format)
+
+	v.check(c.Tracing.Exporter == "none" || c.Tracing.Exporter == "otlp" || c.Tracing.Exporter == "stdout",
+		"invalid tracing.exporter (TRACING_EXPORTER): %q (allowed: none, otlp, stdout)", c.Tracing.Exporter)
+	v.check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1,
+		"tracing.sample_ratio must be between 0 and 1, got %v", c.Tracing.SampleRatio)
+	v.check(c.Tracing.Exporter == "none" || c.Tracing.ServiceName != "", "tracing.service_name is required")
+
+	srv := c.Server
+	v.check(srv.Addr != "" || srv.UnixSocket != "",
+		"at least one of server.addr (SERVER_ADDR) or server.unix_socket (SERVER_UNIX_SOCKET) is required")
+	if srv.TLS.Enabled() {
+		v.check(srv.TLS.CertFile != "" && srv.TLS.KeyFile != "",
+			"server.tls.cert_file and server.tls.key_file must be set together")
+		v.check(srv.Addr != "", "server.tls requires server.addr")
+		v.check(srv.TLS.ReloadInterval > 0, "server.tls.reload_interval must be positive")
+	}
+	v.check(srv.TLS.RedirectAddr == "" || srv.TLS.Enabled(), "server.tls.redirect_addr requires TLS to be enabled")
+	v.check(srv.TLS.RedirectAddr == "" || srv.TLS.RedirectAddr != srv.Addr,
+		"server.tls.redirect_addr must differ from server.addr")
+	v.check(c.Server.ReadTimeout >= 0, "server.read_timeout must not be negative")
+	v.check(c.Server.ReadHeaderTimeout >= 0, "server.read_header_timeout must not be negative")
+	v.check(c.Server.WriteTimeout >= 0, "server.write_timeout must not be negative")
+	v.check(c.Server.IdleTimeout >= 0, "server.idle_timeout must not be negative")
+	v.check(c.Server.ShutdownTimeout > 0, "server.shutdown_timeout must be positive")
+	v.check(c.Server.DrainDelay >= 0, "server.drain_delay must not be negative")
+	for _, proxy := range c.Server.TrustedProxies {
+		v.add(validateTrustedProxy(proxy))
+	}
+
+	db := c.Database
+	v.check(db.Host != "", "database.host (PGHOST) is required")
+	v.check(db.Port > 0 && db.Port < 65536, "database.port (PGPORT) must be between 1 and 65535, got %d", db.Port)
+	v.check(db.User != "", "database.user (PGUSER) is required")
+	v.check(db.Password.IsSet(), "database.password (PGPASSWORD or PGPASSWORD_FILE) is required")
+	v.check(db.AdminUser != "", "database.admin_user (PGADMIN_USER) is required")
+	v.check(db.AdminPassword.IsSet(), "database.admin_password (PGADMIN_PASSWORD or PGADMIN_PASSWORD_FILE) is required")
+	v.check(db.DBName != "", "database.dbname (PGDATABASE) is required")
+	v.add(validateSSLMode(db.SSLMode))
+	v.check(db.Pool.MaxOpenConns > 0, "database.pool.max_open_conns must be positive")
+	v.check(db.Pool.MaxIdleConns >= 0 && db.Pool.MaxIdleConns <= db.Pool.MaxOpenConns,
+		"database.pool.max_idle_conns must be between 0 and max_open_conns (%d)", db.Pool.MaxOpenConns)
+	v.check(db.Pool.ConnMaxLifetime >= 0, "database.pool.conn_max_lifetime must not be negative")
+	for _, role := range db.Roles {
+		v.check(role.Name != "", "database.roles: role name is required")
+	}
+
+	a := c.Auth
+	v.check(a.Salt.IsSet(), "auth.salt (AUTH_SALT) is required")
+	v.check(a.JWTKeysDir != "" || a.JWTSecret.IsSet(), "either auth.jwt_keys_dir (JWT_KEYS_DIR) or auth.jwt_secret (JWT_SECRET) must be set")
+	v.check(a.JWTKeysDir == "" || a.JWTActiveKeyID != "", "auth.jwt_active_key_id (JWT_ACTIVE_KEY_ID) is required when jwt_keys_dir is set")
+	v.check(a.JWTExpiry > 0, "auth.jwt_expiry (JWT_EXPIRY) must be positive")
+	v.check(a.MFAIssuer != "", "auth.mfa_issuer (MFA_ISSUER) must not be empty")
+	v.check(a.WSTicketTTL > 0, "auth.ws_ticket_ttl (WS_TICKET_TTL) must be positive")
+
+	l := c.LoginLimit
+	v.check(l.MaxFailuresPerLogin >= 0, "login_limit.max_failures must not be negative")
+	v.check(l.MaxFailuresPerIP >= 0, "login_limit.max_failures_per_ip must not be negative")
+	v.check(l.FailureWindow > 0, "login_limit.failure_window must be positive")
+	v.check(l.BaseLockout > 0, "login_limit.base_lockout must be positive")
+	v.check(l.MaxLockout >= l.BaseLockout,
+		"login_limit.max_lockout (%s) must not be less than base_lockout (%s)", l.MaxLockout, l.BaseLockout)
+	v.check(l.Store == "memory" || l.Store == "postgres",
+		"invalid login_limit.store: %s (allowed: memory, postgres)", l.Store)
+
+	for _, origin := range c.CORS.AllowedOrigins {
+		v.add(validateOrigin(origin))
+	}
+	v.check(!(c.CORS.AllowCredentials && contains(c.CORS.AllowedOrigins, "*")),
+		"cors.allow_credentials cannot be combined with the \"*\" origin")
+	v.check(c.CORS.MaxAge >= 0, "cors.max_age must not be negative")
+
+	if c.RateLimit.Enabled {
+		v.check(c.RateLimit.RequestsPerSecond > 0, "rate_limit.requests_per_second must be positive")
+		v.check(c.RateLimit.Burst >= 1, "rate_limit.burst must be at least 1")
+		names := make([]string, 0, len(c.RateLimit.Routes))
+		for name := range c.RateLimit.Routes {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			rule := c.RateLimit.Routes[name]
+			v.check(rule.RequestsPerSecond > 0, "rate_limit.routes.%s.requests_per_second must be positive", name)
+			v.check(rule.Burst >= 1, "rate_limit.routes.%s.burst must be at least 1", name)
+		}
+		v.check(c.RateLimit.WebSocketMessages.RequestsPerSecond > 0, "rate_limit.websocket_messages.requests_per_second must be positive")
+		v.check(c.RateLimit.WebSocketMessages.Burst >= 1, "rate_limit.websocket_messages.burst must be at least 1")
+	}
+
+	return errors.Join(v.errs...)
+}
+
+// validator накапливает ошибки проверки
+type validator struct {
+	errs []error
+}
+
+func (v *validator) check(ok bool, format string, args ...interface{}) {
+	if !ok {
+		v.errs = append(v.errs, fmt.Errorf(format, args...))
+	}
+}
+
+func (v *validator) add(err error) {
+	if err != nil {
+		v.errs = append(v.errs, err)
+	}
+}
+
+func validateSSLMode(mode string) error {
+	validModes := map[string]bool{
+		"disable":     true,
+		"allow":       true,
+		"prefer":      true,
+		"require":     true,
+		"verify-ca":   true,
+		"verify-full": true,
+	}
+	if !validModes[mode] {
+		return fmt.Errorf("invalid SSL mode: %q (allowed: disable, allow, prefer, require, verify-ca, verify-full)", mode)
+	}
+	return nil
+}
+
+// validateTrustedProxy проверяет элемент server.trusted_proxies: CIDR, IP или unix
+func validateTrustedProxy(proxy string) error {
+	if proxy == "unix" || net.ParseIP(proxy) != nil {
+		return nil
+	}
+	if _, _, err := net.ParseCIDR(proxy); err != nil {
+		return fmt.Errorf("invalid server.trusted_proxies entry %q (SERVER_TRUSTED_PROXIES): expected CIDR, IP or unix", proxy)
+	}
+	return nil
+}
+
+// validateOrigin проверяет origin из списка CORS: "*" или scheme://host[:port] без пути
+func validateOrigin(origin string) error {
+	if origin == "*" {
+		return nil
+	}
+	u, err := url.Parse(origin)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" ||
+		strings.TrimSuffix(u.Path, "/") != "" || u.RawQuery != "" {
+		return fmt.Errorf("invalid cors origin %q: expected scheme://host[:port]", origin)
+	}
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,256 @@
+package database
+
+import (
+	"context"
+	"cursach/internal/config"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// Privilege - право на таблицу
+type Privilege string
+
+const (
+	PrivilegeSelect Privilege = "SELECT"
+	PrivilegeInsert Privilege = "INSERT"
+	PrivilegeUpdate Privilege = "UPDATE"
+	PrivilegeDelete Privilege = "DELETE"
+)
+
+// tablePrivileges - все права на таблицы, которые проверяются и отзываются
+var tablePrivileges = []Privilege{
+	PrivilegeSelect, PrivilegeInsert, PrivilegeUpdate, PrivilegeDelete,
+	"TRUNCATE", "REFERENCES", "TRIGGER",
+}
+
+var (
+	readOnly   = []Privilege{PrivilegeSelect}
+	appendOnly = []Privilege{PrivilegeSelect, PrivilegeInsert}
+	readWrite  = []Privilege{PrivilegeSelect, PrivilegeInsert, PrivilegeUpdate, PrivilegeDelete}
+)
+
+// TableAccess - права на одну таблицу
+type TableAccess struct {
+	Table      string
+	Privileges []Privilege
+}
+
+// RepositoryAccess - роль, под которой работает репозиторий, и таблицы, к которым он обращается
+type RepositoryAccess struct {
+	Repository string
+	Role       string
+	Tables     []TableAccess
+}
+
+// RepositoryAccessMap - единственный источник прав ролей приложения
+// При изменении запросов репозитория здесь должен меняться и список таблиц
+// RETURNING требует SELECT, ON CONFLICT DO UPDATE - UPDATE
+//
+// Все репозитории работают через одно подключение под ролью messenger_user, и ее права - объединение
+// прав репозиториев: отдельных ролей и пулов соединений для каждого репозитория нет. Поэтому в работающем
+// приложении ошибка в одном репозитории может затронуть таблицы другого. Что каждому репозиторию хватает
+// именно его прав, проверяет TestRepositoryAccess: он выполняет запросы репозитория под ролью только с ними
+var RepositoryAccessMap = []RepositoryAccess{
+	{Repository: "users", Role: config.DBRoleUser, Tables: []TableAccess{
+		{"users", readWrite},
+		{"chats", readOnly},
+		{"chat_users", readOnly},
+		{"messages", readOnly},
+	}},
+	{Repository: "chats", Role: config.DBRoleUser, Tables: []TableAccess{
+		{"chats", []Privilege{PrivilegeSelect, PrivilegeInsert, PrivilegeDelete}},
+		{"chat_users", appendOnly},
+		{"users", readOnly},
+	}},
+	{Repository: "messages", Role: config.DBRoleUser, Tables: []TableAccess{
+		{"messages", readWrite},
+		{"chats", []Privilege{PrivilegeSelect, PrivilegeUpdate}}, // Выдача номера сообщения (last_message_seq)
+		{"users", readOnly},
+	}},
+	{Repository: "tokens", Role: config.DBRoleUser, Tables: []TableAccess{
+		{"revoked_tokens", appendOnly},
+		{"user_token_revocations", []Privilege{PrivilegeSelect, PrivilegeInsert, PrivilegeUpdate}},
+	}},
+	{Repository: "ws_tickets", Role: config.DBRoleUser, Tables: []TableAccess{
+		{"ws_tickets", []Privilege{PrivilegeSelect, PrivilegeInsert, PrivilegeDelete}},
+	}},
+	{Repository: "mfa", Role: config.DBRoleUser, Tables: []TableAccess{
+		{"user_mfa", readWrite},
+		{"mfa_recovery_codes", readWrite},
+	}},
+	{Repository: "login_attempts", Role: config.DBRoleUser, Tables: []TableAccess{
+		{"login_attempts", appendOnly},
+		{"login_throttle", readWrite},
+	}},
+	{Repository: "audit", Role: config.DBRoleUser, Tables: []TableAccess{
+		{"audit_events", appendOnly},
+	}},
+	{Repository: "health", Role: config.DBRoleUser, Tables: []TableAccess{
+		{"schema_migrations", readOnly},
+	}},
+}
+
+// RoleGrants сводит карту доступа к правам ролей: роль -> таблица -> права
+// Роль администратора (для ручного обслуживания) получает полный доступ к данным всех таблиц приложения
+func RoleGrants() map[string]map[string][]Privilege {
+	merged := map[string]map[string]map[Privilege]bool{
+		config.DBRoleAdmin: {},
+		config.DBRoleUser:  {},
+	}
+	for _, repo := range RepositoryAccessMap {
+		if merged[repo.Role] == nil {
+			merged[repo.Role] = map[string]map[Privilege]bool{}
+		}
+		for _, t := range repo.Tables {
+			if merged[repo.Role][t.Table] == nil {
+				merged[repo.Role][t.Table] = map[Privilege]bool{}
+			}
+			for _, p := range t.Privileges {
+				merged[repo.Role][t.Table][p] = true
+			}
+			merged[config.DBRoleAdmin][t.Table] = map[Privilege]bool{
+				PrivilegeSelect: true, PrivilegeInsert: true, PrivilegeUpdate: true, PrivilegeDelete: true,
+			}
+		}
+	}
+
+	grants := make(map[string]map[string][]Privilege, len(merged))
+	for role, tables := range merged {
+		grants[role] = make(map[string][]Privilege, len(tables))
+		for table, privs := range tables {
+			for _, p := range tablePrivileges {
+				if privs[p] {
+					grants[role][table] = append(grants[role][table], p)
+				}
+			}
+		}
+	}
+	return grants
+}
+
+// GrantStatements генерирует SQL, приводящий права ролей в соответствие с картой доступа
+// Сначала отзываются все права на таблицы приложения, поэтому лишние права не накапливаются
+func GrantStatements() []string {
+	return grantStatements(RoleGrants())
+}
+
+// grantStatements генерирует REVOKE/GRANT для прав grants: роль -> таблица -> права
+func grantStatements(grants map[string]map[string][]Privilege) []string {
+	tableSet := map[string]bool{}
+	for _, tables := range grants {
+		for table := range tables {
+			tableSet[table] = true
+		}
+	}
+	allTables := sortedKeys(tableSet)
+	quotedTables := make([]string, len(allTables))
+	for i, t := range allTables {
+		quotedTables[i] = pq.QuoteIdentifier(t)
+	}
+
+	roles := make([]string, 0, len(grants))
+	for role := range grants {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+
+	var stmts []string
+	for _, role := range roles {
+		stmts = append(stmts, fmt.Sprintf("REVOKE ALL PRIVILEGES ON %s FROM %s",
+			strings.Join(quotedTables, ", "), pq.QuoteIdentifier(role)))
+
+		tables := make(map[string]bool, len(grants[role]))
+		for table := range grants[role] {
+			tables[table] = true
+		}
+		for _, table := range sortedKeys(tables) {
+			privs := make([]string, len(grants[role][table]))
+			for i, p := range grants[role][table] {
+				privs[i] = string(p)
+			}
+			stmts = append(stmts, fmt.Sprintf("GRANT %s ON %s TO %s",
+				strings.Join(privs, ", "), pq.QuoteIdentifier(table), pq.QuoteIdentifier(role)))
+		}
+	}
+	return stmts
+}
+
+// ApplyGrants выдает ролям права из карты доступа в одной транзакции
+// Вызывается после миграций, когда все таблицы уже существуют
+func (db *DB) ApplyGrants(ctx context.Context) error {
+	if !db.IsAdmin {
+		return ErrAdminRequired
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, stmt := range GrantStatements() {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("failed to apply grant %q: %w", stmt, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// VerifyPrivileges проверяет, что текущее подключение имеет права роли role из карты доступа
+// Нехватка прав - ошибка; лишние права (например, подключение суперпользователем) только логируются
+func (db *DB) VerifyPrivileges(ctx context.Context, role string) error {
+	expected, ok := RoleGrants()[role]
+	if !ok {
+		return fmt.Errorf("role %s is not in the access map", role)
+	}
+
+	tables := make(map[string]bool, len(expected))
+	for table := range expected {
+		tables[table] = true
+	}
+
+	var missing, extra []string
+	for _, table := range sortedKeys(tables) {
+		want := make(map[Privilege]bool, len(expected[table]))
+		for _, p := range expected[table] {
+			want[p] = true
+		}
+		for _, p := range tablePrivileges {
+			var has bool
+			err := db.QueryRowContext(ctx,
+				`SELECT has_table_privilege(current_user, $1, $2)`, table, string(p),
+			).Scan(&has)
+			if err != nil {
+				return fmt.Errorf("failed to check privilege %s on %s: %w", p, table, err)
+			}
+			switch {
+			case want[p] && !has:
+				missing = append(missing, fmt.Sprintf("%s on %s", p, table))
+			case !want[p] && has:
+				extra = append(extra, fmt.Sprintf("%s on %s", p, table))
+			}
+		}
+	}
+
+	if len(extra) > 0 {
+		slog.WarnContext(ctx, "Database connection has privileges beyond its role", "role", role, "extra", strings.Join(extra, ", "))
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("database connection lacks privileges of role %s: %s", role, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,375 @@
+package database
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"cursach/internal/config"
+	"cursach/internal/models"
+	"cursach/internal/repository"
+
+	"github.com/lib/pq"
+)
+
+// testDatabaseURLEnv - строка подключения суперпользователя к PostgreSQL для интеграционных тестов
+// Тест создает в кластере временную базу и роли и удаляет их по завершении
+const testDatabaseURLEnv = "TEST_DATABASE_URL"
+
+// accessFixture - данные, созданные администратором до проверки репозиториев
+type accessFixture struct {
+	userID    string
+	otherID   string
+	chatID    string
+	messageID string
+}
+
+// repositoryChecks выполняют запросы каждого репозитория из RepositoryAccessMap
+// Репозиторий из карты без проверки - ошибка теста, поэтому новые запросы не остаются непроверенными
+var repositoryChecks = map[string]func(ctx context.Context, db *sql.DB, f accessFixture) error{
+	"users": func(ctx context.Context, db *sql.DB, f accessFixture) error {
+		repo := repository.NewUserRepository(db)
+		id, err := repo.CreateUser(ctx, "access_user", "hash", "user")
+		if err != nil {
+			return err
+		}
+		if _, err := repo.GetUserByID(ctx, f.userID); err != nil {
+			return err
+		}
+		if _, err := repo.GetUserByLogin(ctx, "access_user"); err != nil {
+			return err
+		}
+		if _, err := repo.UserExists(ctx, id); err != nil {
+			return err
+		}
+		if _, err := repo.LoginExists(ctx, "access_user"); err != nil {
+			return err
+		}
+		if err := repo.UpdateLogin(ctx, id, "access_user2"); err != nil {
+			return err
+		}
+		if _, err := repo.SearchUsersByLogin(ctx, "access"); err != nil {
+			return err
+		}
+		if _, _, err := repo.ListUsers(ctx, models.UserFilter{LoginPrefix: "access", Limit: 10}); err != nil {
+			return err
+		}
+		if _, err := repo.GetUserStats(ctx, f.userID); err != nil {
+			return err
+		}
+		if err := repo.UpdateRole(ctx, id, "admin"); err != nil {
+			return err
+		}
+		if err := repo.SetBanned(ctx, id, true); err != nil {
+			return err
+		}
+		return repo.DeleteUser(ctx, id)
+	},
+	"chats": func(ctx context.Context, db *sql.DB, f accessFixture) error {
+		repo := repository.NewChatRepository(db)
+		if _, err := repo.GetChatByID(ctx, f.chatID); err != nil {
+			return err
+		}
+		if _, err := repo.FindChatByUsers(ctx, f.userID, f.otherID); err != nil {
+			return err
+		}
+		if _, err := repo.GetChatUsers(ctx, f.chatID); err != nil {
+			return err
+		}
+		if _, err := repo.IsUserInChat(ctx, f.chatID, f.userID); err != nil {
+			return err
+		}
+		if _, err := repo.GetUserChats(ctx, f.userID); err != nil {
+			return err
+		}
+		if _, err := repo.CreateChatWithUsers(ctx, f.userID); err != nil {
+			return err
+		}
+		id, err := repo.CreateChat(ctx, []string{f.otherID})
+		if err != nil {
+			return err
+		}
+		return repo.DeleteChat(ctx, id)
+	},
+	"messages": func(ctx context.Context, db *sql.DB, f accessFixture) error {
+		repo := repository.NewMessageRepository(db)
+		id, err := repo.Create(ctx, &models.Message{ChatID: f.chatID, UserID: f.userID, Text: "access"})
+		if err != nil {
+			return err
+		}
+		if _, err := repo.GetByID(ctx, f.messageID); err != nil {
+			return err
+		}
+		if _, err := repo.GetByChat(ctx, f.chatID, 10); err != nil {
+			return err
+		}
+		if _, err := repo.GetByChatAfter(ctx, f.chatID, 0, 10); err != nil {
+			return err
+		}
+		if err := repo.Update(ctx, id, "access2"); err != nil {
+			return err
+		}
+		return repo.Delete(ctx, id)
+	},
+	"tokens": func(ctx context.Context, db *sql.DB, f accessFixture) error {
+		repo := repository.NewTokenRepository(db)
+		if err := repo.RevokeToken(ctx, "access-token", f.userID); err != nil {
+			return err
+		}
+		if _, err := repo.IsTokenRevoked(ctx, "access-token"); err != nil {
+			return err
+		}
+		// Повторный отзыв обновляет существующую запись (ON CONFLICT DO UPDATE)
+		for i := 0; i < 2; i++ {
+			if err := repo.RevokeUserTokens(ctx, f.userID, time.Now()); err != nil {
+				return err
+			}
+		}
+		_, err := repo.IsUserTokenRevoked(ctx, f.userID, time.Now())
+		return err
+	},
+	"ws_tickets": func(ctx context.Context, db *sql.DB, f accessFixture) error {
+		repo := repository.NewWSTicketRepository(db)
+		now := time.Now()
+		ticket := &models.WSTicket{Hash: "access-ticket", UserID: f.userID, IssuedAt: now, ExpiresAt: now.Add(time.Minute)}
+		if err := repo.CreateTicket(ctx, ticket); err != nil {
+			return err
+		}
+		_, err := repo.ConsumeTicket(ctx, ticket.Hash, now)
+		return err
+	},
+	"mfa": func(ctx context.Context, db *sql.DB, f accessFixture) error {
+		repo := repository.NewMFARepository(db)
+		if err := repo.SaveSecret(ctx, f.userID, "secret"); err != nil {
+			return err
+		}
+		if _, err := repo.GetMFA(ctx, f.userID); err != nil {
+			return err
+		}
+		if err := repo.Enable(ctx, f.userID, 1, []string{"code-hash"}); err != nil {
+			return err
+		}
+		if _, err := repo.UseStep(ctx, f.userID, 2); err != nil {
+			return err
+		}
+		if _, err := repo.UseRecoveryCode(ctx, f.userID, "code-hash"); err != nil {
+			return err
+		}
+		return repo.Disable(ctx, f.userID)
+	},
+	"login_attempts": func(ctx context.Context, db *sql.DB, f accessFixture) error {
+		repo := repository.NewLoginAttemptRepository(db)
+		now := time.Now()
+		for i := 0; i < 2; i++ {
+			if _, err := repo.RegisterFailure(ctx, "login:access", now, time.Minute); err != nil {
+				return err
+			}
+		}
+		if _, err := repo.GetThrottle(ctx, "login:access"); err != nil {
+			return err
+		}
+		if err := repo.Lock(ctx, "login:access", now.Add(time.Minute)); err != nil {
+			return err
+		}
+		if err := repo.Reset(ctx, "login:access"); err != nil {
+			return err
+		}
+		attempt := &models.LoginAttempt{Login: "access", IP: "127.0.0.1", AttemptedAt: now}
+		if err := repo.RecordAttempt(ctx, attempt); err != nil {
+			return err
+		}
+		_, err := repo.ListAttempts(ctx, "access", "127.0.0.1", 10)
+		return err
+	},
+	"audit": func(ctx context.Context, db *sql.DB, f accessFixture) error {
+		repo := repository.NewAuditRepository(db)
+		event := &models.AuditEvent{
+			ActorID: f.userID, Action: "access.check", TargetType: "user", TargetID: f.userID,
+			IP: "127.0.0.1", Details: []byte(`{}`), CreatedAt: time.Now(),
+		}
+		if err := repo.Append(ctx, event); err != nil {
+			return err
+		}
+		if _, err := repo.Query(ctx, models.AuditFilter{ActorID: f.userID, Limit: 10}); err != nil {
+			return err
+		}
+		return repo.Each(ctx, models.AuditFilter{}, func(*models.AuditEvent) error { return nil })
+	},
+	"health": func(ctx context.Context, db *sql.DB, f accessFixture) error {
+		_, err := (&DB{DB: db}).CheckSchemaVersion(ctx)
+		return err
+	},
+}
+
+// TestRepositoryAccess проверяет карту доступа на живой базе: каждый репозиторий выполняет свои запросы
+// под ролью, которой выданы только его права из RepositoryAccessMap, и не может читать остальные таблицы
+func TestRepositoryAccess(t *testing.T) {
+	for _, access := range RepositoryAccessMap {
+		if repositoryChecks[access.Repository] == nil {
+			t.Fatalf("repository %s from the access map has no check", access.Repository)
+		}
+	}
+
+	adminURL := os.Getenv(testDatabaseURLEnv)
+	if adminURL == "" {
+		t.Skipf("%s is not set", testDatabaseURLEnv)
+	}
+	ctx := context.Background()
+
+	dbURL, admin := createTestDatabase(t, adminURL)
+	fixture := createAccessFixture(t, admin)
+	suffix := randomHex(t, 4)
+
+	var tables []string
+	for table := range RoleGrants()[config.DBRoleAdmin] {
+		tables = append(tables, table)
+	}
+
+	for _, access := range RepositoryAccessMap {
+		t.Run(access.Repository, func(t *testing.T) {
+			grants := make(map[string][]Privilege, len(access.Tables))
+			for _, table := range access.Tables {
+				grants[table.Table] = append(grants[table.Table], table.Privileges...)
+			}
+
+			role := "access_" + access.Repository + "_" + suffix
+			password := randomHex(t, 16)
+			if _, err := admin.ExecContext(ctx, fmt.Sprintf("CREATE ROLE %s LOGIN PASSWORD %s",
+				pq.QuoteIdentifier(role), pq.QuoteLiteral(password))); err != nil {
+				t.Fatalf("failed to create role: %v", err)
+			}
+			t.Cleanup(func() {
+				admin.ExecContext(ctx, "DROP OWNED BY "+pq.QuoteIdentifier(role))
+				admin.ExecContext(ctx, "DROP ROLE "+pq.QuoteIdentifier(role))
+			})
+			for _, stmt := range grantStatements(map[string]map[string][]Privilege{role: grants}) {
+				if _, err := admin.ExecContext(ctx, stmt); err != nil {
+					t.Fatalf("failed to apply grant %q: %v", stmt, err)
+				}
+			}
+
+			roleURL := *dbURL
+			roleURL.User = url.UserPassword(role, password)
+			db, err := sql.Open("postgres", roleURL.String())
+			if err != nil {
+				t.Fatalf("failed to open database as %s: %v", role, err)
+			}
+			defer db.Close()
+
+			if err := repositoryChecks[access.Repository](ctx, db, fixture); err != nil {
+				t.Fatalf("repository queries failed with its own grants: %v", err)
+			}
+
+			for _, table := range tables {
+				if _, ok := grants[table]; ok {
+					continue
+				}
+				_, err := db.ExecContext(ctx, "SELECT 1 FROM "+pq.QuoteIdentifier(table)+" LIMIT 1")
+				var pqErr *pq.Error
+				if !errors.As(err, &pqErr) || pqErr.Code != "42501" {
+					t.Errorf("SELECT from %s outside the grant: got %v, want insufficient_privilege", table, err)
+				}
+			}
+		})
+	}
+}
+
+// createTestDatabase создает временную базу, роли приложения и схему с правами из карты доступа
+// Возвращает адрес базы и подключение к ней суперпользователем
+func createTestDatabase(t *testing.T, adminURL string) (*url.URL, *sql.DB) {
+	t.Helper()
+	ctx := context.Background()
+
+	server, err := sql.Open("postgres", adminURL)
+	if err != nil {
+		t.Fatalf("failed to open %s: %v", testDatabaseURLEnv, err)
+	}
+	t.Cleanup(func() { server.Close() })
+
+	name := "messenger_access_test_" + randomHex(t, 4)
+	if _, err := server.ExecContext(ctx, "CREATE DATABASE "+pq.QuoteIdentifier(name)); err != nil {
+		t.Fatalf("failed to create test database: %v", err)
+	}
+	t.Cleanup(func() {
+		if _, err := server.ExecContext(ctx, "DROP DATABASE "+pq.QuoteIdentifier(name)); err != nil {
+			t.Errorf("failed to drop test database %s: %v", name, err)
+		}
+	})
+
+	dbURL, err := url.Parse(adminURL)
+	if err != nil {
+		t.Fatalf("failed to parse %s: %v", testDatabaseURLEnv, err)
+	}
+	dbURL.Path = "/" + name
+
+	conn, err := sql.Open("postgres", dbURL.String())
+	if err != nil {
+		t.Fatalf("failed to open test database: %v", err)
+	}
+	// Подключение закрывается до удаления базы: очистка выполняется в обратном порядке
+	t.Cleanup(func() { conn.Close() })
+
+	db := &DB{DB: conn, IsAdmin: true}
+	roles := []config.DBRoleConfig{{Name: config.DBRoleAdmin}, {Name: config.DBRoleUser}}
+	if err := db.ProvisionRoles(ctx, roles); err != nil {
+		t.Fatalf("failed to provision roles: %v", err)
+	}
+	if _, err := db.MigrateUp(ctx); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	if err := db.ApplyGrants(ctx); err != nil {
+		t.Fatalf("failed to apply grants: %v", err)
+	}
+	return dbURL, conn
+}
+
+// createAccessFixture создает двух пользователей, чат между ними и сообщение
+func createAccessFixture(t *testing.T, db *sql.DB) accessFixture {
+	t.Helper()
+	ctx := context.Background()
+
+	var f accessFixture
+	err := db.QueryRowContext(ctx,
+		`INSERT INTO users (login, password_hash) VALUES ('access_owner', 'hash') RETURNING id_user`,
+	).Scan(&f.userID)
+	if err == nil {
+		err = db.QueryRowContext(ctx,
+			`INSERT INTO users (login, password_hash) VALUES ('access_other', 'hash') RETURNING id_user`,
+		).Scan(&f.otherID)
+	}
+	if err == nil {
+		err = db.QueryRowContext(ctx, `INSERT INTO chats DEFAULT VALUES RETURNING id_chat`).Scan(&f.chatID)
+	}
+	if err == nil {
+		_, err = db.ExecContext(ctx,
+			`INSERT INTO chat_users (id_chat, id_user) VALUES ($1, $2), ($1, $3)`, f.chatID, f.userID, f.otherID,
+		)
+	}
+	if err == nil {
+		err = db.QueryRowContext(ctx,
+			`INSERT INTO messages (id_chat, id_user, message_text) VALUES ($1, $2, 'fixture') RETURNING id_message`,
+			f.chatID, f.userID,
+		).Scan(&f.messageID)
+	}
+	if err != nil {
+		t.Fatalf("failed to create fixture: %v", err)
+	}
+	return f
+}
+
+func randomHex(t *testing.T, n int) string {
+	t.Helper()
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatalf("failed to generate random bytes: %v", err)
+	}
+	return strings.ToLower(hex.EncodeToString(b))
+}
@@ -3,10 +3,12 @@ package database
 import (
 	"context"
 	"database/sql"
-	"errors"
 	"fmt"
+	"github.com/XSAM/otelsql"
 	"github.com/lib/pq"
-	"log"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"log/slog"
+	"strings"
 	"time"
 
 	"cursach/internal/config"
@@ -24,7 +26,6 @@ type DB struct {
 // Принимает конфигурацию и проверку админа и возвращает *DB или ошибку
 func New(cfg config.DatabaseConfig, isAdmin bool) (*DB, error) {
 	// Сначала проверяем существование базы данных
-	log.Print(cfg)
 	exists, err := databaseExists(cfg)
 	if err != nil {
 		return nil, fmt.Errorf("failed to check database existence: %w", err)
@@ -32,7 +33,7 @@ func New(cfg config.DatabaseConfig, isAdmin bool) (*DB, error) {
 
 	// Если базы не существует - создаем
 	if !exists {
-		log.Printf("Database %s does not exist, creating...", cfg.DBName)
+		slog.Info("Database does not exist, creating", "dbname", cfg.DBName)
 		if err := createDatabase(cfg); err != nil {
 			return nil, fmt.Errorf("failed to create database: %w", err)
 		}
@@ -45,20 +46,16 @@ func New(cfg config.DatabaseConfig, isAdmin bool) (*DB, error) {
 		user = cfg.AdminUser
 		password = cfg.AdminPassword
 	}
-	log.Printf("Using database %s with user %s and password %s", cfg.DBName, user, password)
-	connStr := fmt.Sprintf(
-		"host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
-		cfg.Host, cfg.Port, user, password, cfg.DBName, cfg.SSLMode,
-	)
+	connStr := connString(cfg, user, password, cfg.DBName)
 
-	db, err := sql.Open("postgres", connStr)
+	db, err := open(connStr, isAdmin)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
-	db.SetMaxOpenConns(25)
-	db.SetMaxIdleConns(5)
-	db.SetConnMaxLifetime(5 * time.Minute)
+	db.SetMaxOpenConns(cfg.Pool.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.Pool.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.Pool.ConnMaxLifetime)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -67,26 +64,41 @@ func New(cfg config.DatabaseConfig, isAdmin bool) (*DB, error) {
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	log.Printf("Connected to PostgreSQL as %s", user)
+	slog.Info("Connected to PostgreSQL", "dbname", cfg.DBName, "user", user)
 	return &DB{db, isAdmin}, nil
 }
 
-// InitSchema инициализирует схему базы данных
-func (db *DB) InitSchema() error {
-	if !db.IsAdmin {
-		return errors.New("schema initialization requires admin privileges")
+// open открывает пул соединений; запросы рабочего подключения попадают в трассы как спаны
+// Административное подключение не трассируется: при создании ролей текст запросов содержит пароли
+func open(connStr string, isAdmin bool) (*sql.DB, error) {
+	if isAdmin {
+		return sql.Open("postgres", connStr)
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+	return otelsql.Open("postgres", connStr,
+		otelsql.WithAttributes(semconv.DBSystemPostgreSQL),
+		otelsql.WithSpanOptions(otelsql.SpanOptions{
+			OmitConnResetSession: true,
+			OmitConnPrepare:      true,
+			OmitRows:             true,
+		}),
+	)
+}
 
-	// Выполняем SQL-скрипт создания схемы
-	_, err := db.ExecContext(ctx, Schema)
-	if err != nil {
-		log.Printf("Failed to initialize schema: %v", err)
-	}
+// connString формирует строку подключения libpq
+// Значения берутся в кавычки, чтобы пароль с пробелами или кавычками не ломал строку
+func connString(cfg config.DatabaseConfig, user string, password config.Secret, dbName string) string {
+	return fmt.Sprintf(
+		"host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+		quoteConnValue(cfg.Host), cfg.Port, quoteConnValue(user), quoteConnValue(password.Value()),
+		quoteConnValue(dbName), quoteConnValue(cfg.SSLMode),
+	)
+}
 
-	log.Println("Database schema initialized")
-	return nil
+// quoteConnValue экранирует значение параметра строки подключения
+func quoteConnValue(v string) string {
+	v = strings.ReplaceAll(v, `\`, `\\`)
+	v = strings.ReplaceAll(v, `'`, `\'`)
+	return "'" + v + "'"
 }
 
 // Close закрывает соединение с базой данных
@@ -94,17 +106,15 @@ func (db *DB) Close() error {
 	if err := db.DB.Close(); err != nil {
 		return fmt.Errorf("failed to close database connection: %w", err)
 	}
-	log.Println("Database connection closed")
+	slog.Info("Database connection closed")
 	return nil
 }
 
 // databaseExists проверяет существование базы данных (костыль)
 func databaseExists(cfg config.DatabaseConfig) (bool, error) {
 	// Подключаемся к системной базе данных
-	sysConnStr := fmt.Sprintf(
-		"host=%s port=%d user=%s password=%s dbname=postgres sslmode=%s",
-		cfg.Host, cfg.Port, cfg.User, cfg.Password, cfg.SSLMode,
-	)
+	// Создавать базы может только администратор, обычной роли при первом запуске еще может не быть
+	sysConnStr := connString(cfg, cfg.AdminUser, cfg.AdminPassword, "postgres")
 
 	sysDB, err := sql.Open("postgres", sysConnStr)
 	if err != nil {
@@ -137,10 +147,8 @@ func databaseExists(cfg config.DatabaseConfig) (bool, error) {
 // createDatabase создает новую базу данных
 func createDatabase(cfg config.DatabaseConfig) error {
 	// Подключаемся к системной базе данных
-	sysConnStr := fmt.Sprintf(
-		"host=%s port=%d user=%s password=%s dbname=postgres sslmode=%s",
-		cfg.Host, cfg.Port, cfg.User, cfg.Password, cfg.SSLMode,
-	)
+	// Создавать базы может только администратор, обычной роли при первом запуске еще может не быть
+	sysConnStr := connString(cfg, cfg.AdminUser, cfg.AdminPassword, "postgres")
 
 	sysDB, err := sql.Open("postgres", sysConnStr)
 	if err != nil {
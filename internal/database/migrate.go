@@ -0,0 +1,341 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+//go:embed migrations/*.sql
+var migrationsFS embed.FS
+
+// migrationLockID - ключ advisory lock, не дающий двум процессам применять миграции одновременно
+const migrationLockID = 7231504918
+
+var migrationFileRe = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+var (
+	ErrAdminRequired    = errors.New("operation requires admin privileges")
+	ErrUnknownMigration = errors.New("database has migration unknown to this build")
+	ErrSchemaOutdated   = errors.New("database schema is behind this build")
+)
+
+// Migration одна версионированная миграция схемы
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus состояние миграции в базе данных
+type MigrationStatus struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt sql.NullTime
+}
+
+// LoadMigrations читает встроенные миграции, упорядоченные по версии
+// Каждая версия должна иметь и up, и down файл
+func LoadMigrations() ([]Migration, error) {
+	entries, err := fs.ReadDir(migrationsFS, "migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		m := migrationFileRe.FindStringSubmatch(entry.Name())
+		if m == nil {
+			return nil, fmt.Errorf("invalid migration file name: %s", entry.Name())
+		}
+		version, err := strconv.ParseInt(m[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version %s: %w", entry.Name(), err)
+		}
+
+		body, err := migrationsFS.ReadFile(path.Join("migrations", entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		} else if mig.Name != m[2] {
+			return nil, fmt.Errorf("migration %d has conflicting names %s and %s", version, mig.Name, m[2])
+		}
+		if m[3] == "up" {
+			mig.Up = string(body)
+		} else {
+			mig.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if mig.Up == "" || mig.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s must have both up and down files", mig.Version, mig.Name)
+		}
+		migrations = append(migrations, *mig)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
+// MigrateUp применяет все еще не примененные миграции и возвращает их количество
+// Каждая миграция выполняется в своей транзакции вместе с записью в schema_migrations
+func (db *DB) MigrateUp(ctx context.Context) (int, error) {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return 0, err
+	}
+
+	applied := 0
+	err = db.withMigrationLock(ctx, func(conn *sql.Conn) error {
+		versions, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err := checkKnownVersions(versions, migrations); err != nil {
+			return err
+		}
+
+		for _, mig := range migrations {
+			if _, ok := versions[mig.Version]; ok {
+				continue
+			}
+			slog.InfoContext(ctx, "Applying migration", "version", mig.Version, "name", mig.Name)
+			if err := applyMigration(ctx, conn, mig.Up,
+				`INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, mig.Version, mig.Name); err != nil {
+				return fmt.Errorf("migration %04d_%s failed: %w", mig.Version, mig.Name, err)
+			}
+			applied++
+		}
+		return nil
+	})
+
+	return applied, err
+}
+
+// MigrateDown откатывает последние steps примененных миграций и возвращает их количество
+func (db *DB) MigrateDown(ctx context.Context, steps int) (int, error) {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return 0, err
+	}
+
+	rolledBack := 0
+	err = db.withMigrationLock(ctx, func(conn *sql.Conn) error {
+		versions, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err := checkKnownVersions(versions, migrations); err != nil {
+			return err
+		}
+
+		for i := len(migrations) - 1; i >= 0 && rolledBack < steps; i-- {
+			mig := migrations[i]
+			if _, ok := versions[mig.Version]; !ok {
+				continue
+			}
+			slog.InfoContext(ctx, "Rolling back migration", "version", mig.Version, "name", mig.Name)
+			if err := applyMigration(ctx, conn, mig.Down,
+				`DELETE FROM schema_migrations WHERE version = $1`, mig.Version); err != nil {
+				return fmt.Errorf("rollback of %04d_%s failed: %w", mig.Version, mig.Name, err)
+			}
+			rolledBack++
+		}
+		return nil
+	})
+
+	return rolledBack, err
+}
+
+// MigrationStatus возвращает состояние всех известных миграций
+// Миграции из базы, отсутствующие в этой сборке, тоже попадают в список
+func (db *DB) MigrationStatus(ctx context.Context) ([]MigrationStatus, error) {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ensureMigrationsTable(ctx, db.DB); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.QueryContext(ctx, `SELECT version, name, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]MigrationStatus)
+	for rows.Next() {
+		var s MigrationStatus
+		if err := rows.Scan(&s.Version, &s.Name, &s.AppliedAt); err != nil {
+			return nil, err
+		}
+		s.Applied = true
+		applied[s.Version] = s
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	for _, mig := range migrations {
+		if s, ok := applied[mig.Version]; ok {
+			statuses = append(statuses, s)
+			delete(applied, mig.Version)
+			continue
+		}
+		statuses = append(statuses, MigrationStatus{Version: mig.Version, Name: mig.Name})
+	}
+	for _, s := range applied {
+		statuses = append(statuses, s)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+
+	return statuses, nil
+}
+
+// CheckSchemaVersion сверяет последнюю примененную миграцию с последней миграцией этой сборки
+// и возвращает версию схемы в базе. Не требует прав администратора: читает только schema_migrations
+func (db *DB) CheckSchemaVersion(ctx context.Context) (int64, error) {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return 0, err
+	}
+	var latest int64
+	if len(migrations) > 0 {
+		latest = migrations[len(migrations)-1].Version
+	}
+
+	var current sql.NullInt64
+	if err := db.QueryRowContext(ctx, `SELECT MAX(version) FROM schema_migrations`).Scan(&current); err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	switch {
+	case current.Int64 < latest:
+		return current.Int64, fmt.Errorf("%w: version %d, expected %d", ErrSchemaOutdated, current.Int64, latest)
+	case current.Int64 > latest:
+		return current.Int64, fmt.Errorf("%w: version %d", ErrUnknownMigration, current.Int64)
+	}
+	return current.Int64, nil
+}
+
+// withMigrationLock выполняет fn на выделенном соединении под advisory lock
+// Блокировка сессионная, поэтому все запросы должны идти через одно соединение
+func (db *DB) withMigrationLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	if !db.IsAdmin {
+		return ErrAdminRequired
+	}
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockID); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	defer func() {
+		// Снимаем блокировку даже если контекст уже отменен
+		unlockCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if _, err := conn.ExecContext(unlockCtx, `SELECT pg_advisory_unlock($1)`, migrationLockID); err != nil {
+			slog.Error("Failed to release migration lock", "error", err)
+		}
+	}()
+
+	if err := ensureMigrationsTable(ctx, conn); err != nil {
+		return err
+	}
+
+	return fn(conn)
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// ensureMigrationsTable создает таблицу учета примененных миграций
+func ensureMigrationsTable(ctx context.Context, db execer) error {
+	_, err := db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version BIGINT PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+	return nil
+}
+
+// appliedVersions возвращает множество примененных версий
+func appliedVersions(ctx context.Context, conn *sql.Conn) (map[int64]struct{}, error) {
+	rows, err := conn.QueryContext(ctx, `SELECT version FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	versions := make(map[int64]struct{})
+	for rows.Next() {
+		var v int64
+		if err := rows.Scan(&v); err != nil {
+			return nil, err
+		}
+		versions[v] = struct{}{}
+	}
+	return versions, rows.Err()
+}
+
+// checkKnownVersions не дает работать со схемой, мигрированной более новой сборкой
+func checkKnownVersions(versions map[int64]struct{}, migrations []Migration) error {
+	known := make(map[int64]struct{}, len(migrations))
+	for _, mig := range migrations {
+		known[mig.Version] = struct{}{}
+	}
+	for v := range versions {
+		if _, ok := known[v]; !ok {
+			return fmt.Errorf("%w: version %d", ErrUnknownMigration, v)
+		}
+	}
+	return nil
+}
+
+// applyMigration выполняет скрипт миграции и запись в schema_migrations в одной транзакции
+func applyMigration(ctx context.Context, conn *sql.Conn, script, bookkeeping string, args ...interface{}) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, bookkeeping, args...); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,82 @@
+-- Базовая схема мессенджера
+-- Миграция идемпотентна, чтобы базы, созданные прежним InitSchema, могли перейти на миграции
+CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
+
+-- Таблица пользователей
+CREATE TABLE IF NOT EXISTS users (
+    id_user UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    login TEXT NOT NULL UNIQUE,
+    password_hash TEXT NOT NULL,
+    role VARCHAR(10) NOT NULL DEFAULT 'user' CHECK (role IN ('user', 'admin')),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ
+);
+
+-- Таблица чатов
+CREATE TABLE IF NOT EXISTS chats (
+    id_chat UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ
+);
+
+-- Таблица участников чата
+CREATE TABLE IF NOT EXISTS chat_users (
+    id_chat_user UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    id_chat UUID NOT NULL,
+    id_user UUID NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ
+);
+
+-- Таблица сообщений
+CREATE TABLE IF NOT EXISTS messages (
+    id_message UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    id_chat UUID NOT NULL,
+    id_user UUID NOT NULL,
+    message_text TEXT NOT NULL,
+    sending_time TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ
+);
+
+-- Таблица для отозванных токенов
+CREATE TABLE IF NOT EXISTS revoked_tokens (
+    id_revoked_token UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    token TEXT NOT NULL UNIQUE,
+    id_user UUID NOT NULL,
+    revoked_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Индексы
+CREATE INDEX IF NOT EXISTS idx_chat_users_chat ON chat_users(id_chat);
+CREATE INDEX IF NOT EXISTS idx_chat_users_user ON chat_users(id_user);
+CREATE INDEX IF NOT EXISTS idx_messages_chat ON messages(id_chat);
+CREATE INDEX IF NOT EXISTS idx_messages_sender ON messages(id_user);
+CREATE INDEX IF NOT EXISTS idx_messages_time ON messages(sending_time);
+CREATE INDEX IF NOT EXISTS idx_revoked_tokens_token ON revoked_tokens(token);
+CREATE INDEX IF NOT EXISTS idx_revoked_tokens_user ON revoked_tokens(id_user);
+
+-- Внешние ключи (ADD CONSTRAINT не поддерживает IF NOT EXISTS)
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'fk_chat_users_chat') THEN
+        ALTER TABLE chat_users ADD CONSTRAINT fk_chat_users_chat
+            FOREIGN KEY (id_chat) REFERENCES chats(id_chat) ON DELETE CASCADE;
+    END IF;
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'fk_chat_users_user') THEN
+        ALTER TABLE chat_users ADD CONSTRAINT fk_chat_users_user
+            FOREIGN KEY (id_user) REFERENCES users(id_user) ON DELETE CASCADE;
+    END IF;
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'fk_messages_chat') THEN
+        ALTER TABLE messages ADD CONSTRAINT fk_messages_chat
+            FOREIGN KEY (id_chat) REFERENCES chats(id_chat) ON DELETE CASCADE;
+    END IF;
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'fk_messages_user') THEN
+        ALTER TABLE messages ADD CONSTRAINT fk_messages_user
+            FOREIGN KEY (id_user) REFERENCES users(id_user) ON DELETE CASCADE;
+    END IF;
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'fk_revoked_tokens_user') THEN
+        ALTER TABLE revoked_tokens ADD CONSTRAINT fk_revoked_tokens_user
+            FOREIGN KEY (id_user) REFERENCES users(id_user) ON DELETE CASCADE;
+    END IF;
+END
+$$;
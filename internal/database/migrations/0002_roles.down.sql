@@ -0,0 +1,6 @@
+-- Сами роли не удаляются: они общие для кластера и могут использоваться другими базами
+REVOKE ALL PRIVILEGES ON users, chats, chat_users, messages FROM messenger_user;
+REVOKE EXECUTE ON FUNCTION uuid_generate_v4() FROM messenger_user;
+REVOKE ALL PRIVILEGES ON ALL TABLES IN SCHEMA public FROM messenger_admin;
+REVOKE ALL PRIVILEGES ON ALL SEQUENCES IN SCHEMA public FROM messenger_admin;
+REVOKE EXECUTE ON FUNCTION uuid_generate_v4() FROM messenger_admin;
@@ -0,0 +1,19 @@
+-- Права ролей базы данных
+-- Сами роли создаются приложением до миграций (DB.ProvisionRoles) с паролями из конфигурации
+
+-- Роль администратора (полный доступ ко всем таблицам)
+GRANT ALL PRIVILEGES ON ALL TABLES IN SCHEMA public TO messenger_admin;
+GRANT ALL PRIVILEGES ON ALL SEQUENCES IN SCHEMA public TO messenger_admin;
+GRANT EXECUTE ON FUNCTION uuid_generate_v4() TO messenger_admin;
+
+-- Роль обычного пользователя (ограниченный доступ)
+GRANT SELECT, INSERT, UPDATE, DELETE ON
+    users,
+    chats,
+    chat_users,
+    messages
+TO messenger_user;
+GRANT EXECUTE ON FUNCTION uuid_generate_v4() TO messenger_user;
+
+-- Права на остальные таблицы, включая revoked_tokens, выдаются по карте доступа
+-- репозиториев (internal/database/access.go, DB.ApplyGrants) после миграций
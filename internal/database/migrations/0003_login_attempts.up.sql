@@ -0,0 +1,22 @@
+-- Журнал попыток входа (для защиты от перебора и просмотра администраторами)
+CREATE TABLE IF NOT EXISTS login_attempts (
+    id_login_attempt UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    login TEXT NOT NULL,
+    ip TEXT NOT NULL,
+    success BOOLEAN NOT NULL,
+    attempted_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Счетчики неудачных попыток входа по логину и IP
+CREATE TABLE IF NOT EXISTS login_throttle (
+    throttle_key TEXT PRIMARY KEY,
+    failures INTEGER NOT NULL DEFAULT 0,
+    last_failure TIMESTAMPTZ NOT NULL,
+    locked_until TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_attempts_login ON login_attempts(login, attempted_at);
+CREATE INDEX IF NOT EXISTS idx_login_attempts_ip ON login_attempts(ip, attempted_at);
+
+GRANT ALL PRIVILEGES ON login_attempts, login_throttle TO messenger_admin;
+GRANT SELECT, INSERT, UPDATE, DELETE ON login_attempts, login_throttle TO messenger_user;
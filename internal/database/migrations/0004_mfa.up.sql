@@ -0,0 +1,22 @@
+-- Настройки двухфакторной аутентификации (TOTP)
+CREATE TABLE IF NOT EXISTS user_mfa (
+    id_user UUID PRIMARY KEY REFERENCES users(id_user) ON DELETE CASCADE,
+    totp_secret TEXT NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    last_used_step BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    confirmed_at TIMESTAMPTZ
+);
+
+-- Одноразовые коды восстановления 2FA (хранятся только хеши)
+CREATE TABLE IF NOT EXISTS mfa_recovery_codes (
+    id_recovery_code UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    id_user UUID NOT NULL REFERENCES user_mfa(id_user) ON DELETE CASCADE,
+    code_hash TEXT NOT NULL,
+    used_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_mfa_recovery_codes_user ON mfa_recovery_codes(id_user);
+
+GRANT ALL PRIVILEGES ON user_mfa, mfa_recovery_codes TO messenger_admin;
+GRANT SELECT, INSERT, UPDATE, DELETE ON user_mfa, mfa_recovery_codes TO messenger_user;
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS audit_events;
+DROP FUNCTION IF EXISTS audit_events_append_only();
+DROP TABLE IF EXISTS user_token_revocations;
+ALTER TABLE users DROP COLUMN IF EXISTS banned_at;
@@ -0,0 +1,41 @@
+-- Блокировка пользователей администратором
+ALTER TABLE users ADD COLUMN IF NOT EXISTS banned_at TIMESTAMPTZ;
+
+-- Отзыв всех токенов пользователя, выданных до указанного момента
+CREATE TABLE IF NOT EXISTS user_token_revocations (
+    id_user UUID PRIMARY KEY REFERENCES users(id_user) ON DELETE CASCADE,
+    revoked_before TIMESTAMPTZ NOT NULL
+);
+
+-- Журнал аудита (только добавление записей)
+CREATE TABLE IF NOT EXISTS audit_events (
+    id_audit_event UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    actor_id UUID,
+    action TEXT NOT NULL,
+    target_type TEXT NOT NULL DEFAULT '',
+    target_id TEXT NOT NULL DEFAULT '',
+    ip TEXT NOT NULL DEFAULT '',
+    details JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Запрет изменения и удаления записей журнала аудита
+CREATE OR REPLACE FUNCTION audit_events_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_audit_events_append_only ON audit_events;
+CREATE TRIGGER trg_audit_events_append_only
+    BEFORE UPDATE OR DELETE ON audit_events
+    FOR EACH ROW EXECUTE FUNCTION audit_events_append_only();
+
+CREATE INDEX IF NOT EXISTS idx_audit_events_created ON audit_events(created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_events_actor ON audit_events(actor_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_events_target ON audit_events(target_type, target_id, created_at);
+
+GRANT ALL PRIVILEGES ON user_token_revocations, audit_events TO messenger_admin;
+GRANT SELECT, INSERT, UPDATE, DELETE ON user_token_revocations TO messenger_user;
+-- Журнал аудита доступен обычной роли только на чтение и добавление
+GRANT SELECT, INSERT ON audit_events TO messenger_user;
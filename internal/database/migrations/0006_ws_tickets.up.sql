@@ -0,0 +1,13 @@
+-- Одноразовые билеты для подключения к WebSocket (вместо JWT в строке запроса)
+CREATE TABLE IF NOT EXISTS ws_tickets (
+    ticket_hash TEXT PRIMARY KEY,
+    id_user UUID NOT NULL REFERENCES users(id_user) ON DELETE CASCADE,
+    issued_at TIMESTAMPTZ NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_ws_tickets_expires ON ws_tickets(expires_at);
+
+GRANT ALL PRIVILEGES ON ws_tickets TO messenger_admin;
+-- Билет погашается удалением (DELETE ... RETURNING требует SELECT)
+GRANT SELECT, INSERT, DELETE ON ws_tickets TO messenger_user;
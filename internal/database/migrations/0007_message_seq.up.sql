@@ -0,0 +1,21 @@
+-- Порядковый номер сообщения в чате - курсор SSE и long-poll
+-- Время отправки курсором быть не может: у сообщений бывает одинаковое время, а NOW() - время начала
+-- транзакции, и сообщение с меньшим временем может стать видимым позже сообщения с большим.
+-- Номер выдается увеличением chats.last_message_seq: блокировка строки чата держится до фиксации,
+-- поэтому сообщения чата становятся видимыми строго в порядке номеров
+ALTER TABLE chats ADD COLUMN IF NOT EXISTS last_message_seq BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS seq BIGINT;
+
+-- Нумерация уже отправленных сообщений по времени отправки
+WITH numbered AS (
+    SELECT id_message, ROW_NUMBER() OVER (PARTITION BY id_chat ORDER BY sending_time, id_message) AS seq
+    FROM messages
+)
+UPDATE messages m SET seq = numbered.seq FROM numbered WHERE m.id_message = numbered.id_message;
+
+UPDATE chats c SET last_message_seq = last.seq
+FROM (SELECT id_chat, MAX(seq) AS seq FROM messages GROUP BY id_chat) last
+WHERE c.id_chat = last.id_chat;
+
+ALTER TABLE messages ALTER COLUMN seq SET NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_messages_chat_seq ON messages(id_chat, seq);
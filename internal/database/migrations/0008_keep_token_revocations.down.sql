@@ -0,0 +1,4 @@
+DELETE FROM user_token_revocations r WHERE NOT EXISTS (SELECT 1 FROM users u WHERE u.id_user = r.id_user);
+ALTER TABLE user_token_revocations DROP CONSTRAINT IF EXISTS user_token_revocations_id_user_fkey;
+ALTER TABLE user_token_revocations
+    ADD CONSTRAINT user_token_revocations_id_user_fkey FOREIGN KEY (id_user) REFERENCES users(id_user) ON DELETE CASCADE;
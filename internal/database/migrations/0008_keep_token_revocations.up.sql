@@ -0,0 +1,4 @@
+-- Отзыв токенов переживает удаление пользователя: при каскадном удалении строки уже выданные
+-- токены удаленного аккаунта снова проходили бы проверку до истечения срока действия.
+-- Идентификаторы пользователей (UUID) повторно не выдаются, поэтому строка без пользователя безопасна
+ALTER TABLE user_token_revocations DROP CONSTRAINT IF EXISTS user_token_revocations_id_user_fkey;
@@ -0,0 +1,56 @@
+package database
+
+import (
+	"context"
+	"cursach/internal/config"
+	"fmt"
+	"log/slog"
+
+	"github.com/lib/pq"
+)
+
+// ProvisionRoles создает роли приложения и синхронизирует их пароли с конфигурацией
+// Вызывается до миграций: миграции выдают этим ролям права
+// Существующая роль без пароля в конфигурации не изменяется (пароль управляется вне приложения)
+func (db *DB) ProvisionRoles(ctx context.Context, roles []config.DBRoleConfig) error {
+	if !db.IsAdmin {
+		return ErrAdminRequired
+	}
+
+	for _, role := range roles {
+		var exists bool
+		err := db.QueryRowContext(ctx,
+			`SELECT EXISTS (SELECT 1 FROM pg_roles WHERE rolname = $1)`, role.Name,
+		).Scan(&exists)
+		if err != nil {
+			return fmt.Errorf("failed to check role %s: %w", role.Name, err)
+		}
+
+		// CREATE/ALTER ROLE не поддерживают параметры запроса, поэтому значения экранируются вручную
+		var query string
+		switch {
+		case !exists && role.Password.IsSet():
+			query = fmt.Sprintf("CREATE ROLE %s LOGIN PASSWORD %s",
+				pq.QuoteIdentifier(role.Name), pq.QuoteLiteral(role.Password.Value()))
+		case !exists:
+			query = fmt.Sprintf("CREATE ROLE %s NOLOGIN", pq.QuoteIdentifier(role.Name))
+		case role.Password.IsSet():
+			query = fmt.Sprintf("ALTER ROLE %s LOGIN PASSWORD %s",
+				pq.QuoteIdentifier(role.Name), pq.QuoteLiteral(role.Password.Value()))
+		default:
+			continue
+		}
+
+		// Текст запроса содержит пароль, поэтому в ошибку и лог он не попадает
+		if _, err := db.ExecContext(ctx, query); err != nil {
+			return fmt.Errorf("failed to provision role %s: %w", role.Name, err)
+		}
+		if exists {
+			slog.InfoContext(ctx, "Updated password of database role", "role", role.Name)
+		} else {
+			slog.InfoContext(ctx, "Created database role", "role", role.Name)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,121 @@
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+
+	"cursach/internal/models"
+	"cursach/internal/pkg/apperr"
+	"cursach/internal/usecase/audit"
+)
+
+// ListAuditHandler возвращает события журнала аудита с фильтрами
+type ListAuditHandler struct {
+	useCase *audit.EventReader
+}
+
+// NewListAuditHandler создает новый экземпляр ListAuditHandler
+func NewListAuditHandler(useCase *audit.EventReader) *ListAuditHandler {
+	return &ListAuditHandler{useCase: useCase}
+}
+
+// ServeHTTP обрабатывает HTTP запрос журнала аудита
+// Метод: GET
+// Параметры: actor_id, action, target_type, target_id, from, to (RFC 3339), limit, offset в query
+// Возвращает: JSON массив событий, самые новые первыми
+func (h *ListAuditHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseAuditFilter(r.URL.Query())
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	events, err := h.useCase.List(r.Context(), filter)
+	if err != nil {
+		apperr.Write(w, r, fmt.Errorf("failed to list audit events: %w", err))
+		return
+	}
+	writeJSON(w, r, events)
+}
+
+// ExportAuditHandler выгружает журнал аудита в формате NDJSON (одно событие JSON на строку)
+type ExportAuditHandler struct {
+	useCase *audit.EventReader
+}
+
+// NewExportAuditHandler создает новый экземпляр ExportAuditHandler
+func NewExportAuditHandler(useCase *audit.EventReader) *ExportAuditHandler {
+	return &ExportAuditHandler{useCase: useCase}
+}
+
+// ServeHTTP обрабатывает HTTP запрос выгрузки журнала аудита
+// Метод: GET
+// Параметры: те же фильтры, что и у ListAuditHandler; limit по умолчанию не ограничен
+// Возвращает: поток application/x-ndjson в хронологическом порядке
+func (h *ExportAuditHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseAuditFilter(r.URL.Query())
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Content-Disposition", `attachment; filename="audit.ndjson"`)
+
+	flusher, _ := w.(http.Flusher)
+	encoder := json.NewEncoder(w) // Encode добавляет перевод строки после каждого объекта
+	written := 0
+
+	err = h.useCase.Export(r.Context(), filter, func(e *models.AuditEvent) error {
+		if err := encoder.Encode(e); err != nil {
+			return err
+		}
+		written++
+		if flusher != nil && written%100 == 0 {
+			flusher.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		// Заголовки уже отправлены, поэтому ошибку можно только залогировать
+		slog.ErrorContext(r.Context(), "Audit export failed", "written", written, "error", err)
+	}
+}
+
+// parseAuditFilter разбирает параметры фильтра журнала аудита из query
+// Ошибка разбора - *apperr.Error с кодом validation_failed
+func parseAuditFilter(query url.Values) (models.AuditFilter, error) {
+	filter := models.AuditFilter{
+		ActorID:    query.Get("actor_id"),
+		Action:     query.Get("action"),
+		TargetType: query.Get("target_type"),
+		TargetID:   query.Get("target_id"),
+	}
+
+	var err error
+	if filter.From, err = timeParam(query.Get("from")); err != nil {
+		return filter, invalidParam("from", "from must be an RFC 3339 timestamp")
+	}
+	if filter.To, err = timeParam(query.Get("to")); err != nil {
+		return filter, invalidParam("to", "to must be an RFC 3339 timestamp")
+	}
+	if filter.Limit, err = intParam(query.Get("limit")); err != nil {
+		return filter, invalidParam("limit", "limit must be a non-negative integer")
+	}
+	if filter.Offset, err = intParam(query.Get("offset")); err != nil {
+		return filter, invalidParam("offset", "offset must be a non-negative integer")
+	}
+	return filter, nil
+}
+
+// timeParam разбирает необязательный параметр времени в формате RFC 3339
+func timeParam(raw string) (time.Time, error) {
+	if raw == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, raw)
+}
@@ -0,0 +1,274 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"cursach/internal/models"
+	"cursach/internal/pkg/apperr"
+	"cursach/internal/server"
+	"cursach/internal/usecase/admin"
+	"github.com/gorilla/mux"
+)
+
+// ListUsersHandler возвращает список пользователей с фильтрами и пагинацией
+type ListUsersHandler struct {
+	useCase *admin.UserAdministrator
+}
+
+// NewListUsersHandler создает новый экземпляр ListUsersHandler
+func NewListUsersHandler(useCase *admin.UserAdministrator) *ListUsersHandler {
+	return &ListUsersHandler{useCase: useCase}
+}
+
+// ServeHTTP обрабатывает HTTP запрос списка пользователей
+// Метод: GET
+// Параметры: login (префикс), role, banned (true/false), limit, offset в query
+// Возвращает: JSON с users, total, limit, offset
+func (h *ListUsersHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := models.UserFilter{
+		LoginPrefix: query.Get("login"),
+		Role:        query.Get("role"),
+	}
+
+	if raw := query.Get("banned"); raw != "" {
+		banned, err := strconv.ParseBool(raw)
+		if err != nil {
+			apperr.Write(w, r, invalidParam("banned", "banned must be true or false"))
+			return
+		}
+		filter.Banned = &banned
+	}
+
+	var err error
+	if filter.Limit, err = intParam(query.Get("limit")); err != nil {
+		apperr.Write(w, r, invalidParam("limit", "limit must be a non-negative integer"))
+		return
+	}
+	if filter.Offset, err = intParam(query.Get("offset")); err != nil {
+		apperr.Write(w, r, invalidParam("offset", "offset must be a non-negative integer"))
+		return
+	}
+
+	page, err := h.useCase.ListUsers(r.Context(), filter)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+	writeJSON(w, r, page)
+}
+
+// GetUserHandler возвращает пользователя, его чаты и статистику
+type GetUserHandler struct {
+	useCase *admin.UserAdministrator
+}
+
+// NewGetUserHandler создает новый экземпляр GetUserHandler
+func NewGetUserHandler(useCase *admin.UserAdministrator) *GetUserHandler {
+	return &GetUserHandler{useCase: useCase}
+}
+
+// ServeHTTP обрабатывает HTTP запрос информации о пользователе
+// Метод: GET
+// Параметры: user_id в URL
+// Возвращает: JSON с user, chats, stats
+func (h *GetUserHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	details, err := h.useCase.GetUserDetails(r.Context(), mux.Vars(r)["user_id"])
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+	writeJSON(w, r, details)
+}
+
+// ChangeRoleRequest представляет запрос на смену роли
+type ChangeRoleRequest struct {
+	Role string `json:"role"`
+}
+
+// ChangeRoleHandler изменяет роль пользователя
+type ChangeRoleHandler struct {
+	useCase *admin.UserAdministrator
+}
+
+// NewChangeRoleHandler создает новый экземпляр ChangeRoleHandler
+func NewChangeRoleHandler(useCase *admin.UserAdministrator) *ChangeRoleHandler {
+	return &ChangeRoleHandler{useCase: useCase}
+}
+
+// ServeHTTP обрабатывает HTTP запрос смены роли
+// Метод: PUT
+// Параметры: user_id в URL, JSON с role
+// Возвращает: HTTP статус 204 при успехе
+func (h *ChangeRoleHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	actorID, ok := server.UserIDFromContext(r.Context())
+	if !ok {
+		apperr.Write(w, r, apperr.New(apperr.CodeUnauthorized))
+		return
+	}
+
+	var req ChangeRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apperr.Write(w, r, apperr.Wrap(apperr.CodeInvalidBody, err))
+		return
+	}
+
+	if err := h.useCase.ChangeRole(r.Context(), actorID, mux.Vars(r)["user_id"], req.Role); err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// BanRequest представляет запрос на блокировку
+type BanRequest struct {
+	Reason string `json:"reason,omitempty"`
+}
+
+// BanHandler блокирует пользователя
+type BanHandler struct {
+	useCase *admin.UserAdministrator
+}
+
+// NewBanHandler создает новый экземпляр BanHandler
+func NewBanHandler(useCase *admin.UserAdministrator) *BanHandler {
+	return &BanHandler{useCase: useCase}
+}
+
+// ServeHTTP обрабатывает HTTP запрос блокировки
+// Метод: POST
+// Параметры: user_id в URL, опционально JSON с reason
+// Возвращает: HTTP статус 204 при успехе
+func (h *BanHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	actorID, ok := server.UserIDFromContext(r.Context())
+	if !ok {
+		apperr.Write(w, r, apperr.New(apperr.CodeUnauthorized))
+		return
+	}
+
+	var req BanRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			apperr.Write(w, r, apperr.Wrap(apperr.CodeInvalidBody, err))
+			return
+		}
+	}
+
+	if err := h.useCase.Ban(r.Context(), actorID, mux.Vars(r)["user_id"], req.Reason); err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// UnbanHandler снимает блокировку пользователя
+type UnbanHandler struct {
+	useCase *admin.UserAdministrator
+}
+
+// NewUnbanHandler создает новый экземпляр UnbanHandler
+func NewUnbanHandler(useCase *admin.UserAdministrator) *UnbanHandler {
+	return &UnbanHandler{useCase: useCase}
+}
+
+// ServeHTTP обрабатывает HTTP запрос снятия блокировки
+// Метод: DELETE
+// Параметры: user_id в URL
+// Возвращает: HTTP статус 204 при успехе
+func (h *UnbanHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	actorID, ok := server.UserIDFromContext(r.Context())
+	if !ok {
+		apperr.Write(w, r, apperr.New(apperr.CodeUnauthorized))
+		return
+	}
+
+	if err := h.useCase.Unban(r.Context(), actorID, mux.Vars(r)["user_id"]); err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ForceLogoutHandler завершает все сессии пользователя
+type ForceLogoutHandler struct {
+	useCase *admin.UserAdministrator
+}
+
+// NewForceLogoutHandler создает новый экземпляр ForceLogoutHandler
+func NewForceLogoutHandler(useCase *admin.UserAdministrator) *ForceLogoutHandler {
+	return &ForceLogoutHandler{useCase: useCase}
+}
+
+// ServeHTTP обрабатывает HTTP запрос принудительного выхода
+// Метод: POST
+// Параметры: user_id в URL
+// Возвращает: HTTP статус 204 при успехе
+func (h *ForceLogoutHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	actorID, ok := server.UserIDFromContext(r.Context())
+	if !ok {
+		apperr.Write(w, r, apperr.New(apperr.CodeUnauthorized))
+		return
+	}
+
+	if err := h.useCase.ForceLogout(r.Context(), actorID, mux.Vars(r)["user_id"]); err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DeleteUserHandler удаляет аккаунт пользователя
+type DeleteUserHandler struct {
+	useCase *admin.UserAdministrator
+}
+
+// NewDeleteUserHandler создает новый экземпляр DeleteUserHandler
+func NewDeleteUserHandler(useCase *admin.UserAdministrator) *DeleteUserHandler {
+	return &DeleteUserHandler{useCase: useCase}
+}
+
+// ServeHTTP обрабатывает HTTP запрос удаления аккаунта
+// Метод: DELETE
+// Параметры: user_id в URL
+// Возвращает: HTTP статус 204 при успехе
+func (h *DeleteUserHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	actorID, ok := server.UserIDFromContext(r.Context())
+	if !ok {
+		apperr.Write(w, r, apperr.New(apperr.CodeUnauthorized))
+		return
+	}
+
+	if err := h.useCase.DeleteUser(r.Context(), actorID, mux.Vars(r)["user_id"]); err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeJSON(w http.ResponseWriter, r *http.Request, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to encode admin response", "error", err)
+	}
+}
+
+// invalidParam - ошибка проверки параметра query с его именем в details
+func invalidParam(param, message string) *apperr.Error {
+	return apperr.New(apperr.CodeValidation).WithMessage(message).WithDetail("param", param)
+}
+
+// intParam разбирает необязательный неотрицательный целочисленный параметр
+func intParam(raw string) (int, error) {
+	if raw == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 0 {
+		return 0, errors.New("invalid integer parameter")
+	}
+	return n, nil
+}
@@ -2,10 +2,11 @@ package chat
 
 import (
 	"encoding/json"
-	"errors"
-	"log"
+	"log/slog"
 	"net/http"
 
+	"cursach/internal/pkg/apperr"
+	"cursach/internal/server"
 	"cursach/internal/usecase/chat"
 )
 
@@ -31,35 +32,27 @@ type CreateResponse struct {
 
 func (h *CreateHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Only POST method is allowed", http.StatusMethodNotAllowed)
+		apperr.Write(w, r, apperr.New(apperr.CodeMethodNotAllowed))
 		return
 	}
 
 	// Получаем ID текущего пользователя из контекста
-	currentUserID, ok := r.Context().Value("user_id").(string)
+	currentUserID, ok := server.UserIDFromContext(r.Context())
 	if !ok || currentUserID == "" {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		apperr.Write(w, r, apperr.New(apperr.CodeUnauthorized))
 		return
 	}
 
 	var req CreateRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		apperr.Write(w, r, apperr.Wrap(apperr.CodeInvalidBody, err))
 		return
 	}
 
 	// Передаем ID текущего пользователя и логин целевого пользователя
 	chatID, err := h.useCase.Execute(r.Context(), currentUserID, req.UserLogin)
 	if err != nil {
-		switch {
-		case errors.Is(err, chat.ErrUserNotFound):
-			http.Error(w, err.Error(), http.StatusBadRequest)
-		case errors.Is(err, chat.ErrChatCreation):
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-		default:
-			log.Printf("Create chat error: %v", err)
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
-		}
+		apperr.Write(w, r, err)
 		return
 	}
 
@@ -67,7 +60,6 @@ func (h *CreateHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	if err := json.NewEncoder(w).Encode(resp); err != nil {
-		log.Printf("Failed to encode chat creation response: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Failed to encode chat creation response", "error", err)
 	}
 }
@@ -1,10 +1,10 @@
 package chat
 
 import (
-	"errors"
-	"log"
 	"net/http"
 
+	"cursach/internal/pkg/apperr"
+	"cursach/internal/server"
 	"cursach/internal/usecase/chat"
 	"github.com/gorilla/mux"
 )
@@ -22,37 +22,27 @@ func NewDeleteHandler(useCase *chat.ChatDeleter) *DeleteHandler {
 // ServeHTTP обрабатывает HTTP запрос для удаления чата
 func (h *DeleteHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodDelete {
-		http.Error(w, "Only DELETE method is allowed", http.StatusMethodNotAllowed)
+		apperr.Write(w, r, apperr.New(apperr.CodeMethodNotAllowed))
 		return
 	}
 
 	vars := mux.Vars(r)
-	log.Println(vars)
 	chatID := vars["chat_id"]
 
 	// Получаем userID из контекста (установлено в JWT middleware)
-	userID, ok := r.Context().Value("user_id").(string)
+	userID, ok := server.UserIDFromContext(r.Context())
 	if !ok || userID == "" {
-		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		apperr.Write(w, r, apperr.New(apperr.CodeUnauthorized))
 		return
 	}
 
 	if chatID == "" {
-		http.Error(w, "Missing chat_id parameter", http.StatusBadRequest)
+		apperr.Write(w, r, apperr.New(apperr.CodeValidation).WithMessage("Missing chat_id parameter").WithDetail("param", "chat_id"))
 		return
 	}
-	log.Println(chatID)
 	err := h.useCase.Execute(r.Context(), chatID, userID)
 	if err != nil {
-		switch {
-		case errors.Is(err, chat.ErrUserNotInChat),
-			errors.Is(err, chat.ErrChatNotFound):
-			http.Error(w, err.Error(), http.StatusForbidden)
-		case errors.Is(err, chat.ErrChatDeletion):
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-		default:
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
-		}
+		apperr.Write(w, r, err)
 		return
 	}
 
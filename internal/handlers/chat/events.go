@@ -0,0 +1,471 @@
+package chat
+
+import (
+	"context"
+	"cursach/internal/models"
+	"cursach/internal/pkg/apperr"
+	"cursach/internal/pkg/wsproto"
+	"cursach/internal/server"
+	"cursach/internal/usecase/message"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Резервные транспорты для сетей, где прокси не пропускают WebSocket:
+// события чата читаются через SSE или long-poll, сообщения отправляются через REST.
+// Все транспорты обслуживает один хаб WSHandler, поэтому сообщение из любого из них доходит до всех подписчиков
+
+const (
+	// listenerBuffer - сколько событий может ждать отправки подписчику SSE или long-poll
+	listenerBuffer = 64
+
+	// longPollDefaultWait и longPollMaxWait - сколько long-poll ждет новых сообщений
+	longPollDefaultWait = 25 * time.Second
+	longPollMaxWait     = 30 * time.Second
+)
+
+// ErrSlowConsumer - подписчик не успевает забирать события; клиент переподключается и догружает пропущенное по курсору
+var ErrSlowConsumer = errors.New("event consumer is too slow")
+
+// Курсор SSE и long-poll - порядковый номер последнего полученного сообщения чата (models.Message.Seq)
+// Номера выдаются в порядке фиксации, поэтому сообщения с одинаковым временем отправки не теряются
+
+// listener - подписка SSE-потока или long-poll запроса на события чата
+type listener struct {
+	userID string
+	events chan *models.Message
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+}
+
+// subscribe подписывает на новые сообщения чата; после начала остановки возвращает ErrShuttingDown
+func (h *WSHandler) subscribe(ctx context.Context, chatID, userID string) (*listener, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.shuttingDown {
+		return nil, ErrShuttingDown
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	l := &listener{userID: userID, events: make(chan *models.Message, listenerBuffer), ctx: ctx, cancel: cancel}
+	if _, ok := h.listeners[chatID]; !ok {
+		h.listeners[chatID] = make(map[*listener]struct{})
+	}
+	h.listeners[chatID][l] = struct{}{}
+	return l, nil
+}
+
+func (h *WSHandler) unsubscribe(chatID string, l *listener) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	l.cancel(context.Canceled)
+	if ls, ok := h.listeners[chatID]; ok {
+		delete(ls, l)
+		if len(ls) == 0 {
+			delete(h.listeners, chatID)
+		}
+	}
+}
+
+// notifyListeners передает событие подписчикам чата, не блокируя рассылку; вызывается под h.mu
+func (h *WSHandler) notifyListeners(chatID string, msg *models.Message) {
+	ls := h.listeners[chatID]
+	for l := range ls {
+		select {
+		case l.events <- msg:
+		default:
+			l.cancel(ErrSlowConsumer)
+			delete(ls, l)
+		}
+	}
+	if len(ls) == 0 {
+		delete(h.listeners, chatID)
+	}
+}
+
+// cancelListeners завершает подписки, для которых match возвращает true; вызывается под h.mu
+func (h *WSHandler) cancelListeners(match func(*listener) bool, cause error) {
+	for chatID, ls := range h.listeners {
+		for l := range ls {
+			if match(l) {
+				l.cancel(cause)
+				delete(ls, l)
+			}
+		}
+		if len(ls) == 0 {
+			delete(h.listeners, chatID)
+		}
+	}
+}
+
+// chatMember проверяет, что пользователь из контекста состоит в чате из пути, и возвращает их ID
+// При отказе ответ уже записан
+func (h *WSHandler) chatMember(w http.ResponseWriter, r *http.Request) (chatID, userID string, ok bool) {
+	userID, ok = server.UserIDFromContext(r.Context())
+	if !ok || userID == "" {
+		apperr.Write(w, r, apperr.New(apperr.CodeUnauthorized))
+		return "", "", false
+	}
+
+	chatID = mux.Vars(r)["chat_id"]
+	allowed, err := h.validateChatAccess(r.Context(), userID, chatID)
+	if err != nil {
+		apperr.Write(w, r, fmt.Errorf("failed to check chat membership: %w", err))
+		return "", "", false
+	}
+	if !allowed {
+		apperr.Write(w, r, message.ErrUserNotInChat)
+		return "", "", false
+	}
+	return chatID, userID, true
+}
+
+// SendMessageRequest - тело POST /chats/{chat_id}/messages
+type SendMessageRequest struct {
+	Text string `json:"text"`
+}
+
+// SendMessage отправляет сообщение через REST (POST /chats/{chat_id}/messages)
+// Сообщение рассылается так же, как отправленное через WebSocket
+func (h *WSHandler) SendMessage(w http.ResponseWriter, r *http.Request) {
+	userID, ok := server.UserIDFromContext(r.Context())
+	if !ok || userID == "" {
+		apperr.Write(w, r, apperr.New(apperr.CodeUnauthorized))
+		return
+	}
+
+	var req SendMessageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apperr.Write(w, r, apperr.Wrap(apperr.CodeInvalidBody, err))
+		return
+	}
+
+	// Членство в чате проверяет usecase
+	msg, err := h.sendMessage(r.Context(), mux.Vars(r)["chat_id"], userID, req.Text)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(wsproto.FromModel(msg)); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to encode message response", "error", err)
+	}
+}
+
+// Events отдает события чата потоком Server-Sent Events (GET /chats/{chat_id}/events)
+// Кадры те же, что в WebSocket версии messenger.v2: chat_info и history при подключении, затем message.
+// id события - номер последнего сообщения; при переподключении браузер передает его в Last-Event-ID,
+// и вместо полной истории отправляются только пропущенные сообщения
+func (h *WSHandler) Events(w http.ResponseWriter, r *http.Request) {
+	chatID, userID, ok := h.chatMember(w, r)
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+
+	lastID := r.Header.Get("Last-Event-ID")
+	resume, err := parseCursor(lastID)
+	if err != nil {
+		apperr.Write(w, r, apperr.Wrap(apperr.CodeValidation, err).WithMessage("Invalid Last-Event-ID").WithDetail("field", "Last-Event-ID"))
+		return
+	}
+
+	// Подписка до загрузки истории: сообщения, отправленные во время загрузки, не теряются
+	l, err := h.subscribe(ctx, chatID, userID)
+	if err != nil {
+		w.Header().Set("Retry-After", strconv.Itoa(int(reconnectHint.Seconds())))
+		apperr.Write(w, r, apperr.Wrap(apperr.CodeUnavailable, err).WithMessage("Server is shutting down"))
+		return
+	}
+	defer h.unsubscribe(chatID, l)
+
+	// Поток живет дольше WriteTimeout сервера
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		slog.WarnContext(ctx, "Failed to disable write deadline for event stream", "error", err)
+	}
+
+	header := w.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("X-Accel-Buffering", "no") // Отключает буферизацию в nginx
+	w.WriteHeader(http.StatusOK)
+
+	stream := &eventStream{w: w, rc: rc}
+	// cursor - номер последнего отправленного сообщения: события, уже попавшие в историю, пропускаются
+	cursor := resume
+	sendMissed := func(missed []*models.Message, err error) {
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to load missed messages", "chat_id", chatID, "error", err)
+			stream.sendError(err, "Failed to load message history")
+		}
+		for _, msg := range missed {
+			cursor = msg.Seq
+			stream.send(eventID(cursor), wsproto.TypeMessage, wsproto.NewMessageEvent(msg))
+		}
+	}
+	if lastID == "" {
+		stream.send("", wsproto.TypeChatInfo, h.chatInfoFrame(ctx, chatID, userID))
+		messages, err := h.latestMessages(ctx, chatID)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to send history", "chat_id", chatID, "error", err)
+			stream.sendError(err, "Failed to load message history")
+		} else {
+			if n := len(messages); n > 0 {
+				cursor = messages[n-1].Seq
+			}
+			stream.send(eventID(cursor), wsproto.TypeHistory, wsproto.NewHistoryFor(wsproto.ProtocolV2, messages))
+		}
+	} else {
+		sendMissed(h.messagesAfter(ctx, chatID, resume))
+	}
+
+	ticker := time.NewTicker(pingPeriod)
+	defer ticker.Stop()
+
+	for stream.err == nil {
+		select {
+		case msg := <-l.events:
+			sendMissed(h.catchUp(ctx, chatID, cursor, msg))
+		case <-ticker.C:
+			stream.comment("ping")
+		case <-l.ctx.Done():
+			switch cause := context.Cause(l.ctx); {
+			case errors.Is(cause, ErrShuttingDown):
+				// Браузер переподключится через retry миллисекунд, уже к работающему узлу
+				stream.retry(reconnectHint)
+			case errors.Is(cause, ErrSessionTerminated):
+				stream.sendError(cause, "")
+			}
+			return
+		}
+	}
+}
+
+// MessagesResponse - ответ long-poll
+type MessagesResponse struct {
+	Messages []wsproto.Message `json:"messages"`
+	Cursor   string            `json:"cursor"` // Передается в after следующего запроса
+}
+
+// Poll возвращает сообщения чата после курсора (GET /chats/{chat_id}/messages?after=&wait=)
+// Если новых сообщений нет, запрос ждет до wait секунд; без after возвращается последняя история
+func (h *WSHandler) Poll(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	rawAfter := query.Get("after")
+	after, err := parseCursor(rawAfter)
+	if err != nil {
+		apperr.Write(w, r, apperr.Wrap(apperr.CodeValidation, err).WithMessage("Invalid cursor").WithDetail("field", "after"))
+		return
+	}
+	wait := longPollDefaultWait
+	if raw := query.Get("wait"); raw != "" {
+		seconds, err := strconv.Atoi(raw)
+		if err != nil || seconds < 0 || time.Duration(seconds)*time.Second > longPollMaxWait {
+			apperr.Write(w, r, apperr.New(apperr.CodeValidation).
+				WithMessage(fmt.Sprintf("wait must be an integer from 0 to %d", int(longPollMaxWait.Seconds()))).WithDetail("field", "wait"))
+			return
+		}
+		wait = time.Duration(seconds) * time.Second
+	}
+
+	chatID, userID, ok := h.chatMember(w, r)
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+
+	// Подписка до запроса к БД: сообщение, сохраненное между запросом и ожиданием, не теряется
+	l, err := h.subscribe(ctx, chatID, userID)
+	if err != nil {
+		w.Header().Set("Retry-After", strconv.Itoa(int(reconnectHint.Seconds())))
+		apperr.Write(w, r, apperr.Wrap(apperr.CodeUnavailable, err).WithMessage("Server is shutting down"))
+		return
+	}
+	defer h.unsubscribe(chatID, l)
+
+	if rawAfter == "" {
+		messages, err := h.latestMessages(ctx, chatID)
+		h.writeMessages(w, r, messages, after, err)
+		return
+	}
+	messages, err := h.messagesAfter(ctx, chatID, after)
+	if err != nil || len(messages) > 0 || wait == 0 {
+		h.writeMessages(w, r, messages, after, err)
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Now().Add(wait + writeWait)); err != nil {
+		slog.WarnContext(ctx, "Failed to extend write deadline for long-poll", "error", err)
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	for {
+		select {
+		case msg := <-l.events:
+			messages, err := h.catchUp(ctx, chatID, after, msg)
+			if err != nil || len(messages) > 0 {
+				h.writeMessages(w, r, messages, after, err)
+				return
+			}
+		case <-timer.C:
+			h.writeMessages(w, r, nil, after, nil)
+			return
+		case <-l.ctx.Done():
+			switch cause := context.Cause(l.ctx); {
+			case errors.Is(cause, ErrShuttingDown):
+				w.Header().Set("Retry-After", strconv.Itoa(int(reconnectHint.Seconds())))
+				apperr.Write(w, r, apperr.Wrap(apperr.CodeUnavailable, cause).WithMessage("Server is shutting down"))
+			case errors.Is(cause, ErrSessionTerminated):
+				apperr.Write(w, r, cause)
+			case errors.Is(cause, ErrSlowConsumer):
+				// Пропущенные события клиент получит следующим запросом с тем же курсором
+				h.writeMessages(w, r, nil, after, nil)
+			}
+			// Клиент отключился - отвечать некому
+			return
+		}
+	}
+}
+
+func (h *WSHandler) writeMessages(w http.ResponseWriter, r *http.Request, messages []*models.Message, after int64, err error) {
+	if err != nil {
+		apperr.Write(w, r, fmt.Errorf("failed to load messages: %w", err))
+		return
+	}
+	cursor := after
+	if n := len(messages); n > 0 {
+		cursor = messages[n-1].Seq
+	}
+
+	resp := MessagesResponse{Messages: toWire(messages), Cursor: eventID(cursor)}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to encode messages response", "error", err)
+	}
+}
+
+// catchUp возвращает сообщения, которые нужно отдать подписчику с курсором cursor после события msg
+// Рассылки разных отправителей могут прийти не в порядке номеров: если msg не следует сразу за курсором,
+// пропущенные сообщения догружаются из БД (к моменту рассылки msg они уже сохранены)
+func (h *WSHandler) catchUp(ctx context.Context, chatID string, cursor int64, msg *models.Message) ([]*models.Message, error) {
+	switch {
+	case msg.Seq <= cursor:
+		return nil, nil // Уже отдано клиенту
+	case msg.Seq == cursor+1:
+		return []*models.Message{msg}, nil
+	}
+	return h.messagesAfter(ctx, chatID, cursor)
+}
+
+// messagesAfter загружает сообщения, пропущенные клиентом после курсора
+func (h *WSHandler) messagesAfter(ctx context.Context, chatID string, after int64) ([]*models.Message, error) {
+	var messages []*models.Message
+	err := withTimeout(ctx, func(ctx context.Context) (err error) {
+		messages, err = h.messageRepo.GetByChatAfter(ctx, chatID, after, historyLimit)
+		return err
+	})
+	return messages, err
+}
+
+// chatInfoFrame возвращает chat_info; при ошибке собеседник неизвестен, как и в WebSocket-сессии без ответа БД
+func (h *WSHandler) chatInfoFrame(ctx context.Context, chatID, userID string) wsproto.ChatInfo {
+	name, err := h.interlocutor(ctx, chatID, userID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to get chat users", "chat_id", chatID, "error", err)
+		name = "Unknown"
+	}
+	return wsproto.NewChatInfo(name)
+}
+
+func toWire(messages []*models.Message) []wsproto.Message {
+	out := make([]wsproto.Message, 0, len(messages))
+	for _, msg := range messages {
+		out = append(out, wsproto.FromModel(msg))
+	}
+	return out
+}
+
+// eventID кодирует курсор; 0 - в чате еще нет сообщений
+func eventID(seq int64) string {
+	return strconv.FormatInt(seq, 10)
+}
+
+// parseCursor разбирает курсор из eventID; пустая строка - курсора нет
+func parseCursor(raw string) (int64, error) {
+	if raw == "" {
+		return 0, nil
+	}
+	seq, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || seq < 0 {
+		return 0, fmt.Errorf("invalid cursor %q", raw)
+	}
+	return seq, nil
+}
+
+// eventStream записывает события в формате text/event-stream; после первой ошибки записи остальные пропускаются
+type eventStream struct {
+	w   http.ResponseWriter
+	rc  *http.ResponseController
+	err error
+}
+
+func (s *eventStream) send(id, event string, data any) {
+	if s.err != nil {
+		return
+	}
+	payload, err := json.Marshal(data)
+	if err != nil {
+		s.err = err
+		return
+	}
+	if id != "" {
+		_, s.err = fmt.Fprintf(s.w, "id: %s\n", id)
+	}
+	if s.err == nil {
+		_, s.err = fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", event, payload)
+	}
+	s.flush()
+}
+
+func (s *eventStream) sendError(err error, fallback string) {
+	e := apperr.From(err)
+	if e.Code == apperr.CodeInternal && fallback != "" {
+		e.Message = fallback
+	}
+	s.send("", wsproto.TypeError, wsproto.NewError(e))
+}
+
+func (s *eventStream) comment(text string) {
+	if s.err == nil {
+		_, s.err = fmt.Fprintf(s.w, ": %s\n\n", text)
+		s.flush()
+	}
+}
+
+func (s *eventStream) retry(d time.Duration) {
+	if s.err == nil {
+		_, s.err = fmt.Fprintf(s.w, "retry: %d\n\n", d.Milliseconds())
+		s.flush()
+	}
+}
+
+func (s *eventStream) flush() {
+	if s.err == nil {
+		s.err = s.rc.Flush()
+	}
+}
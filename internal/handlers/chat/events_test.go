@@ -0,0 +1,268 @@
+package chat
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"cursach/internal/models"
+	"cursach/internal/pkg/wsproto"
+	"cursach/internal/repository"
+	"cursach/internal/server"
+	"cursach/internal/usecase/message"
+
+	"github.com/gorilla/mux"
+)
+
+// sameInstant - время отправки всех сообщений фейкового репозитория: курсор не должен на него опираться
+var sameInstant = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+// memoryMessages хранит сообщения в памяти и выдает номера так же, как messages.seq в БД
+type memoryMessages struct {
+	repository.MessageRepository
+
+	mu       sync.Mutex
+	messages []*models.Message
+}
+
+func (m *memoryMessages) Create(ctx context.Context, msg *models.Message) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	msg.Seq = int64(len(m.messages) + 1)
+	msg.ID = "m" + strconv.FormatInt(msg.Seq, 10)
+	msg.SendingTime = sameInstant
+	stored := *msg
+	m.messages = append(m.messages, &stored)
+	return msg.ID, nil
+}
+
+func (m *memoryMessages) GetByChat(ctx context.Context, chatID string, limit int) ([]*models.Message, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var out []*models.Message
+	for i := len(m.messages) - 1; i >= 0 && len(out) < limit; i-- {
+		out = append(out, m.messages[i])
+	}
+	return out, nil
+}
+
+func (m *memoryMessages) GetByChatAfter(ctx context.Context, chatID string, after int64, limit int) ([]*models.Message, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var out []*models.Message
+	for _, msg := range m.messages {
+		if msg.Seq > after && len(out) < limit {
+			out = append(out, msg)
+		}
+	}
+	return out, nil
+}
+
+// openChat - чат, в котором состоит любой пользователь
+type openChat struct {
+	repository.ChatRepository
+}
+
+func (openChat) IsUserInChat(ctx context.Context, chatID, userID string) (bool, error) {
+	return true, nil
+}
+
+func (openChat) GetChatUsers(ctx context.Context, chatID string) ([]models.User, error) {
+	return nil, nil
+}
+
+type namedUsers struct {
+	repository.UserRepository
+}
+
+func (namedUsers) GetUserByID(ctx context.Context, userID string) (*models.User, error) {
+	return &models.User{ID: userID, Login: userID}, nil
+}
+
+// newEventsServer поднимает резервные транспорты чата от имени пользователя alice
+func newEventsServer(t *testing.T) (*WSHandler, *memoryMessages, *httptest.Server) {
+	t.Helper()
+
+	messages := &memoryMessages{}
+	h := NewWSHandler(nil, nil, openChat{}, namedUsers{}, messages, message.NewSender(openChat{}, messages), nil, false, nil, nil)
+
+	r := mux.NewRouter()
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(server.WithPrincipal(r.Context(), &server.Principal{UserID: "alice"})))
+		})
+	})
+	r.HandleFunc("/chats/{chat_id}/messages", h.SendMessage).Methods("POST")
+	r.HandleFunc("/chats/{chat_id}/messages", h.Poll).Methods("GET")
+	r.HandleFunc("/chats/{chat_id}/events", h.Events).Methods("GET")
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+	return h, messages, srv
+}
+
+func postMessage(t *testing.T, srv *httptest.Server, text string) {
+	t.Helper()
+	resp, err := http.Post(srv.URL+"/chats/c1/messages", "application/json", strings.NewReader(`{"text":"`+text+`"}`))
+	if err != nil {
+		t.Fatalf("send %q: %v", text, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("send %q: status %d", text, resp.StatusCode)
+	}
+}
+
+func poll(t *testing.T, srv *httptest.Server, query string) MessagesResponse {
+	t.Helper()
+	resp, err := http.Get(srv.URL + "/chats/c1/messages?" + query)
+	if err != nil {
+		t.Fatalf("poll %s: %v", query, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("poll %s: status %d", query, resp.StatusCode)
+	}
+	var page MessagesResponse
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+		t.Fatalf("poll %s: %v", query, err)
+	}
+	return page
+}
+
+func texts(messages []wsproto.Message) string {
+	var out []string
+	for _, msg := range messages {
+		out = append(out, msg.Text)
+	}
+	return strings.Join(out, ",")
+}
+
+// TestPollDeliversMessagesWithEqualTimestamps проверяет, что курсор long-poll не теряет сообщение,
+// сохраненное в ту же микросекунду, что и уже полученное
+func TestPollDeliversMessagesWithEqualTimestamps(t *testing.T) {
+	_, _, srv := newEventsServer(t)
+
+	postMessage(t, srv, "first")
+	page := poll(t, srv, "")
+	if got := texts(page.Messages); got != "first" || page.Cursor != "1" {
+		t.Fatalf("history = %q cursor %q, want \"first\" cursor \"1\"", got, page.Cursor)
+	}
+
+	postMessage(t, srv, "second")
+	page = poll(t, srv, "wait=0&after="+page.Cursor)
+	if got := texts(page.Messages); got != "second" || page.Cursor != "2" {
+		t.Errorf("after 1 = %q cursor %q, want \"second\" cursor \"2\"", got, page.Cursor)
+	}
+
+	page = poll(t, srv, "wait=0&after=0")
+	if got := texts(page.Messages); got != "first,second" {
+		t.Errorf("after 0 = %q, want both messages", got)
+	}
+}
+
+// TestPollRejectsInvalidCursor проверяет, что курсор в старом формате (время отправки) отклоняется
+func TestPollRejectsInvalidCursor(t *testing.T) {
+	_, _, srv := newEventsServer(t)
+
+	for _, after := range []string{"2024-05-01T12:00:00Z", "-1", "abc"} {
+		resp, err := http.Get(srv.URL + "/chats/c1/messages?wait=0&after=" + after)
+		if err != nil {
+			t.Fatalf("poll: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("after=%s: status %d, want %d", after, resp.StatusCode, http.StatusBadRequest)
+		}
+	}
+}
+
+type sseEvent struct {
+	id, event, data string
+}
+
+// readEvent читает одно событие потока, пропуская комментарии
+func readEvent(t *testing.T, r *bufio.Reader) sseEvent {
+	t.Helper()
+	var ev sseEvent
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read event: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "" && ev.event != "":
+			return ev
+		case strings.HasPrefix(line, "id: "):
+			ev.id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			ev.event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			ev.data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+func (ev sseEvent) text(t *testing.T) string {
+	t.Helper()
+	var frame wsproto.MessageEvent
+	if err := json.Unmarshal([]byte(ev.data), &frame); err != nil {
+		t.Fatalf("decode %s: %v", ev.data, err)
+	}
+	return frame.Message.Text
+}
+
+// TestEventsResumeAndCatchUp проверяет, что поток с Last-Event-ID отдает пропущенные сообщения
+// с одинаковым временем отправки, а рассылка не по порядку номеров догружает пропуск из БД
+func TestEventsResumeAndCatchUp(t *testing.T) {
+	h, messages, srv := newEventsServer(t)
+	postMessage(t, srv, "first")
+	postMessage(t, srv, "second")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/chats/c1/events", nil)
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	req.Header.Set("Last-Event-ID", "1")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("events: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("events: status %d", resp.StatusCode)
+	}
+	stream := bufio.NewReader(resp.Body)
+
+	ev := readEvent(t, stream)
+	if ev.event != wsproto.TypeMessage || ev.id != "2" || ev.text(t) != "second" {
+		t.Fatalf("resumed event = %+v, want message \"second\" with id 2", ev)
+	}
+
+	// Третье и четвертое сообщения сохранены, но рассылка четвертого обогнала третье
+	third := &models.Message{ChatID: "c1", Text: "third"}
+	fourth := &models.Message{ChatID: "c1", Text: "fourth"}
+	messages.Create(ctx, third)
+	messages.Create(ctx, fourth)
+	h.broadcastMessage("c1", fourth)
+	h.broadcastMessage("c1", third)
+
+	for _, want := range []struct{ id, text string }{{"3", "third"}, {"4", "fourth"}} {
+		ev := readEvent(t, stream)
+		if ev.id != want.id || ev.text(t) != want.text {
+			t.Errorf("event = id %s %q, want id %s %q", ev.id, ev.text(t), want.id, want.text)
+		}
+	}
+}
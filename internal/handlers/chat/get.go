@@ -1,8 +1,12 @@
 package chat
 
 import (
+	"cursach/internal/pkg/apperr"
+	"cursach/internal/server"
 	"cursach/internal/usecase/chat"
 	"encoding/json"
+	"fmt"
+	"log/slog"
 	"net/http"
 )
 
@@ -15,16 +19,20 @@ func NewGetChatsHandler(chatLister *chat.ChatLister) *GetChatsHandler {
 }
 
 func (h *GetChatsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	userID := r.Context().Value("user_id").(string)
+	userID, ok := server.UserIDFromContext(r.Context())
+	if !ok {
+		apperr.Write(w, r, apperr.New(apperr.CodeUnauthorized))
+		return
+	}
 
 	chats, err := h.chatLister.Execute(r.Context(), userID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		apperr.Write(w, r, fmt.Errorf("failed to list chats: %w", err))
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(chats); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Failed to encode chats response", "error", err)
 	}
 }
@@ -0,0 +1,72 @@
+package chat
+
+import (
+	"context"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// outboundQueueSize - сколько кадров может ждать отправки в WebSocket-соединение
+const outboundQueueSize = 64
+
+// outbound - очередь исходящих кадров WebSocket-соединения с единственной горутиной-писателем
+// gorilla/websocket допускает только одного писателя, поэтому все кадры сессии, рассылка и пинги
+// проходят через run. Закрытие отправляется через WriteControl, который можно вызывать параллельно
+type outbound struct {
+	conn  *websocket.Conn
+	queue chan interface{}
+	done  chan struct{} // Закрывается, когда писатель завершился
+}
+
+func newOutbound(conn *websocket.Conn) *outbound {
+	return &outbound{
+		conn:  conn,
+		queue: make(chan interface{}, outboundQueueSize),
+		done:  make(chan struct{}),
+	}
+}
+
+// send ставит кадр в очередь, не блокируя отправителя
+// false - писатель завершился или очередь переполнена (клиент не успевает читать)
+func (o *outbound) send(frame interface{}) bool {
+	select {
+	case <-o.done:
+		return false
+	default:
+	}
+
+	select {
+	case o.queue <- frame:
+		return true
+	default:
+		return false
+	}
+}
+
+// run пишет кадры из очереди и пинги, пока не отменен ctx или запись не завершилась ошибкой
+// Каждая запись ограничена writeWait; при ошибке соединение закрывается, и чтение сессии завершается
+func (o *outbound) run(ctx context.Context) {
+	defer close(o.done)
+
+	ticker := time.NewTicker(pingPeriod)
+	defer ticker.Stop()
+
+	for {
+		var err error
+		select {
+		case frame := <-o.queue:
+			o.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			err = o.conn.WriteJSON(frame)
+		case <-ticker.C:
+			o.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			err = o.conn.WriteMessage(websocket.PingMessage, nil)
+		case <-ctx.Done():
+			return
+		}
+		if err != nil {
+			o.conn.Close()
+			return
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package chat
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// dialOutbound поднимает WebSocket-сервер, запускает писатель на серверной стороне и возвращает клиента
+func dialOutbound(t *testing.T) (*outbound, *websocket.Conn) {
+	t.Helper()
+
+	ready := make(chan *outbound, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upgrader := websocket.Upgrader{}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("upgrade: %v", err)
+			return
+		}
+		out := newOutbound(conn)
+		ready <- out
+		out.run(context.Background())
+	}))
+	t.Cleanup(srv.Close)
+
+	client, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return <-ready, client
+}
+
+// TestOutboundSerializesConcurrentWrites отправляет кадры из нескольких горутин, как рассылка
+// из REST-запросов и ошибки сессии; запись идет только из писателя, поэтому клиент получает все кадры
+func TestOutboundSerializesConcurrentWrites(t *testing.T) {
+	out, client := dialOutbound(t)
+
+	const senders, perSender = 4, outboundQueueSize / 4
+	var wg sync.WaitGroup
+	for i := 0; i < senders; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < perSender; j++ {
+				if !out.send(map[string]int{"n": j}) {
+					t.Error("send rejected a frame while the queue had room")
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	client.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for i := 0; i < senders*perSender; i++ {
+		var frame map[string]int
+		if err := client.ReadJSON(&frame); err != nil {
+			t.Fatalf("frame %d: %v", i, err)
+		}
+	}
+}
+
+// TestOutboundStopsOnWriteError проверяет, что писатель завершается, когда клиент пропал,
+// и после этого send не ставит кадры в очередь
+func TestOutboundStopsOnWriteError(t *testing.T) {
+	out, client := dialOutbound(t)
+	client.Close()
+
+	timeout := time.After(5 * time.Second)
+	for stopped := false; !stopped; {
+		out.send(struct{}{})
+		select {
+		case <-out.done:
+			stopped = true
+		case <-timeout:
+			t.Fatal("writer did not stop after the connection closed")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	if out.send(struct{}{}) {
+		t.Error("send accepted a frame after the writer stopped")
+	}
+}
@@ -2,18 +2,29 @@ package chat
 
 import (
 	"context"
+	"cursach/internal/models"
+	"cursach/internal/pkg/apperr"
 	"cursach/internal/pkg/auth"
+	"cursach/internal/pkg/metrics"
+	"cursach/internal/pkg/ratelimit"
+	"cursach/internal/pkg/tracing"
+	"cursach/internal/pkg/wsproto"
 	"cursach/internal/repository"
+	"cursach/internal/server"
 	"cursach/internal/usecase/message"
-	"encoding/json"
+	userusecase "cursach/internal/usecase/user"
 	"errors"
-	"log"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"strconv"
 	"sync"
 	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/gorilla/websocket"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
@@ -21,129 +32,298 @@ const (
 	pongWait       = 60 * time.Second
 	pingPeriod     = (pongWait * 9) / 10
 	maxMessageSize = 1024 // 1KB
+
+	// reconnectHint - через сколько клиенту стоит переподключиться после перезапуска сервера
+	reconnectHint = 5 * time.Second
+
+	// dbOperationTimeout ограничивает каждое обращение к БД из WebSocket-сессии
+	dbOperationTimeout = 5 * time.Second
+	// frameQueueSize - сколько прочитанных кадров может ждать обработки, пока чтение продолжает следить за закрытием
+	frameQueueSize = 16
+
+	// MessageRateLimit - имя правила ограничения частоты отправки сообщений
+	// Совпадает с operationId отправки через REST, чтобы WebSocket и REST расходовали одну корзину
+	MessageRateLimit = "sendMessage"
+
+	// historyLimit - сколько последних сообщений отправляется при подключении
+	historyLimit = 500
 )
 
-var upgrader = websocket.Upgrader{
-	ReadBufferSize:  1024,
-	WriteBufferSize: 1024,
-	CheckOrigin: func(r *http.Request) bool {
-		return true // Для разработки
-	},
-}
+var (
+	ErrShuttingDown      = errors.New("websocket hub is shutting down")
+	ErrConnectionClosed  = errors.New("websocket connection closed")
+	ErrSessionTerminated = errors.New("websocket session terminated")
+	ErrOperationTimeout  = errors.New("operation timed out")
+	ErrMissingTicket     = errors.New("websocket ticket is required")
+	ErrQueryTokenDenied  = errors.New("jwt in query string is disabled, use a websocket ticket")
+)
+
+// restartCloseReason - причина закрытия при остановке сервера с подсказкой для клиента
+var restartCloseReason = fmt.Sprintf("server restarting; retry_after=%d", int(reconnectHint.Seconds()))
 
 type WSHandler struct {
-	jwtSecret   string
+	jwtKeys     *auth.KeySet
 	tokenRepo   repository.TokenRepository
 	chatRepo    repository.ChatRepository
 	userRepo    repository.UserRepository
 	messageRepo repository.MessageRepository
 	messageUC   *message.Sender
-	connections map[string]map[*websocket.Conn]bool
+	tickets     *userusecase.WSTicketManager
+	origins     *server.OriginPolicy
+	limiter     *ratelimit.Limiter // nil - без ограничения частоты сообщений
+	upgrader    websocket.Upgrader
+	connections map[string]map[*websocket.Conn]*wsSession // chatID -> соединение -> сессия
+	listeners   map[string]map[*listener]struct{}         // chatID -> подписки SSE и long-poll
 	mu          sync.Mutex
+
+	allowQueryToken bool // Принимать JWT в ?token= (до перехода клиентов на билеты)
+
+	shuttingDown bool           // После начала остановки новые соединения не принимаются
+	sessions     sync.WaitGroup // Активные сессии, включая обработку уже принятых сообщений
+}
+
+// wsSession - зарегистрированное соединение
+type wsSession struct {
+	userID string
+	cancel context.CancelCauseFunc // Отменяет операции сессии при закрытии соединения
+	out    *outbound               // Единственный путь записи в соединение после регистрации
 }
 
 func NewWSHandler(
-	jwtSecret string,
+	jwtKeys *auth.KeySet,
 	tokenRepo repository.TokenRepository,
 	chatRepo repository.ChatRepository,
 	userRepo repository.UserRepository,
 	messageRepo repository.MessageRepository,
 	messageUC *message.Sender,
+	tickets *userusecase.WSTicketManager,
+	allowQueryToken bool,
+	origins *server.OriginPolicy,
+	limiter *ratelimit.Limiter,
 ) *WSHandler {
 	return &WSHandler{
-		jwtSecret:   jwtSecret,
+		jwtKeys:     jwtKeys,
 		tokenRepo:   tokenRepo,
 		chatRepo:    chatRepo,
 		userRepo:    userRepo,
 		messageRepo: messageRepo,
 		messageUC:   messageUC,
-		connections: make(map[string]map[*websocket.Conn]bool),
+		tickets:     tickets,
+		origins:     origins,
+		limiter:     limiter,
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  1024,
+			WriteBufferSize: 1024,
+			Subprotocols:    wsproto.Supported,
+			CheckOrigin:     origins.AllowWebSocket,
+		},
+		connections: make(map[string]map[*websocket.Conn]*wsSession),
+		listeners:   make(map[string]map[*listener]struct{}),
+
+		allowQueryToken: allowQueryToken,
 	}
 }
 
 func (h *WSHandler) Handle(w http.ResponseWriter, r *http.Request) {
-	log.Println("WebSocket connection requested")
-	conn, err := upgrader.Upgrade(w, r, nil)
-	log.Println("conn", conn)
+	if h.isShuttingDown() {
+		w.Header().Set("Retry-After", strconv.Itoa(int(reconnectHint.Seconds())))
+		apperr.Write(w, r, apperr.Wrap(apperr.CodeUnavailable, ErrShuttingDown).WithMessage("Server is shutting down"))
+		return
+	}
+
+	// Origin проверяется и в upgrader, но здесь отказ можно вернуть в общем формате ошибок
+	if !h.origins.AllowWebSocket(r) {
+		slog.WarnContext(r.Context(), "WebSocket origin rejected", "origin", r.Header.Get("Origin"))
+		apperr.Write(w, r, apperr.New(apperr.CodeForbidden).WithMessage("Origin not allowed").WithDetail("origin", r.Header.Get("Origin")))
+		return
+	}
+
+	// Версия согласуется до переключения протокола, чтобы неподдерживаемую можно было отклонить ответом HTTP
+	protocol, err := wsproto.Negotiate(r)
+	if err != nil {
+		apperr.Write(w, r, apperr.Wrap(apperr.CodeUnsupportedProtocol, err).WithDetail("supported", wsproto.Supported))
+		return
+	}
+	trace.SpanFromContext(r.Context()).SetAttributes(attribute.String("ws.protocol", protocol))
+
+	ctx := r.Context()
+	conn, err := h.upgrader.Upgrade(w, r, nil)
 	if err != nil {
-		log.Printf("WebSocket upgrade failed: %v", err)
+		slog.WarnContext(ctx, "WebSocket upgrade failed", "error", err)
 		return
 	}
 	defer conn.Close()
 
+	// Контекст соединения: после hijack http.Server не отменяет контекст запроса,
+	// поэтому он отменяется при закрытии соединения, чтобы запросы к БД не выполнялись впустую
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(ErrConnectionClosed)
+
 	// Аутентификация
-	claims, err := h.authenticate(r)
+	userID, err := h.authenticate(ctx, r)
 	if err != nil {
-		log.Printf("Authentication error: %v", err)
-		conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(4001, "Auth failed"))
+		slog.InfoContext(ctx, "WebSocket authentication failed", "error", err)
+		if errors.Is(err, ErrOperationTimeout) {
+			closeTryAgain(conn)
+			return
+		}
+		conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(wsproto.CloseAuthFailed, "Auth failed"))
 		return
 	}
-	log.Println("claims", claims)
 
 	// Проверка доступа к чату
 	vars := mux.Vars(r)
 	chatID := vars["chat_id"]
-	log.Println("chatID", chatID)
 	if chatID == "" {
-		conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(4002, "Chat ID not provided"))
+		conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(wsproto.CloseChatIDMissing, "Chat ID not provided"))
 		return
 	}
 
-	if !h.validateChatAccess(claims.UserID, chatID) {
-		conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(4003, "Access denied"))
+	allowed, err := h.validateChatAccess(ctx, userID, chatID)
+	if errors.Is(err, ErrOperationTimeout) {
+		closeTryAgain(conn)
+		return
+	}
+	if !allowed {
+		conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(wsproto.CloseAccessDenied, "Access denied"))
 		return
 	}
 
-	// Регистрация соединения
-	h.registerConnection(chatID, conn)
+	// Регистрация соединения (сервер мог начать остановку, пока шла проверка доступа)
+	// До регистрации в соединение пишет только эта горутина, после - только писатель out
+	out := newOutbound(conn)
+	if !h.registerConnection(chatID, userID, conn, cancel, out) {
+		conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseServiceRestart, restartCloseReason))
+		return
+	}
+	defer h.sessions.Done()
 	defer h.unregisterConnection(chatID, conn)
+	metrics.WebSocketConnections.Inc()
+	defer metrics.WebSocketConnections.Dec()
 
-	// Запускаем горутину для отправки пингов
-	go h.keepAlive(conn)
+	// Запускаем писателя: он же отправляет пинги
+	go out.run(ctx)
 
 	// Отправляем информацию о чате
-	h.sendChatInfo(conn, chatID, claims.UserID)
+	h.sendChatInfo(ctx, out, chatID, userID)
 
 	// Загружаем и отправляем историю сообщений
-	if err := h.sendHistory(conn, chatID); err != nil {
-		log.Printf("Failed to send history: %v", err)
+	if err := h.sendHistory(ctx, out, chatID, protocol); err != nil {
+		slog.ErrorContext(ctx, "Failed to send history", "chat_id", chatID, "error", err)
+		writeError(out, err, "Failed to load message history")
 	}
 
 	// Обработка входящих сообщений
-	h.handleMessages(conn, chatID, claims.UserID)
+	h.handleMessages(ctx, cancel, conn, out, chatID, userID)
 }
 
-func (h *WSHandler) authenticate(r *http.Request) (*auth.Claims, error) {
-	tokenString := r.URL.Query().Get("token")
-	if tokenString == "" {
-		return nil, errors.New("missing token")
+// authenticate определяет пользователя по одноразовому билету из POST /api/v1/ws-ticket (?ticket=)
+// JWT в ?token= принимается, пока не выключен allowQueryToken: он попадает в журналы прокси и историю браузера
+func (h *WSHandler) authenticate(ctx context.Context, r *http.Request) (string, error) {
+	query := r.URL.Query()
+
+	var userID string
+	if ticket := query.Get("ticket"); ticket != "" {
+		err := withTimeout(ctx, func(ctx context.Context) (err error) {
+			userID, err = h.tickets.Redeem(ctx, ticket)
+			return err
+		})
+		return userID, err
 	}
 
-	// Проверяем, не отозван ли токен
-	revoked, err := h.tokenRepo.IsTokenRevoked(context.Background(), tokenString)
-	if err != nil {
-		return nil, err
+	tokenString := query.Get("token")
+	if tokenString == "" {
+		return "", ErrMissingTicket
+	}
+	if !h.allowQueryToken {
+		return "", ErrQueryTokenDenied
 	}
-	if revoked {
-		return nil, errors.New("token revoked")
+	metrics.DeprecatedAPIRequests.Inc("/ws/{chat_id}?token")
+
+	err := withTimeout(ctx, func(ctx context.Context) error {
+		claims, err := server.AuthenticateToken(ctx, tokenString, h.jwtKeys, h.tokenRepo)
+		if err == nil {
+			userID = claims.UserID
+		}
+		return err
+	})
+	return userID, err
+}
+
+func (h *WSHandler) validateChatAccess(ctx context.Context, userID, chatID string) (bool, error) {
+	var isMember bool
+	err := withTimeout(ctx, func(ctx context.Context) (err error) {
+		isMember, err = h.chatRepo.IsUserInChat(ctx, chatID, userID)
+		return err
+	})
+	return err == nil && isMember, err
+}
+
+// withTimeout выполняет одну операцию с БД с ограничением dbOperationTimeout
+// Истечение именно этого ограничения (а не закрытие соединения) возвращается как ErrOperationTimeout
+func withTimeout(ctx context.Context, op func(ctx context.Context) error) error {
+	opCtx, cancel := context.WithTimeout(ctx, dbOperationTimeout)
+	defer cancel()
+
+	err := op(opCtx)
+	if err != nil && ctx.Err() == nil && errors.Is(opCtx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%w: %w", ErrOperationTimeout, err)
 	}
+	return err
+}
 
-	return auth.ValidateToken(tokenString, h.jwtSecret)
+// writeError отправляет клиенту кадр ошибки с тем же кодом, который вернул бы REST
+func writeError(out *outbound, err error, fallback string) {
+	out.send(errorFrame(err, fallback))
 }
 
-func (h *WSHandler) validateChatAccess(userID, chatID string) bool {
-	isMember, err := h.chatRepo.IsUserInChat(context.Background(), chatID, userID)
-	return err == nil && isMember
+// errorFrame формирует кадр ошибки; fallback заменяет общее сообщение internal_error,
+// чтобы клиент видел, какая операция не удалась
+func errorFrame(err error, fallback string) wsproto.Error {
+	e := apperr.From(err)
+	if e.Code == apperr.CodeInternal && fallback != "" {
+		e.Message = fallback
+	}
+	return wsproto.NewError(e)
 }
 
-func (h *WSHandler) registerConnection(chatID string, conn *websocket.Conn) {
+// closeTryAgain закрывает соединение кодом 1013 (Try Again Later), если БД не ответила вовремя при подключении
+// Вызывается до регистрации соединения, когда писателя еще нет
+func closeTryAgain(conn *websocket.Conn) {
+	conn.SetWriteDeadline(time.Now().Add(writeWait))
+	conn.WriteJSON(errorFrame(ErrOperationTimeout, ""))
+	conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "Database timeout"))
+}
+
+// registerConnection добавляет соединение и начинает сессию; после начала остановки возвращает false
+func (h *WSHandler) registerConnection(chatID, userID string, conn *websocket.Conn, cancel context.CancelCauseFunc, out *outbound) bool {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
+	if h.shuttingDown {
+		return false
+	}
+	h.sessions.Add(1)
+
 	if _, ok := h.connections[chatID]; !ok {
-		h.connections[chatID] = make(map[*websocket.Conn]bool)
+		h.connections[chatID] = make(map[*websocket.Conn]*wsSession)
+	}
+	h.connections[chatID][conn] = &wsSession{userID: userID, cancel: cancel, out: out}
+	return true
+}
+
+func (h *WSHandler) isShuttingDown() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.shuttingDown
+}
+
+// Ready - проверка готовности для /readyz: после начала остановки новые соединения не принимаются
+func (h *WSHandler) Ready(ctx context.Context) error {
+	if h.isShuttingDown() {
+		return ErrShuttingDown
 	}
-	h.connections[chatID][conn] = true
+	return nil
 }
 
 func (h *WSHandler) unregisterConnection(chatID string, conn *websocket.Conn) {
@@ -158,70 +338,149 @@ func (h *WSHandler) unregisterConnection(chatID string, conn *websocket.Conn) {
 	}
 }
 
-func (h *WSHandler) keepAlive(conn *websocket.Conn) {
-	ticker := time.NewTicker(pingPeriod)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ticker.C:
-			conn.SetWriteDeadline(time.Now().Add(writeWait))
-			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
-				return
+// DisconnectUser закрывает все WebSocket-соединения пользователя
+// Используется при блокировке, принудительном выходе и удалении аккаунта
+func (h *WSHandler) DisconnectUser(userID string) {
+	h.mu.Lock()
+	var closing []*websocket.Conn
+	for chatID, conns := range h.connections {
+		for conn, session := range conns {
+			if session.userID != userID {
+				continue
 			}
+			session.cancel(ErrSessionTerminated)
+			closing = append(closing, conn)
+			delete(conns, conn)
 		}
+		if len(conns) == 0 {
+			delete(h.connections, chatID)
+		}
+	}
+	h.cancelListeners(func(l *listener) bool { return l.userID == userID }, ErrSessionTerminated)
+	h.mu.Unlock()
+
+	// Запись может ждать до writeWait, поэтому выполняется без h.mu: медленный клиент не задерживает рассылку
+	closeMsg := websocket.FormatCloseMessage(wsproto.CloseSessionTerminated, "Session terminated")
+	for _, conn := range closing {
+		conn.WriteControl(websocket.CloseMessage, closeMsg, time.Now().Add(writeWait))
+		conn.Close()
 	}
 }
 
-func (h *WSHandler) sendChatInfo(conn *websocket.Conn, chatID, userID string) {
-	// Получаем пользователей чата
-	users, err := h.chatRepo.GetChatUsers(context.Background(), chatID)
-	if err != nil {
-		log.Printf("Failed to get chat users: %v", err)
-		conn.WriteJSON(map[string]interface{}{
-			"type":    "error",
-			"message": "Failed to get chat info",
-		})
-		return
+// Shutdown завершает все WebSocket-соединения при остановке сервера
+// Новые подключения отклоняются, клиентам отправляется close 1012 (Service Restart) с подсказкой,
+// когда переподключаться. Затем ожидается завершение сессий: уже принятые сообщения успевают
+// сохраниться в БД и разослаться. Если ctx истекает раньше, оставшиеся соединения закрываются принудительно
+func (h *WSHandler) Shutdown(ctx context.Context) error {
+	h.mu.Lock()
+	h.shuttingDown = true
+	// Потоки SSE и long-poll не принимают сообщений от клиента, поэтому закрываются сразу:
+	// клиенты переподключатся к другому узлу
+	h.cancelListeners(func(*listener) bool { return true }, ErrShuttingDown)
+	var closing []*websocket.Conn
+	for _, conns := range h.connections {
+		for conn := range conns {
+			closing = append(closing, conn)
+		}
 	}
+	h.mu.Unlock()
 
-	// Находим собеседника (исключая текущего пользователя)
-	var interlocutorName string
-	for _, user := range users {
-		if user.ID != userID {
-			interlocutorName = user.Login
-			break
-		}
+	// Закрытие отправляется без h.mu: пока оно пишется, сессии дообрабатывают принятые сообщения и рассылают их
+	closeMsg := websocket.FormatCloseMessage(websocket.CloseServiceRestart, restartCloseReason)
+	for _, conn := range closing {
+		conn.WriteControl(websocket.CloseMessage, closeMsg, time.Now().Add(writeWait))
 	}
 
-	if interlocutorName == "" {
-		interlocutorName = "Unknown"
+	done := make(chan struct{})
+	go func() {
+		h.sessions.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		slog.InfoContext(ctx, "WebSocket connections drained")
+		return nil
+	case <-ctx.Done():
+		h.mu.Lock()
+		for _, conns := range h.connections {
+			for conn, session := range conns {
+				session.cancel(ErrShuttingDown)
+				conn.Close()
+			}
+		}
+		h.mu.Unlock()
+		return fmt.Errorf("websocket drain interrupted: %w", ctx.Err())
 	}
+}
 
-	// Отправляем информацию о чате
-	conn.WriteJSON(map[string]interface{}{
-		"type": "chat_info",
-		"name": interlocutorName,
-	})
+func (h *WSHandler) sendChatInfo(ctx context.Context, out *outbound, chatID, userID string) {
+	name, err := h.interlocutor(ctx, chatID, userID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to get chat users", "chat_id", chatID, "error", err)
+		writeError(out, err, "Failed to get chat info")
+		return
+	}
+	out.send(wsproto.NewChatInfo(name))
 }
 
-func (h *WSHandler) sendHistory(conn *websocket.Conn, chatID string) error {
-	// Получаем историю сообщений
-	messages, err := h.messageRepo.GetByChat(context.Background(), chatID, 500)
+func (h *WSHandler) sendHistory(ctx context.Context, out *outbound, chatID, protocol string) error {
+	history, err := h.history(ctx, chatID, protocol)
 	if err != nil {
 		return err
 	}
+	out.send(history)
+	return nil
+}
+
+// history загружает последние сообщения чата в порядке версии протокола protocol
+func (h *WSHandler) history(ctx context.Context, chatID, protocol string) (wsproto.History, error) {
+	messages, err := h.latestMessages(ctx, chatID)
+	if err != nil {
+		return wsproto.History{}, err
+	}
+	return wsproto.NewHistoryFor(protocol, messages), nil
+}
 
-	// Отправляем историю
-	conn.WriteJSON(map[string]interface{}{
-		"type":     "history",
-		"messages": messages,
+// latestMessages загружает последние historyLimit сообщений чата от старых к новым
+func (h *WSHandler) latestMessages(ctx context.Context, chatID string) ([]*models.Message, error) {
+	var messages []*models.Message
+	err := withTimeout(ctx, func(ctx context.Context) (err error) {
+		messages, err = h.messageRepo.GetByChat(ctx, chatID, historyLimit)
+		return err
 	})
+	if err != nil {
+		return nil, err
+	}
+	// Репозиторий отдает последние сообщения от новых к старым
+	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+		messages[i], messages[j] = messages[j], messages[i]
+	}
+	return messages, nil
+}
 
-	return nil
+// interlocutor возвращает логин собеседника userID в чате
+func (h *WSHandler) interlocutor(ctx context.Context, chatID, userID string) (string, error) {
+	var users []models.User
+	err := withTimeout(ctx, func(ctx context.Context) (err error) {
+		users, err = h.chatRepo.GetChatUsers(ctx, chatID)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	for _, user := range users {
+		if user.ID != userID {
+			return user.Login, nil
+		}
+	}
+	return "Unknown", nil
 }
 
-func (h *WSHandler) handleMessages(conn *websocket.Conn, chatID, userID string) {
+// handleMessages читает кадры в отдельной горутине и обрабатывает их по порядку
+// Чтение продолжается во время обработки, поэтому разрыв соединения сразу отменяет ctx и текущий запрос к БД
+// При плановой остановке сервера уже принятые сообщения дообрабатываются (их ограничивает Shutdown)
+func (h *WSHandler) handleMessages(ctx context.Context, cancel context.CancelCauseFunc, conn *websocket.Conn, out *outbound, chatID, userID string) {
 	conn.SetReadLimit(maxMessageSize)
 	conn.SetReadDeadline(time.Now().Add(pongWait))
 	conn.SetPongHandler(func(string) error {
@@ -229,86 +488,139 @@ func (h *WSHandler) handleMessages(conn *websocket.Conn, chatID, userID string)
 		return nil
 	})
 
-	for {
-		_, msgBytes, err := conn.ReadMessage()
-		if err != nil {
-			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-				log.Printf("WebSocket read error: %v", err)
+	frames := make(chan []byte, frameQueueSize)
+	go func() {
+		defer close(frames)
+		for {
+			_, msgBytes, err := conn.ReadMessage()
+			if err != nil {
+				if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure, websocket.CloseServiceRestart) {
+					slog.WarnContext(ctx, "WebSocket read error", "error", err)
+				}
+				if !h.isShuttingDown() {
+					cancel(ErrConnectionClosed)
+				}
+				return
+			}
+			select {
+			case frames <- msgBytes:
+			case <-ctx.Done():
+				return
 			}
-			break
 		}
+	}()
 
-		var input struct {
-			Type string `json:"type"`
-			Text string `json:"text,omitempty"`
+	for msgBytes := range frames {
+		if ctx.Err() != nil {
+			return
 		}
+		h.handleFrame(ctx, out, chatID, userID, msgBytes)
+	}
+}
 
-		if err := json.Unmarshal(msgBytes, &input); err != nil {
-			log.Printf("Invalid message format: %v", err)
-			conn.WriteJSON(map[string]interface{}{
-				"type":    "error",
-				"message": "Invalid message format",
-			})
-			continue
+// handleFrame обрабатывает одно входящее сообщение клиента
+// Каждое сообщение - отдельная трасса со ссылкой на спан подключения: сессия может длиться часами,
+// и дочерние спаны внутри одной трассы сессии было бы невозможно найти
+func (h *WSHandler) handleFrame(ctx context.Context, out *outbound, chatID, userID string, msgBytes []byte) {
+	ctx, span := tracing.Start(ctx, "WS message",
+		trace.WithNewRoot(),
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithLinks(trace.LinkFromContext(ctx)),
+	)
+	var err error
+	defer func() { tracing.End(span, err) }()
+
+	input, err := wsproto.Decode(msgBytes)
+	if err != nil {
+		slog.DebugContext(ctx, "Invalid WebSocket message format", "error", err)
+		writeError(out, apperr.Wrap(apperr.CodeInvalidBody, err).WithMessage("Invalid message format"), "")
+		return
+	}
+	span.SetAttributes(attribute.String("ws.message.type", input.Type))
+
+	switch input.Type {
+	case wsproto.TypeMessage:
+		// Обработка нового сообщения
+		if input.Text == "" {
+			writeError(out, message.ErrEmptyMessage, "")
+			return
 		}
-
-		switch input.Type {
-		case "message":
-			// Обработка нового сообщения
-			if input.Text == "" {
-				conn.WriteJSON(map[string]interface{}{
-					"type":    "error",
-					"message": "Message text is empty",
-				})
-				continue
-			}
-
-			msg, err := h.messageUC.Execute(context.Background(), chatID, userID, input.Text)
-			if err != nil {
-				log.Printf("Message processing failed: %v", err)
-				conn.WriteJSON(map[string]interface{}{
-					"type":    "error",
-					"message": "Failed to send message",
-				})
-				continue
+		// Проверяется до обращений к БД: каждое сообщение - несколько запросов
+		if h.limiter != nil {
+			if ok, retryAfter := h.limiter.Allow(MessageRateLimit, "user:"+userID); !ok {
+				metrics.RateLimited.Inc(MessageRateLimit)
+				writeError(out, apperr.New(apperr.CodeRateLimited).WithDetail("retry_after", server.RetryAfterSeconds(retryAfter)), "")
+				return
 			}
+		}
 
-			// Добавляем логин отправителя
-			user, err := h.userRepo.GetUserByID(context.Background(), userID)
-			if err == nil && user != nil {
-				msg.Login = user.Login
+		if _, err = h.sendMessage(ctx, chatID, userID, input.Text); err != nil {
+			if ctx.Err() != nil {
+				return // Соединение закрыто, отвечать некому
 			}
-
-			// Рассылаем сообщение всем участникам чата
-			h.broadcastMessage(chatID, map[string]interface{}{
-				"type":    "message",
-				"message": msg,
-			})
-
-		default:
-			log.Printf("Unknown message type: %s", input.Type)
-			conn.WriteJSON(map[string]interface{}{
-				"type":    "error",
-				"message": "Unknown message type",
-			})
+			slog.ErrorContext(ctx, "Message processing failed", "chat_id", chatID, "error", err)
+			writeError(out, err, "Failed to send message")
+			return
 		}
+
+	default:
+		slog.DebugContext(ctx, "Unknown WebSocket message type", "type", input.Type)
+		writeError(out, apperr.New(apperr.CodeUnknownMessageType), "")
 	}
 }
 
-func (h *WSHandler) broadcastMessage(chatID string, msg interface{}) {
-	h.mu.Lock()
-	defer h.mu.Unlock()
+// sendMessage сохраняет сообщение и рассылает его всем подписчикам чата
+// Используется и WebSocket-сессиями, и отправкой через REST
+func (h *WSHandler) sendMessage(ctx context.Context, chatID, userID, text string) (*models.Message, error) {
+	var msg *models.Message
+	err := withTimeout(ctx, func(ctx context.Context) (err error) {
+		msg, err = h.messageUC.Execute(ctx, chatID, userID, text)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
 
-	conns, ok := h.connections[chatID]
-	if !ok {
-		return
+	// Добавляем логин отправителя (сообщение уже сохранено, поэтому ошибка здесь не мешает рассылке)
+	var user *models.User
+	userErr := withTimeout(ctx, func(ctx context.Context) (err error) {
+		user, err = h.userRepo.GetUserByID(ctx, userID)
+		return err
+	})
+	if userErr == nil && user != nil {
+		msg.Login = user.Login
 	}
 
-	for conn := range conns {
-		if err := conn.WriteJSON(msg); err != nil {
-			log.Printf("Broadcast failed: %v", err)
-			conn.Close()
+	h.broadcastMessage(chatID, msg)
+	return msg, nil
+}
+
+// broadcastMessage ставит событие в очереди подписчиков чата
+// Под h.mu выполняются только неблокирующие постановки в очередь: так все подписчики получают сообщения
+// в одном порядке. Запись в сеть (с ограничением writeWait) выполняют писатели соединений вне блокировки
+func (h *WSHandler) broadcastMessage(chatID string, msg *models.Message) {
+	frame := wsproto.NewMessageEvent(msg)
+
+	h.mu.Lock()
+	h.notifyListeners(chatID, msg)
+
+	// Клиент с переполненной очередью отключается и догружает пропущенное при переподключении
+	var slow []*websocket.Conn
+	conns := h.connections[chatID]
+	for conn, session := range conns {
+		if !session.out.send(frame) {
+			session.cancel(ErrSlowConsumer)
+			slow = append(slow, conn)
 			delete(conns, conn)
 		}
 	}
+	if len(conns) == 0 {
+		delete(h.connections, chatID)
+	}
+	h.mu.Unlock()
+
+	for _, conn := range slow {
+		slog.Warn("Broadcast failed", "chat_id", chatID, "error", ErrSlowConsumer)
+		conn.Close()
+	}
 }
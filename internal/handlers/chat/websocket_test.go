@@ -0,0 +1,206 @@
+package chat
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"cursach/internal/models"
+	"cursach/internal/pkg/apperr"
+	"cursach/internal/pkg/wsproto"
+
+	"github.com/gorilla/websocket"
+)
+
+// stuckSession регистрирует в хабе соединение, клиент которого не читает, и забивает его очередь,
+// так что писатель соединения висит на записи в сеть
+func stuckSession(t *testing.T, h *WSHandler, chatID, userID string) context.Context {
+	t.Helper()
+
+	out, _ := dialOutbound(t)
+	ctx, cancel := context.WithCancelCause(context.Background())
+	if !h.registerConnection(chatID, userID, out.conn, cancel, out) {
+		t.Fatal("registerConnection refused the session")
+	}
+
+	frame := wsproto.NewMessageEvent(&models.Message{Text: strings.Repeat("x", 64<<10)})
+	// Очередь считается забитой, когда писатель не забрал из нее ни одного кадра за паузу
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		for out.send(frame) {
+		}
+		time.Sleep(200 * time.Millisecond)
+		if !out.send(frame) {
+			return ctx
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("outbound queue never filled")
+		}
+	}
+}
+
+// update перезаписывает эталоны: go test ./internal/handlers/chat -update
+var update = flag.Bool("update", false, "rewrite testdata/*.golden")
+
+func newTestHub() *WSHandler {
+	return NewWSHandler(nil, nil, nil, nil, nil, nil, nil, false, nil, nil)
+}
+
+// TestBroadcastDisconnectsSlowConsumer проверяет, что рассылка не ждет клиента, который не читает,
+// а отключает его, когда очередь соединения переполнена
+func TestBroadcastDisconnectsSlowConsumer(t *testing.T) {
+	h := newTestHub()
+	ctx := stuckSession(t, h, "chat", "slow")
+
+	done := make(chan struct{})
+	go func() {
+		h.broadcastMessage("chat", &models.Message{Text: "hi"})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("broadcastMessage blocked on a slow consumer")
+	}
+
+	if cause := context.Cause(ctx); !errors.Is(cause, ErrSlowConsumer) {
+		t.Errorf("session cause = %v, want %v", cause, ErrSlowConsumer)
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.connections) != 0 {
+		t.Errorf("slow connection is still registered: %v", h.connections)
+	}
+}
+
+// TestDisconnectUserReleasesHubLock проверяет, что отправка закрытия зависшему клиенту
+// (до writeWait) не держит блокировку хаба
+func TestDisconnectUserReleasesHubLock(t *testing.T) {
+	h := newTestHub()
+	ctx := stuckSession(t, h, "chat", "stuck")
+
+	go h.DisconnectUser("stuck")
+	<-ctx.Done()
+
+	locked := make(chan struct{})
+	go func() {
+		h.isShuttingDown()
+		close(locked)
+	}()
+	select {
+	case <-locked:
+	case <-time.After(time.Second):
+		t.Fatal("DisconnectUser holds the hub lock while writing the close frame")
+	}
+}
+
+// TestHandleNegotiatesSubprotocol проверяет согласование версии протокола при подключении:
+// без заголовка и с поддерживаемой версией рукопожатие проходит, с неизвестной - ответ 400 до переключения протокола
+func TestHandleNegotiatesSubprotocol(t *testing.T) {
+	h := newTestHub()
+	srv := httptest.NewServer(http.HandlerFunc(h.Handle))
+	defer srv.Close()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/ws/c1"
+
+	tests := []struct {
+		name     string
+		offered  []string
+		status   int
+		protocol string
+	}{
+		{name: "none offered", status: http.StatusSwitchingProtocols},
+		{name: "v1", offered: []string{wsproto.ProtocolV1}, status: http.StatusSwitchingProtocols, protocol: wsproto.ProtocolV1},
+		{name: "v2 preferred", offered: []string{wsproto.ProtocolV1, wsproto.ProtocolV2}, status: http.StatusSwitchingProtocols, protocol: wsproto.ProtocolV2},
+		{name: "unsupported", offered: []string{"messenger.v9"}, status: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dialer := websocket.Dialer{Subprotocols: tt.offered}
+			conn, resp, err := dialer.Dial(url, nil)
+			if resp == nil {
+				t.Fatalf("dial: %v", err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != tt.status {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+
+			if tt.status != http.StatusSwitchingProtocols {
+				var body struct {
+					Error struct {
+						Code    string                 `json:"code"`
+						Details map[string]interface{} `json:"details"`
+					} `json:"error"`
+				}
+				if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+					t.Fatalf("failed to decode error body: %v", err)
+				}
+				if body.Error.Code != string(apperr.CodeUnsupportedProtocol) || body.Error.Details["supported"] == nil {
+					t.Errorf("error body = %+v, want unsupported_protocol with supported versions", body.Error)
+				}
+				return
+			}
+
+			defer conn.Close()
+			if got := conn.Subprotocol(); got != tt.protocol {
+				t.Errorf("negotiated subprotocol = %q, want %q", got, tt.protocol)
+			}
+			// Билета нет, поэтому сессия закрывается с кодом ошибки аутентификации
+			conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+			_, _, err = conn.ReadMessage()
+			if !websocket.IsCloseError(err, wsproto.CloseAuthFailed) {
+				t.Errorf("read after handshake: %v, want close %d", err, wsproto.CloseAuthFailed)
+			}
+		})
+	}
+}
+
+// TestHistoryOrderGolden сверяет кадр history каждой версии протокола с эталоном:
+// messenger.v1 сохраняет порядок от новых к старым, messenger.v2 идет от старых к новым
+func TestHistoryOrderGolden(t *testing.T) {
+	messages := &memoryMessages{}
+	for _, msg := range []*models.Message{
+		{ChatID: "c1", UserID: "u1", Login: "alice", Text: "Привет"},
+		{ChatID: "c1", UserID: "u2", Login: "bob", Text: "Привет!"},
+		{ChatID: "c1", UserID: "u1", Login: "alice", Text: "Как дела?"},
+	} {
+		messages.Create(context.Background(), msg)
+	}
+	h := NewWSHandler(nil, nil, nil, nil, messages, nil, nil, false, nil, nil)
+
+	for golden, protocol := range map[string]string{"history_v1": wsproto.ProtocolV1, "history_v2": wsproto.ProtocolV2} {
+		t.Run(protocol, func(t *testing.T) {
+			history, err := h.history(context.Background(), "c1", protocol)
+			if err != nil {
+				t.Fatalf("history: %v", err)
+			}
+			var got bytes.Buffer
+			if err := json.NewEncoder(&got).Encode(history); err != nil {
+				t.Fatalf("encode: %v", err)
+			}
+
+			path := filepath.Join("testdata", golden+".golden")
+			if *update {
+				if err := os.WriteFile(path, got.Bytes(), 0o644); err != nil {
+					t.Fatalf("failed to update %s: %v", path, err)
+				}
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("failed to read %s: %v", path, err)
+			}
+			if !bytes.Equal(got.Bytes(), want) {
+				t.Errorf("history differs from %s\ngot:  %s\nwant: %s", path, got.Bytes(), want)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"context"
+	chathandler "cursach/internal/handlers/chat"
+	"cursach/internal/pkg/apperr"
+	"cursach/internal/pkg/auth"
+	"cursach/internal/pkg/wsproto"
+	"cursach/internal/server"
+	adminusecase "cursach/internal/usecase/admin"
+	chatusecase "cursach/internal/usecase/chat"
+	messageusecase "cursach/internal/usecase/message"
+	userusecase "cursach/internal/usecase/user"
+)
+
+// Единое сопоставление ошибок usecase-слоя кодам API
+// Обработчики REST и WebSocket не выбирают статус сами, а передают ошибку в apperr
+func init() {
+	apperr.Register(apperr.CodeTimeout, context.DeadlineExceeded, chathandler.ErrOperationTimeout)
+	apperr.Register(apperr.CodeUnavailable, chathandler.ErrShuttingDown)
+	apperr.Register(apperr.CodeUnsupportedProtocol, wsproto.ErrUnsupportedProtocol)
+
+	apperr.Register(apperr.CodeInvalidToken, auth.ErrInvalidToken)
+	// Сессия завершается при блокировке, принудительном выходе и удалении аккаунта - вместе с отзывом токенов
+	apperr.Register(apperr.CodeTokenRevoked, server.ErrTokenRevoked, chathandler.ErrSessionTerminated)
+	apperr.Register(apperr.CodeForbidden, userusecase.ErrForbidden)
+	apperr.Register(apperr.CodeEmptyCredentials, userusecase.ErrEmptyCredentials)
+	apperr.Register(apperr.CodeInvalidCredentials, userusecase.ErrInvalidCredentials)
+	apperr.Register(apperr.CodeUserBanned, userusecase.ErrUserBanned)
+	apperr.Register(apperr.CodeTooManyAttempts, userusecase.ErrTooManyAttempts)
+	apperr.Register(apperr.CodeInvalidMFACode, userusecase.ErrInvalidMFACode)
+	apperr.Register(apperr.CodeMFAAlreadyEnabled, userusecase.ErrMFAAlreadyEnabled)
+	apperr.Register(apperr.CodeMFANotEnrolled, userusecase.ErrMFANotEnrolled)
+	apperr.Register(apperr.CodeMFANotEnabled, userusecase.ErrMFANotEnabled)
+
+	apperr.Register(apperr.CodeUserNotFound,
+		userusecase.ErrUserNotFound, adminusecase.ErrUserNotFound, chatusecase.ErrUserNotFound)
+	apperr.Register(apperr.CodeLoginAlreadyExists, userusecase.ErrLoginAlreadyExists)
+	apperr.Register(apperr.CodeInvalidRole, userusecase.ErrInvalidRole, adminusecase.ErrInvalidRole)
+	apperr.Register(apperr.CodeCannotTargetSelf, adminusecase.ErrCannotTargetSelf)
+
+	// Отсутствие чата и отсутствие доступа к нему не различаются, чтобы не раскрывать существование чатов
+	apperr.Register(apperr.CodeChatAccessDenied,
+		chatusecase.ErrChatNotFound, chatusecase.ErrUserNotInChat, messageusecase.ErrUserNotInChat)
+	apperr.Register(apperr.CodeSelfChat, chatusecase.ErrSelfChat)
+	apperr.Register(apperr.CodeValidation, chatusecase.ErrEmptyUsers)
+	apperr.Register(apperr.CodeEmptyMessage, messageusecase.ErrEmptyMessage)
+}
@@ -0,0 +1,118 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// checkTimeout ограничивает время всех проверок готовности, чтобы зависшая БД не вешала пробу
+const checkTimeout = 2 * time.Second
+
+var ErrDraining = errors.New("server is shutting down")
+
+// Check - проверка одной зависимости; nil означает, что зависимость готова
+type Check func(ctx context.Context) error
+
+// CheckResult - результат одной проверки в ответе /readyz
+type CheckResult struct {
+	Status    string  `json:"status"` // ok или fail
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Response - тело ответа /healthz и /readyz
+type Response struct {
+	Status string                 `json:"status"` // ok или fail
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+// Handler отвечает на пробы живости и готовности
+type Handler struct {
+	checks   map[string]Check
+	draining atomic.Bool
+}
+
+// NewHandler создает обработчик с именованными проверками готовности
+func NewHandler(checks map[string]Check) *Handler {
+	return &Handler{checks: checks}
+}
+
+// SetDraining переводит узел в состояние остановки: /readyz отвечает 503 независимо от проверок
+func (h *Handler) SetDraining() {
+	h.draining.Store(true)
+}
+
+// Live обрабатывает /healthz: процесс запущен и обслуживает HTTP
+// Метод: GET
+// Возвращает: 200 {"status": "ok"}
+func (h *Handler) Live(w http.ResponseWriter, r *http.Request) {
+	writeResponse(w, r, http.StatusOK, Response{Status: "ok"})
+}
+
+// Ready обрабатывает /readyz: все проверки выполняются параллельно
+// Метод: GET
+// Возвращает: 200, если все проверки прошли, иначе 503; в теле - статус и задержка каждой проверки
+func (h *Handler) Ready(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), checkTimeout)
+	defer cancel()
+
+	resp := Response{Status: "ok", Checks: make(map[string]CheckResult, len(h.checks)+1)}
+	var (
+		wg sync.WaitGroup
+		mu sync.Mutex
+	)
+	for name, check := range h.checks {
+		wg.Add(1)
+		go func(name string, check Check) {
+			defer wg.Done()
+			result := run(ctx, check)
+			mu.Lock()
+			resp.Checks[name] = result
+			mu.Unlock()
+		}(name, check)
+	}
+	wg.Wait()
+
+	if h.draining.Load() {
+		resp.Checks["shutdown"] = CheckResult{Status: "fail", Error: ErrDraining.Error()}
+	}
+
+	status := http.StatusOK
+	for _, result := range resp.Checks {
+		if result.Status != "ok" {
+			resp.Status = "fail"
+			status = http.StatusServiceUnavailable
+			break
+		}
+	}
+	writeResponse(w, r, status, resp)
+}
+
+func run(ctx context.Context, check Check) CheckResult {
+	start := time.Now()
+	err := check(ctx)
+	result := CheckResult{
+		Status:    "ok",
+		LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Status = "fail"
+		result.Error = err.Error()
+	}
+	return result
+}
+
+func writeResponse(w http.ResponseWriter, r *http.Request, status int, resp Response) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to encode health response", "error", err)
+	}
+}
@@ -0,0 +1,253 @@
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"cursach/internal/server"
+
+	"github.com/gorilla/mux"
+)
+
+// Пакет openapi хранит спецификацию REST API (openapi.json рядом с кодом), отдает ее клиентам
+// и проверяет по ней тела запросов. При добавлении маршрута в SetupRouter его нужно описать в openapi.json:
+// VerifyRoutes не даст серверу запуститься с неописанным маршрутом
+
+//go:embed openapi.json
+var document []byte
+
+const schemaRefPrefix = "#/components/schemas/"
+
+// Schema - подмножество JSON Schema, которое используется в openapi.json
+type Schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 string             `json:"type"`
+	Properties           map[string]*Schema `json:"properties"`
+	Required             []string           `json:"required"`
+	AdditionalProperties *bool              `json:"additionalProperties"`
+	Items                *Schema            `json:"items"`
+	Enum                 []interface{}      `json:"enum"`
+	MinLength            *int               `json:"minLength"`
+	MaxLength            *int               `json:"maxLength"`
+	Minimum              *float64           `json:"minimum"`
+	Maximum              *float64           `json:"maximum"`
+}
+
+// mediaType - описание тела для одного типа содержимого
+type mediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// requestBody - описание тела запроса операции
+type requestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]mediaType `json:"content"`
+}
+
+// operation - операция (метод пути), из которой нужно только тело запроса
+type operation struct {
+	OperationID string       `json:"operationId"`
+	RequestBody *requestBody `json:"requestBody"`
+}
+
+// Spec - разобранная спецификация
+type Spec struct {
+	raw        []byte
+	operations map[string]map[string]*operation // шаблон пути -> метод (GET, POST, ...) -> операция
+	schemas    map[string]*Schema
+	aliases    map[string]string // устаревший префикс -> префикс, под которым маршруты описаны
+}
+
+// httpMethods - ключи объекта пути, которые являются операциями
+var httpMethods = map[string]bool{
+	"get": true, "put": true, "post": true, "delete": true,
+	"options": true, "head": true, "patch": true, "trace": true,
+}
+
+// Load разбирает встроенную спецификацию и проверяет, что все ссылки на схемы разрешаются
+func Load() (*Spec, error) {
+	var doc struct {
+		Paths      map[string]map[string]json.RawMessage `json:"paths"`
+		Components struct {
+			Schemas map[string]*Schema `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(document, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse openapi.json: %w", err)
+	}
+
+	s := &Spec{
+		raw:        document,
+		operations: make(map[string]map[string]*operation, len(doc.Paths)),
+		schemas:    doc.Components.Schemas,
+	}
+	for path, item := range doc.Paths {
+		s.operations[path] = make(map[string]*operation)
+		for key, raw := range item {
+			if !httpMethods[key] {
+				continue // parameters, summary и т.п.
+			}
+			var op operation
+			if err := json.Unmarshal(raw, &op); err != nil {
+				return nil, fmt.Errorf("failed to parse operation %s %s: %w", strings.ToUpper(key), path, err)
+			}
+			if op.RequestBody != nil {
+				for contentType, media := range op.RequestBody.Content {
+					if err := s.checkRefs(media.Schema); err != nil {
+						return nil, fmt.Errorf("%s %s (%s): %w", strings.ToUpper(key), path, contentType, err)
+					}
+				}
+			}
+			s.operations[path][strings.ToUpper(key)] = &op
+		}
+	}
+	return s, nil
+}
+
+// AddAlias описывает маршруты под prefix операциями под target: например, /api/chats проверяется как /api/v1/chats
+// Используется для устаревших псевдонимов версий API, которые в спецификации не дублируются
+func (s *Spec) AddAlias(prefix, target string) {
+	if s.aliases == nil {
+		s.aliases = make(map[string]string)
+	}
+	s.aliases[prefix] = target
+}
+
+// operation ищет операцию по шаблону маршрута mux и методу с учетом псевдонимов
+func (s *Spec) operation(tmpl, method string) *operation {
+	return s.operations[s.canonical(tmpl)][method]
+}
+
+// OperationID возвращает operationId операции, которую обслуживает запрос, или "", если она не описана
+// Должен вызываться внутри маршрутизатора, когда шаблон маршрута уже известен
+func (s *Spec) OperationID(r *http.Request) string {
+	if op := s.operation(server.RouteTemplate(r), r.Method); op != nil {
+		return op.OperationID
+	}
+	return ""
+}
+
+// HasOperation проверяет, что в спецификации есть операция с таким operationId
+func (s *Spec) HasOperation(id string) bool {
+	for _, ops := range s.operations {
+		for _, op := range ops {
+			if op.OperationID == id {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// canonical возвращает шаблон пути, под которым маршрут описан в спецификации
+func (s *Spec) canonical(tmpl string) string {
+	if _, ok := s.operations[tmpl]; ok {
+		return tmpl
+	}
+	for prefix, target := range s.aliases {
+		if strings.HasPrefix(tmpl, prefix+"/") {
+			return target + strings.TrimPrefix(tmpl, prefix)
+		}
+	}
+	return tmpl
+}
+
+// checkRefs проверяет, что все $ref внутри схемы указывают на существующие схемы
+func (s *Spec) checkRefs(schema *Schema) error {
+	if schema == nil {
+		return nil
+	}
+	if schema.Ref != "" {
+		if _, err := s.resolve(schema); err != nil {
+			return err
+		}
+		return nil // Сама схема по ссылке проверяется, когда на нее ссылается операция
+	}
+	for _, prop := range schema.Properties {
+		if err := s.checkRefs(prop); err != nil {
+			return err
+		}
+	}
+	return s.checkRefs(schema.Items)
+}
+
+// resolve возвращает схему по $ref или саму схему, если ссылки нет
+func (s *Spec) resolve(schema *Schema) (*Schema, error) {
+	for depth := 0; schema.Ref != ""; depth++ {
+		if depth > 8 || !strings.HasPrefix(schema.Ref, schemaRefPrefix) {
+			return nil, fmt.Errorf("unsupported schema reference %q", schema.Ref)
+		}
+		target, ok := s.schemas[strings.TrimPrefix(schema.Ref, schemaRefPrefix)]
+		if !ok {
+			return nil, fmt.Errorf("unknown schema reference %q", schema.Ref)
+		}
+		schema = target
+	}
+	return schema, nil
+}
+
+// Handler отдает спецификацию как есть
+// Метод: GET
+// Возвращает: application/json с документом OpenAPI 3
+func (s *Spec) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(s.raw)
+	})
+}
+
+// VerifyRoutes сверяет маршруты mux со спецификацией в обе стороны:
+// каждый маршрут должен быть описан, и каждая операция спецификации должна обслуживаться
+func (s *Spec) VerifyRoutes(router *mux.Router) error {
+	routed := make(map[string]map[string]bool)
+	var errs []error
+
+	err := router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		if route.GetHandler() == nil {
+			return nil // PathPrefix(...).Subrouter() - сам по себе не маршрут
+		}
+		tmpl, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		// Маршруты-префиксы (раздача статики) не входят в API: их регулярное выражение не закреплено в конце
+		if re, err := route.GetPathRegexp(); err == nil && !strings.HasSuffix(re, "$") {
+			return nil
+		}
+
+		methods, err := route.GetMethods()
+		if err != nil {
+			methods = []string{http.MethodGet} // Маршрут без ограничения методов (WebSocket) описывается как GET
+		}
+		path := s.canonical(tmpl)
+		if routed[path] == nil {
+			routed[path] = make(map[string]bool)
+		}
+		for _, method := range methods {
+			routed[path][method] = true
+			if s.operation(tmpl, method) == nil {
+				errs = append(errs, fmt.Errorf("route %s %s is not described in openapi.json", method, tmpl))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to walk routes: %w", err)
+	}
+
+	for path, ops := range s.operations {
+		for method := range ops {
+			if !routed[path][method] {
+				errs = append(errs, fmt.Errorf("openapi.json describes %s %s, but no such route is registered", method, path))
+			}
+		}
+	}
+
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Error() < errs[j].Error() })
+	return errors.Join(errs...)
+}
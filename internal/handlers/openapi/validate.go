@@ -0,0 +1,210 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"unicode/utf8"
+
+	"cursach/internal/pkg/apperr"
+	"cursach/internal/server"
+)
+
+// maxBodySize ограничивает тело запроса, которое проверяется и передается обработчику
+const maxBodySize = 1 << 20 // 1MB
+
+const jsonContentType = "application/json"
+
+// ValidationError - несоответствие тела запроса схеме
+type ValidationError struct {
+	Field  string // Путь к полю: login, items[0].id; пусто - тело целиком
+	Reason string
+}
+
+func (e *ValidationError) Error() string {
+	if e.Field == "" {
+		return e.Reason
+	}
+	return e.Field + ": " + e.Reason
+}
+
+// Middleware проверяет тело запроса по схеме операции, найденной по шаблону маршрута и методу
+// Неописанные операции и операции без тела пропускаются; проверенное тело передается обработчику без изменений
+// Должен подключаться на уровне маршрутизатора, чтобы шаблон маршрута был уже известен
+func (s *Spec) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		op := s.operation(server.RouteTemplate(r), r.Method)
+		if op == nil || op.RequestBody == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxBodySize+1))
+		if err != nil {
+			apperr.Write(w, r, apperr.Wrap(apperr.CodeInvalidBody, err))
+			return
+		}
+		if len(body) > maxBodySize {
+			apperr.Write(w, r, apperr.New(apperr.CodeBodyTooLarge).WithDetail("max_bytes", maxBodySize))
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		if len(bytes.TrimSpace(body)) == 0 {
+			if op.RequestBody.Required {
+				apperr.Write(w, r, apperr.New(apperr.CodeValidation).WithMessage("Request body is required"))
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Без Content-Type тело считается JSON, как и раньше в обработчиках
+		contentType := jsonContentType
+		if header := r.Header.Get("Content-Type"); header != "" {
+			if contentType, _, err = mime.ParseMediaType(header); err != nil {
+				contentType = header
+			}
+		}
+		media, ok := op.RequestBody.Content[contentType]
+		if !ok {
+			apperr.Write(w, r, apperr.New(apperr.CodeUnsupportedMedia).WithDetail("content_type", contentType))
+			return
+		}
+
+		if media.Schema != nil && contentType == jsonContentType {
+			var value interface{}
+			if err := json.Unmarshal(body, &value); err != nil {
+				apperr.Write(w, r, apperr.Wrap(apperr.CodeInvalidBody, err))
+				return
+			}
+			if err := s.validate(media.Schema, value, ""); err != nil {
+				writeValidationError(w, r, err)
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func writeValidationError(w http.ResponseWriter, r *http.Request, err error) {
+	e := apperr.Wrap(apperr.CodeValidation, err).WithMessage("Request body does not match the API schema")
+	if verr, ok := err.(*ValidationError); ok {
+		if verr.Field != "" {
+			e.WithDetail("field", verr.Field)
+		}
+		e.WithDetail("reason", verr.Reason)
+	}
+	apperr.Write(w, r, e)
+}
+
+// validate проверяет значение, полученное json.Unmarshal, по схеме
+func (s *Spec) validate(schema *Schema, value interface{}, field string) error {
+	schema, err := s.resolve(schema)
+	if err != nil {
+		return err
+	}
+
+	if len(schema.Enum) > 0 && !inEnum(schema.Enum, value) {
+		return &ValidationError{Field: field, Reason: fmt.Sprintf("must be one of %v", schema.Enum)}
+	}
+
+	switch schema.Type {
+	case "":
+		return nil
+	case "object":
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return typeError(field, "object")
+		}
+		for _, name := range schema.Required {
+			if _, ok := obj[name]; !ok {
+				return &ValidationError{Field: join(field, name), Reason: "is required"}
+			}
+		}
+		for name, v := range obj {
+			prop, ok := schema.Properties[name]
+			if !ok {
+				if schema.AdditionalProperties != nil && !*schema.AdditionalProperties {
+					return &ValidationError{Field: join(field, name), Reason: "unknown field"}
+				}
+				continue
+			}
+			if err := s.validate(prop, v, join(field, name)); err != nil {
+				return err
+			}
+		}
+	case "array":
+		arr, ok := value.([]interface{})
+		if !ok {
+			return typeError(field, "array")
+		}
+		if schema.Items != nil {
+			for i, item := range arr {
+				if err := s.validate(schema.Items, item, field+"["+strconv.Itoa(i)+"]"); err != nil {
+					return err
+				}
+			}
+		}
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return typeError(field, "string")
+		}
+		length := utf8.RuneCountInString(str)
+		if schema.MinLength != nil && length < *schema.MinLength {
+			if *schema.MinLength == 1 {
+				return &ValidationError{Field: field, Reason: "must not be empty"}
+			}
+			return &ValidationError{Field: field, Reason: fmt.Sprintf("must be at least %d characters", *schema.MinLength)}
+		}
+		if schema.MaxLength != nil && length > *schema.MaxLength {
+			return &ValidationError{Field: field, Reason: fmt.Sprintf("must be at most %d characters", *schema.MaxLength)}
+		}
+	case "integer", "number":
+		num, ok := value.(float64)
+		if !ok || (schema.Type == "integer" && num != math.Trunc(num)) {
+			return typeError(field, schema.Type)
+		}
+		if schema.Minimum != nil && num < *schema.Minimum {
+			return &ValidationError{Field: field, Reason: fmt.Sprintf("must be at least %v", *schema.Minimum)}
+		}
+		if schema.Maximum != nil && num > *schema.Maximum {
+			return &ValidationError{Field: field, Reason: fmt.Sprintf("must be at most %v", *schema.Maximum)}
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return typeError(field, "boolean")
+		}
+	default:
+		return fmt.Errorf("unsupported schema type %q", schema.Type)
+	}
+	return nil
+}
+
+func typeError(field, want string) *ValidationError {
+	return &ValidationError{Field: field, Reason: "must be " + want}
+}
+
+func join(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
+
+func inEnum(enum []interface{}, value interface{}) bool {
+	for _, allowed := range enum {
+		if reflect.DeepEqual(allowed, value) {
+			return true
+		}
+	}
+	return false
+}
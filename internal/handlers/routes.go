@@ -1,56 +1,222 @@
 package handlers
 
 import (
+	adminhandler "cursach/internal/handlers/admin"
 	chathandler "cursach/internal/handlers/chat"
+	healthhandler "cursach/internal/handlers/health"
+	"cursach/internal/handlers/openapi"
 	userhandler "cursach/internal/handlers/user"
+	"cursach/internal/pkg/apperr"
+	"cursach/internal/pkg/auth"
+	"cursach/internal/pkg/metrics"
+	"cursach/internal/pkg/ratelimit"
+	"cursach/internal/pkg/wsproto"
 	"cursach/internal/repository"
 	"cursach/internal/server"
+	adminusecase "cursach/internal/usecase/admin"
+	auditusecase "cursach/internal/usecase/audit"
 	chatusecase "cursach/internal/usecase/chat"
 	userusecase "cursach/internal/usecase/user"
 	"github.com/gorilla/mux"
 	"net/http"
+	"time"
 )
 
-// SetupRouter создает и настраивает маршрутизатор HTTP Вынести, надо посмотреть как сделат не больше 5 методов, посмотреть как через конфиг это организовать yaml
+// Префиксы версий API
+const (
+	apiV1Prefix     = "/api/v1"
+	legacyAPIPrefix = "/api" // Устаревший псевдоним v1 без версии
+)
+
+// Псевдоним /api объявлен устаревшим; после даты Sunset он будет удален
+var (
+	legacyAPIDeprecatedAt = time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	legacyAPISunset       = time.Date(2027, 4, 30, 0, 0, 0, 0, time.UTC)
+)
+
+// SetupRouter создает и настраивает маршрутизатор HTTP
+// Маршруты API монтируются по версиям: v1 на /api/v1 и на устаревший /api; v2 монтируется рядом на свой префикс
 func SetupRouter(
 	chatCreator *chatusecase.ChatCreator,
 	chatDeleter *chatusecase.ChatDeleter,
 	userManager *userusecase.UserManager,
 	userDeleter *userusecase.UserDeleter,
 	authUC *userusecase.Authenticator,
-	jwtSecret string,
+	loginGuard *userusecase.LoginGuard,
+	mfaUC *userusecase.MFAManager,
+	jwtKeys *auth.KeySet,
+	jwtExpiry time.Duration,
 	tokenRepo repository.TokenRepository,
 	logoutUC *userusecase.Logouter,
 	loginUpdater *userusecase.LoginUpdater,
 	wsHandler *chathandler.WSHandler,
+	wsTickets *userusecase.WSTicketManager,
 	chatLister *chatusecase.ChatLister,
 	userSearcher *userusecase.UserSearcher,
+	userAdmin *adminusecase.UserAdministrator,
+	auditReader *auditusecase.EventReader,
+	healthHandler *healthhandler.Handler,
+	apiSpec *openapi.Spec,
+	limiter *ratelimit.Limiter,
+	proxies *server.ProxyPolicy,
 ) *mux.Router {
 	r := mux.NewRouter()
+	r.MethodNotAllowedHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		apperr.Write(w, r, apperr.New(apperr.CodeMethodNotAllowed))
+	})
+	r.Use(server.RequestIDMiddleware, server.TracingMiddleware, server.AccessLogMiddleware, server.MetricsMiddleware, server.ClientIPMiddleware(proxies))
 
-	// Public routes
-	authHandler := userhandler.NewAuthHandler(authUC, jwtSecret)
-	r.Handle("/api/auth", authHandler).Methods("POST")                                // Вход
-	r.Handle("/api/users", userhandler.NewCreateHandler(userManager)).Methods("POST") // Регистрация
+	// Служебные маршруты вне версий API
+	r.Handle("/.well-known/jwks.json", userhandler.NewJWKSHandler(jwtKeys)).Methods("GET") // Открытые ключи JWT
+	r.Handle("/metrics", metrics.Handler()).Methods("GET")                                 // Метрики Prometheus
+	r.HandleFunc("/healthz", healthHandler.Live).Methods("GET")                            // Проба живости
+	r.HandleFunc("/readyz", healthHandler.Ready).Methods("GET")                            // Проба готовности
+	// WebSocket вне protected: браузер не передает Authorization при подключении, поэтому
+	// пользователь проверяется в обработчике по одноразовому билету из POST /api/v1/ws-ticket
+	r.HandleFunc("/ws/{chat_id}", wsHandler.Handle)
 
-	logoutHandler := userhandler.NewLogoutHandler(logoutUC)
+	v1 := &apiV1{
+		validate:       apiSpec.Middleware,
+		authMiddleware: server.JWTAuthMiddleware(jwtKeys, tokenRepo),
+		rateLimit:      server.RateLimitMiddleware(limiter, apiSpec.OperationID),
 
-	// Protected routes
-	protected := r.PathPrefix("/api").Subrouter()
-	protected.Use(server.JWTAuthMiddleware(jwtSecret, tokenRepo))
+		spec:     apiSpec.Handler(),
+		asyncAPI: wsproto.Handler(),
+		auth:     userhandler.NewAuthHandler(authUC, loginGuard, jwtKeys, jwtExpiry),
+		mfaLogin: userhandler.NewMFALoginHandler(mfaUC, loginGuard, jwtKeys, jwtExpiry),
+		register: userhandler.NewCreateHandler(userManager, loginGuard),
 
-	r.HandleFunc("/ws/{chat_id}", wsHandler.Handle)
-	protected.Handle("/chats", chathandler.NewCreateHandler(chatCreator)).Methods("POST")
-	protected.Handle("/chats", chathandler.NewGetChatsHandler(chatLister)).Methods("GET")
-	protected.Handle("/chats/{chat_id}", chathandler.NewDeleteHandler(chatDeleter)).Methods("DELETE")
-	protected.Handle("/user", userhandler.NewGetUserHandler(userManager)).Methods("GET")
-	// protected.Handle("/users/me", userhandler.NewDeleteHandler(userDeleter)).Methods("DELETE")
-	protected.Handle("/users/{user_id}", userhandler.NewDeleteHandler(userDeleter)).Methods("DELETE")
-	protected.Handle("/logout", logoutHandler).Methods("POST")
-	protected.Handle("/users/search", userhandler.NewSearchUsersHandler(userSearcher)).Methods("GET")
-	protected.Handle("/users/login", userhandler.NewUpdateLoginHandler(loginUpdater)).Methods("PUT")
+		createChat:  chathandler.NewCreateHandler(chatCreator),
+		listChats:   chathandler.NewGetChatsHandler(chatLister),
+		deleteChat:  chathandler.NewDeleteHandler(chatDeleter),
+		chatEvents:  http.HandlerFunc(wsHandler.Events),
+		sendMessage: http.HandlerFunc(wsHandler.SendMessage),
+		pollChat:    http.HandlerFunc(wsHandler.Poll),
+		currentUser: userhandler.NewGetUserHandler(userManager),
+		deleteUser:  userhandler.NewDeleteHandler(userDeleter),
+		logout:      userhandler.NewLogoutHandler(logoutUC),
+		wsTicket:    userhandler.NewWSTicketHandler(wsTickets),
+		searchUsers: userhandler.NewSearchUsersHandler(userSearcher),
+		updateLogin: userhandler.NewUpdateLoginHandler(loginUpdater),
+		mfaEnroll:   userhandler.NewMFAEnrollHandler(mfaUC),
+		mfaConfirm:  userhandler.NewMFAConfirmHandler(mfaUC),
+		mfaDisable:  userhandler.NewMFADisableHandler(mfaUC, loginGuard),
+
+		loginAttempts:   userhandler.NewLoginAttemptsHandler(loginGuard),
+		adminListUsers:  adminhandler.NewListUsersHandler(userAdmin),
+		adminGetUser:    adminhandler.NewGetUserHandler(userAdmin),
+		adminDeleteUser: adminhandler.NewDeleteUserHandler(userAdmin),
+		adminChangeRole: adminhandler.NewChangeRoleHandler(userAdmin),
+		adminBan:        adminhandler.NewBanHandler(userAdmin),
+		adminUnban:      adminhandler.NewUnbanHandler(userAdmin),
+		adminLogout:     adminhandler.NewForceLogoutHandler(userAdmin),
+		auditList:       adminhandler.NewListAuditHandler(auditReader),
+		auditExport:     adminhandler.NewExportAuditHandler(auditReader),
+	}
+
+	// /api/v1 регистрируется раньше /api, иначе префикс /api перехватит его запросы
+	// Префиксы задаются со слешем на конце: PathPrefix("/api") совпал бы и с /apiX
+	v1.mount(r.PathPrefix(apiV1Prefix + "/").Subrouter())
+
+	legacy := r.PathPrefix(legacyAPIPrefix + "/").Subrouter()
+	legacy.Use(server.DeprecationMiddleware(legacyAPIPrefix, apiV1Prefix, legacyAPIDeprecatedAt, legacyAPISunset))
+	v1.mount(legacy)
+	apiSpec.AddAlias(legacyAPIPrefix, apiV1Prefix)
+
+	// Сами /api/v1 и /api операций не имеют, но отвечают ошибкой API, а не уходят в раздачу статики
+	// Маршруты без шаблона пути, поэтому VerifyRoutes их не сверяет со спецификацией
+	for _, prefix := range []string{apiV1Prefix, legacyAPIPrefix} {
+		r.MatcherFunc(exactPath(prefix)).HandlerFunc(apiNotFound)
+	}
 
 	r.PathPrefix("/").Handler(http.FileServer(http.Dir("./static/")))
 
 	return r
 }
+
+// exactPath совпадает только с путем path, без вложенных путей
+func exactPath(path string) mux.MatcherFunc {
+	return func(r *http.Request, _ *mux.RouteMatch) bool {
+		return r.URL.Path == path
+	}
+}
+
+// apiNotFound отвечает 404 в общем формате ошибок API
+func apiNotFound(w http.ResponseWriter, r *http.Request) {
+	apperr.Write(w, r, apperr.New(apperr.CodeNotFound))
+}
+
+// apiV1 - обработчики версии v1; создаются один раз и обслуживают все префиксы, на которые смонтирована версия
+type apiV1 struct {
+	validate       mux.MiddlewareFunc // Проверка тел запросов по openapi.json
+	authMiddleware mux.MiddlewareFunc
+	rateLimit      mux.MiddlewareFunc // По пользователю, для публичных маршрутов - по IP
+
+	spec, asyncAPI, auth, mfaLogin, register http.Handler
+
+	createChat, listChats, deleteChat                         http.Handler
+	chatEvents, sendMessage, pollChat                         http.Handler // Резервные транспорты чата, если WebSocket недоступен
+	currentUser, deleteUser, logout, searchUsers, updateLogin http.Handler
+	wsTicket                                                  http.Handler
+	mfaEnroll, mfaConfirm, mfaDisable                         http.Handler
+	loginAttempts                                             http.Handler
+	adminListUsers, adminGetUser, adminDeleteUser             http.Handler
+	adminChangeRole, adminBan, adminUnban, adminLogout        http.Handler
+	auditList, auditExport                                    http.Handler
+}
+
+// mount регистрирует маршруты v1 на подмаршрутизаторе api (пути указываются относительно префикса)
+func (v *apiV1) mount(api *mux.Router) {
+	api.Use(v.validate)
+
+	// Public routes
+	public := api.NewRoute().Subrouter()
+	public.Use(v.rateLimit)
+
+	public.Handle("/openapi.json", v.spec).Methods("GET")      // Спецификация API
+	public.Handle("/asyncapi.json", v.asyncAPI).Methods("GET") // Описание протокола WebSocket
+	public.Handle("/auth", v.auth).Methods("POST")             // Вход
+	public.Handle("/auth/mfa", v.mfaLogin).Methods("POST")     // Вход, шаг 2FA
+	public.Handle("/users", v.register).Methods("POST")        // Регистрация
+
+	// Protected routes
+	protected := api.NewRoute().Subrouter()
+	protected.Use(v.authMiddleware, v.rateLimit)
+
+	protected.Handle("/chats", v.createChat).Methods("POST")
+	protected.Handle("/chats", v.listChats).Methods("GET")
+	protected.Handle("/chats/{chat_id}", v.deleteChat).Methods("DELETE")
+	protected.Handle("/chats/{chat_id}/events", v.chatEvents).Methods("GET") // Поток SSE
+	protected.Handle("/chats/{chat_id}/messages", v.sendMessage).Methods("POST")
+	protected.Handle("/chats/{chat_id}/messages", v.pollChat).Methods("GET") // Long-poll
+	protected.Handle("/user", v.currentUser).Methods("GET")
+	protected.Handle("/users/{user_id}", v.deleteUser).Methods("DELETE")
+	protected.Handle("/logout", v.logout).Methods("POST")
+	protected.Handle("/ws-ticket", v.wsTicket).Methods("POST")
+	protected.Handle("/users/search", v.searchUsers).Methods("GET")
+	protected.Handle("/users/login", v.updateLogin).Methods("PUT")
+	protected.Handle("/users/mfa/enroll", v.mfaEnroll).Methods("POST")
+	protected.Handle("/users/mfa/confirm", v.mfaConfirm).Methods("POST")
+	protected.Handle("/users/mfa/disable", v.mfaDisable).Methods("POST")
+
+	// Admin routes: доступны только пользователям с ролью admin
+	admin := protected.PathPrefix("/admin").Subrouter()
+	admin.Use(server.RequireRole(server.RoleAdmin))
+	requireViewAttempts := server.RequirePermission(server.PermissionViewLoginAttempts)
+	admin.Handle("/login-attempts", requireViewAttempts(v.loginAttempts)).Methods("GET")
+
+	users := admin.PathPrefix("/users").Subrouter()
+	users.Use(server.RequirePermission(server.PermissionManageUsers))
+	users.Handle("", v.adminListUsers).Methods("GET")
+	users.Handle("/{user_id}", v.adminGetUser).Methods("GET")
+	users.Handle("/{user_id}", v.adminDeleteUser).Methods("DELETE")
+	users.Handle("/{user_id}/role", v.adminChangeRole).Methods("PUT")
+	users.Handle("/{user_id}/ban", v.adminBan).Methods("POST")
+	users.Handle("/{user_id}/ban", v.adminUnban).Methods("DELETE")
+	users.Handle("/{user_id}/logout", v.adminLogout).Methods("POST")
+
+	auditRoutes := admin.PathPrefix("/audit").Subrouter()
+	auditRoutes.Use(server.RequirePermission(server.PermissionViewAudit))
+	auditRoutes.Handle("", v.auditList).Methods("GET")
+	auditRoutes.Handle("/export", v.auditExport).Methods("GET")
+}
@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"cursach/internal/handlers/openapi"
+
+	"github.com/gorilla/mux"
+)
+
+// newTestRouter собирает настоящий маршрутизатор приложения; зависимости обработчиков не нужны,
+// так как запросы не выполняются - проверяется только набор маршрутов
+func newTestRouter(t *testing.T) (*mux.Router, *openapi.Spec) {
+	t.Helper()
+
+	spec, err := openapi.Load()
+	if err != nil {
+		t.Fatalf("failed to load openapi.json: %v", err)
+	}
+	router := SetupRouter(nil, nil, nil, nil, nil, nil, nil, nil, 0, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, spec, nil, nil)
+	return router, spec
+}
+
+// copyRoutes переносит конечные маршруты router в новый маршрутизатор, пропуская те, для которых skip возвращает true
+func copyRoutes(t *testing.T, router *mux.Router, skip func(tmpl, method string) bool) *mux.Router {
+	t.Helper()
+
+	copied := mux.NewRouter()
+	err := router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		if route.GetHandler() == nil {
+			return nil
+		}
+		tmpl, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		if re, err := route.GetPathRegexp(); err == nil && !strings.HasSuffix(re, "$") {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			methods = []string{http.MethodGet}
+		}
+		for _, method := range methods {
+			if !skip(tmpl, method) {
+				copied.Handle(tmpl, route.GetHandler()).Methods(method)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to walk routes: %v", err)
+	}
+	return copied
+}
+
+// TestRoutesMatchOpenAPI сверяет маршруты приложения с openapi.json, как это делает main при запуске
+func TestRoutesMatchOpenAPI(t *testing.T) {
+	router, spec := newTestRouter(t)
+	if err := spec.VerifyRoutes(router); err != nil {
+		t.Fatalf("routes do not match openapi.json:\n%v", err)
+	}
+}
+
+// TestVerifyRoutesReportsMismatch проверяет, что VerifyRoutes находит расхождения в обе стороны
+func TestVerifyRoutesReportsMismatch(t *testing.T) {
+	t.Run("missing route", func(t *testing.T) {
+		router, spec := newTestRouter(t)
+		// Маршрут убирается и из /api/v1, и из устаревшего /api: оба описаны одной операцией
+		missing := copyRoutes(t, router, func(tmpl, method string) bool {
+			return method == http.MethodPost &&
+				(tmpl == apiV1Prefix+"/chats/{chat_id}/messages" || tmpl == legacyAPIPrefix+"/chats/{chat_id}/messages")
+		})
+
+		err := spec.VerifyRoutes(missing)
+		want := "openapi.json describes POST /api/v1/chats/{chat_id}/messages, but no such route is registered"
+		if err == nil || err.Error() != want {
+			t.Errorf("VerifyRoutes() = %v, want %q", err, want)
+		}
+	})
+
+	t.Run("undescribed route", func(t *testing.T) {
+		router, spec := newTestRouter(t)
+		router.HandleFunc(apiV1Prefix+"/undocumented", func(http.ResponseWriter, *http.Request) {}).Methods(http.MethodGet)
+
+		err := spec.VerifyRoutes(router)
+		want := "route GET /api/v1/undocumented is not described in openapi.json"
+		if err == nil || err.Error() != want {
+			t.Errorf("VerifyRoutes() = %v, want %q", err, want)
+		}
+	})
+}
+
+// TestAPIPrefixBoundaries проверяет, что префиксы API совпадают только по границе сегмента пути,
+// а сами корни /api и /api/v1 отвечают ошибкой API
+func TestAPIPrefixBoundaries(t *testing.T) {
+	router, _ := newTestRouter(t)
+
+	tests := []struct {
+		path       string
+		status     int
+		deprecated bool
+		apiError   bool
+	}{
+		{path: "/api/v1/openapi.json", status: http.StatusOK},
+		{path: "/api/openapi.json", status: http.StatusOK, deprecated: true},
+		{path: "/api/v1", status: http.StatusNotFound, apiError: true},
+		{path: "/api", status: http.StatusNotFound, apiError: true},
+		{path: "/apiX/openapi.json", status: http.StatusNotFound},
+		{path: "/api/v1X/openapi.json", status: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if w.Code != tt.status {
+				t.Errorf("status = %d, want %d", w.Code, tt.status)
+			}
+			if got := w.Header().Get("Deprecation") != ""; got != tt.deprecated {
+				t.Errorf("Deprecation header present = %v, want %v", got, tt.deprecated)
+			}
+			var body struct {
+				Error struct {
+					Code string `json:"code"`
+				} `json:"error"`
+			}
+			isAPIError := json.Unmarshal(w.Body.Bytes(), &body) == nil && body.Error.Code == "not_found"
+			if isAPIError != tt.apiError {
+				t.Errorf("API error body = %v, want %v (body %q)", isAPIError, tt.apiError, w.Body.String())
+			}
+		})
+	}
+}
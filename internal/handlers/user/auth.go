@@ -2,23 +2,36 @@ package user
 
 import (
 	"encoding/json"
-	"log"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
 	"net/http"
+	"strconv"
 	"time"
 
+	"cursach/internal/pkg/apperr"
 	"cursach/internal/pkg/auth"
+	"cursach/internal/server"
 	"cursach/internal/usecase/user"
 )
 
+// mfaPendingTTL - время жизни промежуточного токена между вводом пароля и кода 2FA
+const mfaPendingTTL = 5 * time.Minute
+
 type AuthHandler struct {
-	authUC    *user.Authenticator
-	jwtSecret string
+	authUC     *user.Authenticator
+	loginGuard *user.LoginGuard
+	jwtKeys    *auth.KeySet
+	tokenTTL   time.Duration
 }
 
-func NewAuthHandler(authUC *user.Authenticator, jwtSecret string) *AuthHandler {
+func NewAuthHandler(authUC *user.Authenticator, loginGuard *user.LoginGuard, jwtKeys *auth.KeySet, tokenTTL time.Duration) *AuthHandler {
 	return &AuthHandler{
-		authUC:    authUC,
-		jwtSecret: jwtSecret,
+		authUC:     authUC,
+		loginGuard: loginGuard,
+		jwtKeys:    jwtKeys,
+		tokenTTL:   tokenTTL,
 	}
 }
 
@@ -27,39 +40,84 @@ type AuthRequest struct {
 	Password string `json:"password"`
 }
 
+// AuthResponse содержит либо токен доступа, либо промежуточный токен, если включена 2FA
 type AuthResponse struct {
-	Token string `json:"token"`
+	Token       string `json:"token,omitempty"`
+	MFARequired bool   `json:"mfa_required,omitempty"`
+	MFAToken    string `json:"mfa_token,omitempty"`
 }
 
 func (h *AuthHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Only POST method is allowed", http.StatusMethodNotAllowed)
+		apperr.Write(w, r, apperr.New(apperr.CodeMethodNotAllowed))
 		return
 	}
 
 	var req AuthRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		apperr.Write(w, r, apperr.Wrap(apperr.CodeInvalidBody, err))
+		return
+	}
+
+	ip := server.ClientIP(r)
+
+	// Проверяем, не заблокирован ли вход для логина или IP
+	if err := h.loginGuard.Check(r.Context(), req.Login, ip); err != nil {
+		writeLoginGuardError(w, r, err)
 		return
 	}
 
-	authUser, err := h.authUC.Authenticate(r.Context(), req.Login, req.Password)
+	authUser, mfaRequired, err := h.authUC.Authenticate(r.Context(), req.Login, req.Password)
 	if err != nil {
-		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+		if errors.Is(err, user.ErrInvalidCredentials) {
+			if err := h.loginGuard.RegisterFailure(r.Context(), req.Login, ip); err != nil {
+				writeLoginGuardError(w, r, err)
+				return
+			}
+		}
+		apperr.Write(w, r, err)
 		return
 	}
-	token, err := auth.GenerateJWT(authUser, h.jwtSecret, 24*time.Hour)
+
+	if mfaRequired {
+		// Счетчик неудач не сбрасываем до ввода кода, иначе перебор кодов 2FA не ограничен
+		mfaToken, err := auth.GenerateMFAPendingJWT(authUser, h.jwtKeys, mfaPendingTTL)
+		if err != nil {
+			apperr.Write(w, r, fmt.Errorf("failed to generate MFA token: %w", err))
+			return
+		}
+		writeAuthResponse(w, r, AuthResponse{MFARequired: true, MFAToken: mfaToken})
+		return
+	}
+
+	if err := h.loginGuard.RegisterSuccess(r.Context(), req.Login, ip); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to register successful login", "error", err)
+	}
+
+	token, err := auth.GenerateJWT(authUser, h.jwtKeys, h.tokenTTL)
 	if err != nil {
-		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+		apperr.Write(w, r, fmt.Errorf("failed to generate token: %w", err))
 		return
 	}
 
-	resp := AuthResponse{Token: token}
-	w.Header().Set("Content-Type", "application/json")
+	writeAuthResponse(w, r, AuthResponse{Token: token})
+}
 
-	// Добавлена обработка ошибки кодирования
+func writeAuthResponse(w http.ResponseWriter, r *http.Request, resp AuthResponse) {
+	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(resp); err != nil {
-		log.Printf("Failed to encode auth response: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Failed to encode auth response", "error", err)
+	}
+}
+
+// writeLoginGuardError отвечает 429 с Retry-After при блокировке входа или 500 при ошибке хранилища
+func writeLoginGuardError(w http.ResponseWriter, r *http.Request, err error) {
+	var locked *user.LockedError
+	if errors.As(err, &locked) {
+		seconds := int(math.Ceil(locked.RetryAfter.Seconds()))
+		w.Header().Set("Retry-After", strconv.Itoa(seconds))
+		apperr.Write(w, r, apperr.Wrap(apperr.CodeTooManyAttempts, err).WithDetail("retry_after", seconds))
+		return
 	}
+	apperr.Write(w, r, fmt.Errorf("login guard: %w", err))
 }
@@ -3,20 +3,24 @@ package user
 import (
 	"encoding/json"
 	"errors"
-	"log"
+	"log/slog"
 	"net/http"
 
+	"cursach/internal/pkg/apperr"
+	"cursach/internal/server"
 	"cursach/internal/usecase/user"
 )
 
 // CreateHandler обрабатывает HTTP запросы для создания/авторизации пользователей
 type CreateHandler struct {
-	useCase *user.UserManager
+	useCase    *user.UserManager
+	loginGuard *user.LoginGuard
 }
 
 // NewCreateHandler создает новый экземпляр CreateHandler
-func NewCreateHandler(useCase *user.UserManager) *CreateHandler {
-	return &CreateHandler{useCase: useCase}
+// loginGuard нужен, так как для существующего логина обработчик проверяет пароль
+func NewCreateHandler(useCase *user.UserManager, loginGuard *user.LoginGuard) *CreateHandler {
+	return &CreateHandler{useCase: useCase, loginGuard: loginGuard}
 }
 
 // CreateRequest представляет структуру запроса для создания/авторизации пользователя
@@ -37,27 +41,40 @@ type CreateResponse struct {
 // Возвращает: JSON с user_id или сообщение об ошибке
 func (h *CreateHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Only POST method is allowed", http.StatusMethodNotAllowed)
+		apperr.Write(w, r, apperr.New(apperr.CodeMethodNotAllowed))
 		return
 	}
 
 	var req CreateRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		apperr.Write(w, r, apperr.Wrap(apperr.CodeInvalidBody, err))
+		return
+	}
+
+	// Роль admin выдается только администратором, самостоятельная регистрация создает обычного пользователя
+	if req.Role == "" {
+		req.Role = server.RoleUser
+	}
+	if req.Role != server.RoleUser {
+		apperr.Write(w, r, apperr.New(apperr.CodeForbidden).WithMessage("Only the user role can be requested on registration"))
+		return
+	}
+
+	ip := server.ClientIP(r)
+	if err := h.loginGuard.Check(r.Context(), req.Login, ip); err != nil {
+		writeLoginGuardError(w, r, err)
 		return
 	}
 
 	userID, err := h.useCase.CreateOrGetUser(r.Context(), req.Login, req.Password, req.Role)
 	if err != nil {
-		switch {
-		case errors.Is(err, user.ErrEmptyCredentials),
-			errors.Is(err, user.ErrInvalidRole):
-			http.Error(w, err.Error(), http.StatusBadRequest)
-		case errors.Is(err, user.ErrInvalidCredentials):
-			http.Error(w, err.Error(), http.StatusUnauthorized)
-		default:
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		if errors.Is(err, user.ErrInvalidCredentials) {
+			if err := h.loginGuard.RegisterFailure(r.Context(), req.Login, ip); err != nil {
+				writeLoginGuardError(w, r, err)
+				return
+			}
 		}
+		apperr.Write(w, r, err)
 		return
 	}
 
@@ -65,7 +82,6 @@ func (h *CreateHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	if err := json.NewEncoder(w).Encode(resp); err != nil {
-		log.Printf("Failed to encode user creation response: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Failed to encode user creation response", "error", err)
 	}
 }
@@ -1,11 +1,10 @@
 package user
 
 import (
-	"errors"
-	"log"
 	"net/http"
 
-	_ "cursach/internal/server"
+	"cursach/internal/pkg/apperr"
+	"cursach/internal/server"
 	"cursach/internal/usecase/user"
 	"github.com/gorilla/mux"
 )
@@ -25,23 +24,20 @@ func NewDeleteHandler(useCase *user.UserDeleter) *DeleteHandler {
 // Параметры: user_id в URL, текущий user_id из контекста JWT
 // Возвращает: HTTP статус 204 при успехе или сообщение об ошибке
 func (h *DeleteHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	log.Println("correct")
 	if r.Method != http.MethodDelete {
-		http.Error(w, "Only DELETE method is allowed", http.StatusMethodNotAllowed)
+		apperr.Write(w, r, apperr.New(apperr.CodeMethodNotAllowed))
 		return
 	}
 
 	// Получаем currentUserID из контекста
-	currentUserID, ok := r.Context().Value("user_id").(string)
+	currentUserID, ok := server.UserIDFromContext(r.Context())
 	if !ok || currentUserID == "" {
-		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		apperr.Write(w, r, apperr.New(apperr.CodeUnauthorized))
 		return
 	}
 
 	vars := mux.Vars(r)
 	requestedUserID := vars["user_id"]
-	log.Println("delete", requestedUserID)
-	log.Println("delete", currentUserID)
 
 	// Определяем ID пользователя для удаления
 	userIDToDelete := requestedUserID
@@ -51,19 +47,14 @@ func (h *DeleteHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	// Проверяем права доступа (ТОЛЬКО ЗДЕСЬ)
 	if currentUserID != userIDToDelete {
-		http.Error(w, "You can only delete your own account", http.StatusForbidden)
+		apperr.Write(w, r, apperr.New(apperr.CodeForbidden).WithMessage("You can only delete your own account"))
 		return
 	}
 
 	// Выполняем удаление (передаем ТОЛЬКО userIDToDelete)
 	err := h.useCase.Execute(r.Context(), userIDToDelete)
 	if err != nil {
-		switch {
-		case errors.Is(err, user.ErrUserNotFound):
-			http.Error(w, err.Error(), http.StatusNotFound)
-		default:
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
-		}
+		apperr.Write(w, r, err)
 		return
 	}
 
@@ -2,9 +2,12 @@ package user
 
 import (
 	"encoding/json"
-	"log"
+	"fmt"
+	"log/slog"
 	"net/http"
 
+	"cursach/internal/pkg/apperr"
+	"cursach/internal/server"
 	"cursach/internal/usecase/user"
 )
 
@@ -17,16 +20,15 @@ func NewGetUserHandler(userManager *user.UserManager) *GetUserHandler {
 }
 
 func (h *GetUserHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	userID, ok := r.Context().Value("user_id").(string)
+	userID, ok := server.UserIDFromContext(r.Context())
 	if !ok || userID == "" {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		apperr.Write(w, r, apperr.New(apperr.CodeUnauthorized))
 		return
 	}
 
 	userData, err := h.userManager.GetUserByID(r.Context(), userID)
 	if err != nil {
-		log.Printf("Failed to get user by ID: %v", err)
-		http.Error(w, "User not found", http.StatusNotFound)
+		apperr.Write(w, r, fmt.Errorf("failed to get user by ID: %w", err))
 		return
 	}
 
@@ -60,6 +62,6 @@ func (h *GetUserHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(response); err != nil {
-		log.Printf("Failed to encode user response: %v", err)
+		slog.ErrorContext(r.Context(), "Failed to encode user response", "error", err)
 	}
 }
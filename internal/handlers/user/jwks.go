@@ -0,0 +1,32 @@
+package user
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"cursach/internal/pkg/auth"
+)
+
+// JWKSHandler отдает открытые ключи подписи JWT в формате JWKS
+// Позволяет другим сервисам проверять наши токены без общего секрета
+type JWKSHandler struct {
+	jwtKeys *auth.KeySet
+}
+
+// NewJWKSHandler создает новый экземпляр JWKSHandler
+func NewJWKSHandler(jwtKeys *auth.KeySet) *JWKSHandler {
+	return &JWKSHandler{jwtKeys: jwtKeys}
+}
+
+// ServeHTTP обрабатывает HTTP запрос набора ключей
+// Метод: GET
+// Возвращает: JSON {"keys": [...]} по RFC 7517
+func (h *JWKSHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	// Ключи меняются только при ротации, поэтому клиентам можно кешировать ответ
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	if err := json.NewEncoder(w).Encode(h.jwtKeys.JWKS()); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to encode JWKS response", "error", err)
+	}
+}
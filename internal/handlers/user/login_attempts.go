@@ -0,0 +1,56 @@
+package user
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"cursach/internal/pkg/apperr"
+	"cursach/internal/usecase/user"
+)
+
+const (
+	defaultAttemptsLimit = 100
+	maxAttemptsLimit     = 1000
+)
+
+// LoginAttemptsHandler отдает администратору журнал попыток входа
+type LoginAttemptsHandler struct {
+	loginGuard *user.LoginGuard
+}
+
+// NewLoginAttemptsHandler создает новый экземпляр LoginAttemptsHandler
+func NewLoginAttemptsHandler(loginGuard *user.LoginGuard) *LoginAttemptsHandler {
+	return &LoginAttemptsHandler{loginGuard: loginGuard}
+}
+
+// ServeHTTP обрабатывает HTTP запрос журнала попыток входа
+// Метод: GET
+// Параметры: login, ip и limit в query (все необязательные)
+// Возвращает: JSON массив попыток, самые новые первыми
+func (h *LoginAttemptsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	limit := defaultAttemptsLimit
+	if raw := query.Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 || n > maxAttemptsLimit {
+			apperr.Write(w, r, apperr.New(apperr.CodeValidation).WithMessage("limit must be between 1 and 1000").WithDetail("param", "limit"))
+			return
+		}
+		limit = n
+	}
+
+	attempts, err := h.loginGuard.ListAttempts(r.Context(), query.Get("login"), query.Get("ip"), limit)
+	if err != nil {
+		apperr.Write(w, r, fmt.Errorf("failed to list login attempts: %w", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(attempts); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to encode login attempts response", "error", err)
+	}
+}
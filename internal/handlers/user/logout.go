@@ -2,10 +2,13 @@ package user
 
 import (
 	"encoding/json"
-	"log"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"strings"
 
+	"cursach/internal/pkg/apperr"
+	"cursach/internal/server"
 	"cursach/internal/usecase/user"
 )
 
@@ -19,26 +22,26 @@ func NewLogoutHandler(logoutUC *user.Logouter) *LogoutHandler {
 
 func (h *LogoutHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Only POST method is allowed", http.StatusMethodNotAllowed)
+		apperr.Write(w, r, apperr.New(apperr.CodeMethodNotAllowed))
 		return
 	}
 
-	userID, ok := r.Context().Value("user_id").(string)
+	userID, ok := server.UserIDFromContext(r.Context())
 	if !ok {
-		http.Error(w, "User ID header not set", http.StatusBadRequest)
+		apperr.Write(w, r, apperr.New(apperr.CodeUnauthorized))
+		return
 	}
-	log.Println("Logout", userID)
 
 	// Извлекаем токен из заголовка Authorization
 	authHeader := r.Header.Get("Authorization")
 	if authHeader == "" {
-		http.Error(w, "Authorization header is required", http.StatusUnauthorized)
+		apperr.Write(w, r, apperr.New(apperr.CodeUnauthorized).WithMessage("Authorization header is required"))
 		return
 	}
 
 	tokenParts := strings.Split(authHeader, " ")
 	if len(tokenParts) != 2 || tokenParts[0] != "Bearer" {
-		http.Error(w, "Invalid authorization header format", http.StatusUnauthorized)
+		apperr.Write(w, r, apperr.New(apperr.CodeInvalidToken).WithMessage("Invalid authorization header format"))
 		return
 	}
 	token := tokenParts[1]
@@ -46,13 +49,12 @@ func (h *LogoutHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// Инвалидируем токен
 	err := h.logoutUC.Logout(r.Context(), token, userID)
 	if err != nil {
-		http.Error(w, "Failed to logout", http.StatusInternalServerError)
+		apperr.Write(w, r, fmt.Errorf("failed to logout: %w", err))
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(map[string]string{"message": "Successfully logged out"}); err != nil {
-		log.Printf("Failed to encode logout response: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Failed to encode logout response", "error", err)
 	}
 }
@@ -0,0 +1,246 @@
+package user
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"cursach/internal/pkg/apperr"
+	"cursach/internal/pkg/auth"
+	"cursach/internal/server"
+	"cursach/internal/usecase/user"
+)
+
+// MFALoginHandler обрабатывает второй шаг входа: обмен промежуточного токена и кода 2FA на JWT
+type MFALoginHandler struct {
+	mfaUC      *user.MFAManager
+	loginGuard *user.LoginGuard
+	jwtKeys    *auth.KeySet
+	tokenTTL   time.Duration
+}
+
+// NewMFALoginHandler создает новый экземпляр MFALoginHandler
+func NewMFALoginHandler(mfaUC *user.MFAManager, loginGuard *user.LoginGuard, jwtKeys *auth.KeySet, tokenTTL time.Duration) *MFALoginHandler {
+	return &MFALoginHandler{
+		mfaUC:      mfaUC,
+		loginGuard: loginGuard,
+		jwtKeys:    jwtKeys,
+		tokenTTL:   tokenTTL,
+	}
+}
+
+// MFALoginRequest представляет запрос второго шага входа
+type MFALoginRequest struct {
+	MFAToken string `json:"mfa_token"`
+	Code     string `json:"code"` // Код TOTP или код восстановления
+}
+
+// ServeHTTP обрабатывает HTTP запрос второго шага входа
+// Метод: POST
+// Параметры: JSON с mfa_token и code
+// Возвращает: JSON с token или сообщение об ошибке
+func (h *MFALoginHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		apperr.Write(w, r, apperr.New(apperr.CodeMethodNotAllowed))
+		return
+	}
+
+	var req MFALoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apperr.Write(w, r, apperr.Wrap(apperr.CodeInvalidBody, err))
+		return
+	}
+
+	claims, err := auth.ValidateMFAPendingToken(req.MFAToken, h.jwtKeys)
+	if err != nil {
+		apperr.Write(w, r, apperr.Wrap(apperr.CodeInvalidMFAToken, err))
+		return
+	}
+
+	ip := server.ClientIP(r)
+	if err := h.loginGuard.Check(r.Context(), claims.Login, ip); err != nil {
+		writeLoginGuardError(w, r, err)
+		return
+	}
+
+	authUser, err := h.mfaUC.CompleteLogin(r.Context(), claims.UserID, req.Code)
+	if err != nil {
+		switch {
+		case errors.Is(err, user.ErrInvalidMFACode):
+			if err := h.loginGuard.RegisterFailure(r.Context(), claims.Login, ip); err != nil {
+				writeLoginGuardError(w, r, err)
+				return
+			}
+			// На шаге входа неверный код - это неудачная аутентификация, а не ошибка запроса
+			apperr.Write(w, r, apperr.Wrap(apperr.CodeInvalidMFACode, err).WithStatus(http.StatusUnauthorized))
+		case errors.Is(err, user.ErrMFANotEnabled),
+			errors.Is(err, user.ErrUserNotFound):
+			apperr.Write(w, r, apperr.Wrap(apperr.CodeInvalidMFAToken, err))
+		default:
+			apperr.Write(w, r, err)
+		}
+		return
+	}
+
+	if err := h.loginGuard.RegisterSuccess(r.Context(), claims.Login, ip); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to register successful login", "error", err)
+	}
+
+	token, err := auth.GenerateJWT(authUser, h.jwtKeys, h.tokenTTL)
+	if err != nil {
+		apperr.Write(w, r, fmt.Errorf("failed to generate token: %w", err))
+		return
+	}
+
+	writeAuthResponse(w, r, AuthResponse{Token: token})
+}
+
+// MFAEnrollHandler начинает подключение 2FA для текущего пользователя
+type MFAEnrollHandler struct {
+	mfaUC *user.MFAManager
+}
+
+// NewMFAEnrollHandler создает новый экземпляр MFAEnrollHandler
+func NewMFAEnrollHandler(mfaUC *user.MFAManager) *MFAEnrollHandler {
+	return &MFAEnrollHandler{mfaUC: mfaUC}
+}
+
+// ServeHTTP обрабатывает HTTP запрос на подключение 2FA
+// Метод: POST
+// Возвращает: JSON с secret и otpauth_uri
+func (h *MFAEnrollHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		apperr.Write(w, r, apperr.New(apperr.CodeMethodNotAllowed))
+		return
+	}
+
+	userID, ok := server.UserIDFromContext(r.Context())
+	if !ok || userID == "" {
+		apperr.Write(w, r, apperr.New(apperr.CodeUnauthorized))
+		return
+	}
+
+	enrollment, err := h.mfaUC.BeginEnrollment(r.Context(), userID)
+	if err != nil {
+		apperr.Write(w, r, fmt.Errorf("MFA enrollment failed: %w", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(enrollment); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to encode MFA enrollment response", "error", err)
+	}
+}
+
+// MFACodeRequest представляет запрос с кодом 2FA
+type MFACodeRequest struct {
+	Code string `json:"code"`
+}
+
+// MFAConfirmResponse содержит одноразовые коды восстановления
+type MFAConfirmResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// MFAConfirmHandler подтверждает подключение 2FA первым кодом
+type MFAConfirmHandler struct {
+	mfaUC *user.MFAManager
+}
+
+// NewMFAConfirmHandler создает новый экземпляр MFAConfirmHandler
+func NewMFAConfirmHandler(mfaUC *user.MFAManager) *MFAConfirmHandler {
+	return &MFAConfirmHandler{mfaUC: mfaUC}
+}
+
+// ServeHTTP обрабатывает HTTP запрос на подтверждение 2FA
+// Метод: POST
+// Параметры: JSON с code
+// Возвращает: JSON с recovery_codes
+func (h *MFAConfirmHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		apperr.Write(w, r, apperr.New(apperr.CodeMethodNotAllowed))
+		return
+	}
+
+	userID, ok := server.UserIDFromContext(r.Context())
+	if !ok || userID == "" {
+		apperr.Write(w, r, apperr.New(apperr.CodeUnauthorized))
+		return
+	}
+
+	var req MFACodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apperr.Write(w, r, apperr.Wrap(apperr.CodeInvalidBody, err))
+		return
+	}
+
+	codes, err := h.mfaUC.ConfirmEnrollment(r.Context(), userID, req.Code)
+	if err != nil {
+		apperr.Write(w, r, fmt.Errorf("MFA confirmation failed: %w", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(MFAConfirmResponse{RecoveryCodes: codes}); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to encode MFA confirmation response", "error", err)
+	}
+}
+
+// MFADisableHandler выключает 2FA для текущего пользователя
+// Неверные коды считаются в LoginGuard по пользователю: иначе похищенный токен доступа позволял бы перебирать коды
+type MFADisableHandler struct {
+	mfaUC      *user.MFAManager
+	loginGuard *user.LoginGuard
+}
+
+// NewMFADisableHandler создает новый экземпляр MFADisableHandler
+func NewMFADisableHandler(mfaUC *user.MFAManager, loginGuard *user.LoginGuard) *MFADisableHandler {
+	return &MFADisableHandler{mfaUC: mfaUC, loginGuard: loginGuard}
+}
+
+// ServeHTTP обрабатывает HTTP запрос на выключение 2FA
+// Метод: POST
+// Параметры: JSON с code (TOTP или код восстановления)
+// Возвращает: HTTP статус 204 при успехе
+func (h *MFADisableHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		apperr.Write(w, r, apperr.New(apperr.CodeMethodNotAllowed))
+		return
+	}
+
+	userID, ok := server.UserIDFromContext(r.Context())
+	if !ok || userID == "" {
+		apperr.Write(w, r, apperr.New(apperr.CodeUnauthorized))
+		return
+	}
+
+	var req MFACodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apperr.Write(w, r, apperr.Wrap(apperr.CodeInvalidBody, err))
+		return
+	}
+
+	if err := h.loginGuard.CheckUser(r.Context(), userID); err != nil {
+		writeLoginGuardError(w, r, err)
+		return
+	}
+
+	if err := h.mfaUC.Disable(r.Context(), userID, req.Code); err != nil {
+		if errors.Is(err, user.ErrInvalidMFACode) {
+			if err := h.loginGuard.RegisterUserFailure(r.Context(), userID); err != nil {
+				writeLoginGuardError(w, r, err)
+				return
+			}
+		}
+		apperr.Write(w, r, fmt.Errorf("MFA disable failed: %w", err))
+		return
+	}
+
+	if err := h.loginGuard.RegisterUserSuccess(r.Context(), userID); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to reset MFA throttle", "error", err)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
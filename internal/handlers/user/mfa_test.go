@@ -0,0 +1,67 @@
+package user
+
+import (
+	"context"
+	"cursach/internal/config"
+	"cursach/internal/models"
+	"cursach/internal/repository"
+	"cursach/internal/server"
+	"cursach/internal/usecase/user"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// enabledMFARepository - у всех пользователей включена 2FA, а кодов восстановления нет
+type enabledMFARepository struct {
+	repository.MFARepository
+}
+
+func (enabledMFARepository) GetMFA(_ context.Context, userID string) (*models.UserMFA, error) {
+	return &models.UserMFA{UserID: userID, Secret: "JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP", Enabled: true}, nil
+}
+
+func (enabledMFARepository) UseRecoveryCode(context.Context, string, string) (bool, error) {
+	return false, nil
+}
+
+// TestMFADisableLocksAfterFailures проверяет, что перебор кодов при выключении 2FA упирается в LoginGuard:
+// после лимита неверных кодов пользователь получает 429 с Retry-After, а другие пользователи - нет
+func TestMFADisableLocksAfterFailures(t *testing.T) {
+	guard := user.NewLoginGuard(repository.NewMemoryLoginAttemptRepository(), config.LoginLimitConfig{
+		MaxFailuresPerLogin: 3,
+		FailureWindow:       15 * time.Minute,
+		BaseLockout:         time.Minute,
+		MaxLockout:          time.Hour,
+	})
+	h := NewMFADisableHandler(user.NewMFAManager(enabledMFARepository{}, nil, nil, "salt", "test"), guard)
+
+	disable := func(userID string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/api/v1/users/mfa/disable", strings.NewReader(`{"code":"wrong-code"}`))
+		r = r.WithContext(server.WithPrincipal(r.Context(), &server.Principal{UserID: userID, Role: server.RoleUser}))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	for i := 1; i < 3; i++ {
+		if w := disable("u1"); w.Code == http.StatusTooManyRequests || w.Code < 400 {
+			t.Fatalf("attempt %d: status %d, want a rejected code without lockout", i, w.Code)
+		}
+	}
+	for i := 3; i <= 4; i++ {
+		w := disable("u1")
+		if w.Code != http.StatusTooManyRequests {
+			t.Fatalf("attempt %d: status %d, want %d", i, w.Code, http.StatusTooManyRequests)
+		}
+		if w.Header().Get("Retry-After") == "" {
+			t.Errorf("attempt %d: Retry-After is missing", i)
+		}
+	}
+
+	if w := disable("u2"); w.Code == http.StatusTooManyRequests {
+		t.Errorf("another user: status %d, want the lockout to be per user", w.Code)
+	}
+}
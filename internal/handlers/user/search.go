@@ -1,9 +1,12 @@
 package user
 
 import (
+	"cursach/internal/pkg/apperr"
 	"cursach/internal/usecase/user"
 	"encoding/json"
+	"fmt"
 	_ "github.com/gorilla/mux"
+	"log/slog"
 	"net/http"
 )
 
@@ -18,18 +21,18 @@ func NewSearchUsersHandler(userSearcher *user.UserSearcher) *SearchUsersHandler
 func (h *SearchUsersHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	login := r.URL.Query().Get("login")
 	if login == "" {
-		http.Error(w, "login parameter is required", http.StatusBadRequest)
+		apperr.Write(w, r, apperr.New(apperr.CodeValidation).WithMessage("login parameter is required").WithDetail("param", "login"))
 		return
 	}
 
 	users, err := h.userSearcher.Execute(r.Context(), login)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		apperr.Write(w, r, fmt.Errorf("failed to search users: %w", err))
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(users); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Failed to encode user search response", "error", err)
 	}
 }
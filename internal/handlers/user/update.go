@@ -2,9 +2,10 @@ package user
 
 import (
 	"encoding/json"
-	"errors"
 	"net/http"
 
+	"cursach/internal/pkg/apperr"
+	"cursach/internal/server"
 	"cursach/internal/usecase/user"
 )
 
@@ -22,37 +23,32 @@ type UpdateLoginRequest struct {
 
 func (h *UpdateLoginHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPut {
-		http.Error(w, "Only PUT method is allowed", http.StatusMethodNotAllowed)
+		apperr.Write(w, r, apperr.New(apperr.CodeMethodNotAllowed))
 		return
 	}
 
 	// Получаем текущего пользователя из контекста
-	currentUserID, ok := r.Context().Value("user_id").(string)
+	currentUserID, ok := server.UserIDFromContext(r.Context())
 	if !ok || currentUserID == "" {
-		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		apperr.Write(w, r, apperr.New(apperr.CodeUnauthorized))
 		return
 	}
 
 	var req UpdateLoginRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		apperr.Write(w, r, apperr.Wrap(apperr.CodeInvalidBody, err))
 		return
 	}
 
 	if req.NewLogin == "" {
-		http.Error(w, "New login is required", http.StatusBadRequest)
+		apperr.Write(w, r, apperr.New(apperr.CodeValidation).WithMessage("New login is required").WithDetail("field", "new_login"))
 		return
 	}
 
 	// Обновляем логин
 	err := h.updateUC.UpdateLogin(r.Context(), currentUserID, req.NewLogin)
 	if err != nil {
-		switch {
-		case errors.Is(err, user.ErrLoginAlreadyExists):
-			http.Error(w, err.Error(), http.StatusConflict)
-		default:
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
-		}
+		apperr.Write(w, r, err)
 		return
 	}
 
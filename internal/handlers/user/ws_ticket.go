@@ -0,0 +1,49 @@
+package user
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"cursach/internal/pkg/apperr"
+	"cursach/internal/server"
+	"cursach/internal/usecase/user"
+)
+
+type WSTicketHandler struct {
+	ticketUC *user.WSTicketManager
+}
+
+func NewWSTicketHandler(ticketUC *user.WSTicketManager) *WSTicketHandler {
+	return &WSTicketHandler{ticketUC: ticketUC}
+}
+
+// ServeHTTP выдает одноразовый билет для подключения к /ws/{chat_id}?ticket=...
+// Метод: POST
+// Возвращает: {"ticket": "...", "expires_at": "..."}
+func (h *WSTicketHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	userID, ok := server.UserIDFromContext(r.Context())
+	if !ok || userID == "" {
+		apperr.Write(w, r, apperr.New(apperr.CodeUnauthorized))
+		return
+	}
+
+	ticket, expiresAt, err := h.ticketUC.Issue(r.Context(), userID)
+	if err != nil {
+		apperr.Write(w, r, fmt.Errorf("failed to issue ws ticket: %w", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	// Билет действует как пароль, поэтому ответ не должен кешироваться
+	w.Header().Set("Cache-Control", "no-store")
+	response := struct {
+		Ticket    string    `json:"ticket"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}{ticket, expiresAt}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to encode ws ticket response", "error", err)
+	}
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// AuditEvent представляет запись журнала аудита о значимом для безопасности действии
+type AuditEvent struct {
+	ID         string          `json:"id"`          // Уникальный идентификатор записи
+	ActorID    string          `json:"actor_id"`    // ID пользователя, выполнившего действие (пусто для анонимных)
+	Action     string          `json:"action"`      // Тип действия, например "user.login"
+	TargetType string          `json:"target_type"` // Тип объекта действия: user, chat
+	TargetID   string          `json:"target_id"`   // ID объекта действия
+	IP         string          `json:"ip"`          // IP-адрес клиента
+	Details    json.RawMessage `json:"details"`     // Дополнительные данные в JSON
+	CreatedAt  time.Time       `json:"created_at"`  // Время действия
+}
+
+// AuditFilter задает условия выборки событий аудита (пустые поля не ограничивают выборку)
+type AuditFilter struct {
+	ActorID    string
+	Action     string
+	TargetType string
+	TargetID   string
+	From       time.Time // Начало периода включительно
+	To         time.Time // Конец периода не включительно
+	Limit      int       // 0 - без ограничения (используется при выгрузке)
+	Offset     int
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// LoginAttempt представляет запись о попытке входа в систему
+type LoginAttempt struct {
+	ID          string    `json:"id"`           // Уникальный идентификатор записи
+	Login       string    `json:"login"`        // Логин, под которым пытались войти
+	IP          string    `json:"ip"`           // IP-адрес клиента
+	Success     bool      `json:"success"`      // Признак успешного входа
+	AttemptedAt time.Time `json:"attempted_at"` // Время попытки
+}
+
+// LoginThrottle представляет состояние счетчика неудачных попыток входа по ключу (логин или IP)
+type LoginThrottle struct {
+	Key         string    `json:"key"`          // Ключ счетчика (например, "login:alice" или "ip:10.0.0.1")
+	Failures    int       `json:"failures"`     // Количество неудачных попыток в текущем окне
+	LastFailure time.Time `json:"last_failure"` // Время последней неудачной попытки
+	LockedUntil time.Time `json:"locked_until"` // Время окончания блокировки (нулевое, если блокировки нет)
+}
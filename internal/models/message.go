@@ -15,5 +15,6 @@ type Message struct {
 	ReplyTo     string       `json:"reply_to"`     // ID сообщения, на которое дан ответ (опционально) НЕ БУДЕТ
 	IsDraft     bool         `json:"is_draft"`     // Флаг черновика НЕ БУДЕТ
 	SendingTime time.Time    `json:"sending_time"` // Время отправки сообщения
+	Seq         int64        `json:"seq"`          // Порядковый номер сообщения в чате; курсор SSE и long-poll
 	UpdatedAt   sql.NullTime `json:"updated_at"`   // Время последнего обновления (опционально)
 }
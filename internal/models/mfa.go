@@ -0,0 +1,16 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// UserMFA представляет настройки двухфакторной аутентификации (TOTP) пользователя
+type UserMFA struct {
+	UserID       string       `json:"user_id"`      // ID пользователя
+	Secret       string       `json:"-"`            // Секрет TOTP в base32 (не экспортируется в JSON)
+	Enabled      bool         `json:"enabled"`      // 2FA подтверждена первым кодом и включена
+	LastUsedStep int64        `json:"-"`            // Последний использованный шаг TOTP (защита от повтора кода)
+	CreatedAt    time.Time    `json:"created_at"`   // Время начала подключения
+	ConfirmedAt  sql.NullTime `json:"confirmed_at"` // Время подтверждения (опционально)
+}
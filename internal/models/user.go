@@ -13,9 +13,26 @@ type User struct {
 	Role      string         `json:"role"`       // Роль пользователя (user/admin)
 	CreatedAt time.Time      `json:"created_at"` // Время создания пользователя
 	UpdatedAt sql.NullTime   `json:"updated_at"` // Время последнего обновления (опционально)
+	BannedAt  sql.NullTime   `json:"banned_at"`  // Время блокировки администратором (опционально)
 	Chats     []ChatWithUser `json:"chats,omitempty"`
 }
 
+// UserFilter задает условия выборки пользователей для администратора
+type UserFilter struct {
+	LoginPrefix string // Начало логина (пусто - любой)
+	Role        string // Роль (пусто - любая)
+	Banned      *bool  // Признак блокировки (nil - любой)
+	Limit       int
+	Offset      int
+}
+
+// UserStats представляет статистику активности пользователя
+type UserStats struct {
+	ChatsCount    int          `json:"chats_count"`     // Количество чатов
+	MessagesCount int          `json:"messages_count"`  // Количество отправленных сообщений
+	LastMessageAt sql.NullTime `json:"last_message_at"` // Время последнего сообщения (опционально)
+}
+
 // ChatWithUser представляет чат с информацией о собеседнике
 type ChatWithUser struct {
 	Chat Chat `json:"chat"`
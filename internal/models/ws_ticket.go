@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// WSTicket представляет одноразовый билет для подключения к WebSocket
+// Сам билет не хранится, только его SHA-256
+type WSTicket struct {
+	Hash      string    `json:"-"`          // SHA-256 билета в hex
+	UserID    string    `json:"user_id"`    // Пользователь, которому выдан билет
+	IssuedAt  time.Time `json:"issued_at"`  // Время выдачи
+	ExpiresAt time.Time `json:"expires_at"` // Время, после которого билет не принимается
+}
@@ -0,0 +1,140 @@
+package apperr
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync"
+
+	"cursach/internal/pkg/reqctx"
+)
+
+// Пакет apperr задает общий формат ошибок API: стабильный код, HTTP статус, сообщение и детали
+// REST отвечает {"error": {...}}, WebSocket отправляет кадр {"type": "error", ...} с теми же полями
+
+// Error - ошибка, которую можно показать клиенту
+type Error struct {
+	Code    Code                   `json:"code"`
+	Status  int                    `json:"-"`
+	Message string                 `json:"message"`
+	Details map[string]interface{} `json:"details,omitempty"`
+	Err     error                  `json:"-"` // Исходная ошибка; попадает только в лог
+}
+
+// New создает ошибку с HTTP статусом и сообщением из справочника
+func New(code Code) *Error {
+	e, ok := catalogue[code]
+	if !ok {
+		e = catalogue[CodeInternal]
+	}
+	return &Error{Code: code, Status: e.status, Message: e.message}
+}
+
+// Wrap создает ошибку с кодом code, сохраняя исходную ошибку для лога и errors.Is
+func Wrap(code Code, err error) *Error {
+	e := New(code)
+	e.Err = err
+	return e
+}
+
+// WithMessage заменяет сообщение по умолчанию более точным
+func (e *Error) WithMessage(message string) *Error {
+	e.Message = message
+	return e
+}
+
+// WithStatus заменяет HTTP статус из справочника, если в конкретном контексте уместен другой
+func (e *Error) WithStatus(status int) *Error {
+	e.Status = status
+	return e
+}
+
+// WithDetail добавляет поле в details
+func (e *Error) WithDetail(key string, value interface{}) *Error {
+	if e.Details == nil {
+		e.Details = make(map[string]interface{})
+	}
+	e.Details[key] = value
+	return e
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return string(e.Code) + ": " + e.Err.Error()
+	}
+	return string(e.Code) + ": " + e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// mapping - сопоставление ошибки нижнего слоя коду API
+type mapping struct {
+	target error
+	code   Code
+}
+
+var (
+	mappingsMu sync.RWMutex
+	mappings   []mapping
+)
+
+// Register сопоставляет ошибки (обычно sentinel-ошибки usecase) коду code
+// Сравнение выполняется через errors.Is, поэтому обернутые ошибки тоже распознаются
+func Register(code Code, targets ...error) {
+	mappingsMu.Lock()
+	defer mappingsMu.Unlock()
+	for _, target := range targets {
+		mappings = append(mappings, mapping{target: target, code: code})
+	}
+}
+
+// From приводит любую ошибку к *Error
+// Незарегистрированные ошибки становятся internal_error без текста исходной ошибки
+func From(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		clone := *e
+		return &clone
+	}
+
+	mappingsMu.RLock()
+	defer mappingsMu.RUnlock()
+	for _, m := range mappings {
+		if errors.Is(err, m.target) {
+			return Wrap(m.code, err)
+		}
+	}
+	return Wrap(CodeInternal, err)
+}
+
+// envelope - тело ответа REST с ошибкой
+type envelope struct {
+	Error body `json:"error"`
+}
+
+type body struct {
+	*Error
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// Write отвечает клиенту ошибкой в формате {"error": {"code", "message", "details", "request_id"}}
+// Внутренние ошибки логируются вместе с исходной ошибкой, клиент видит только общее сообщение
+func Write(w http.ResponseWriter, r *http.Request, err error) {
+	e := From(err)
+	if e.Code == CodeInternal {
+		slog.ErrorContext(r.Context(), "Request failed", "error", err)
+	} else if e.Status >= http.StatusInternalServerError {
+		slog.WarnContext(r.Context(), "Request failed", "code", e.Code, "error", err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(e.Status)
+	resp := envelope{Error: body{Error: e, RequestID: reqctx.RequestID(r.Context())}}
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to encode error response", "error", err)
+	}
+}
@@ -0,0 +1,97 @@
+package apperr
+
+import "net/http"
+
+// Code - стабильный машинный код ошибки; клиенты ветвятся по нему, а не по тексту сообщения
+// Коды общие для REST и WebSocket; существующие коды не переименовываются
+type Code string
+
+// Общие ошибки запроса
+const (
+	CodeBadRequest       Code = "bad_request"
+	CodeInvalidBody      Code = "invalid_body"
+	CodeValidation       Code = "validation_failed"
+	CodeBodyTooLarge     Code = "body_too_large"
+	CodeUnsupportedMedia Code = "unsupported_media_type"
+	CodeNotFound         Code = "not_found"
+	CodeMethodNotAllowed Code = "method_not_allowed"
+	CodeInternal         Code = "internal_error"
+	CodeTimeout          Code = "timeout"
+	CodeRateLimited      Code = "rate_limited"
+	CodeUnavailable      Code = "unavailable"
+)
+
+// Аутентификация и доступ
+const (
+	CodeUnauthorized       Code = "unauthorized"
+	CodeInvalidToken       Code = "invalid_token"
+	CodeTokenRevoked       Code = "token_revoked"
+	CodeForbidden          Code = "forbidden"
+	CodeEmptyCredentials   Code = "empty_credentials"
+	CodeInvalidCredentials Code = "invalid_credentials"
+	CodeUserBanned         Code = "user_banned"
+	CodeTooManyAttempts    Code = "too_many_attempts"
+	CodeInvalidMFAToken    Code = "invalid_mfa_token"
+	CodeInvalidMFACode     Code = "invalid_mfa_code"
+	CodeMFAAlreadyEnabled  Code = "mfa_already_enabled"
+	CodeMFANotEnrolled     Code = "mfa_not_enrolled"
+	CodeMFANotEnabled      Code = "mfa_not_enabled"
+)
+
+// Пользователи, чаты и сообщения
+const (
+	CodeUserNotFound        Code = "user_not_found"
+	CodeLoginAlreadyExists  Code = "login_already_exists"
+	CodeInvalidRole         Code = "invalid_role"
+	CodeCannotTargetSelf    Code = "cannot_target_self"
+	CodeChatAccessDenied    Code = "chat_access_denied"
+	CodeSelfChat            Code = "self_chat"
+	CodeEmptyMessage        Code = "empty_message"
+	CodeUnknownMessageType  Code = "unknown_message_type"
+	CodeUnsupportedProtocol Code = "unsupported_protocol"
+)
+
+// entry - HTTP статус и сообщение по умолчанию для кода
+type entry struct {
+	status  int
+	message string
+}
+
+// catalogue - единый справочник кодов; сообщения безопасно показывать клиенту
+var catalogue = map[Code]entry{
+	CodeBadRequest:       {http.StatusBadRequest, "Invalid request"},
+	CodeInvalidBody:      {http.StatusBadRequest, "Invalid request body"},
+	CodeValidation:       {http.StatusBadRequest, "Invalid request parameters"},
+	CodeBodyTooLarge:     {http.StatusRequestEntityTooLarge, "Request body is too large"},
+	CodeUnsupportedMedia: {http.StatusUnsupportedMediaType, "Unsupported content type"},
+	CodeNotFound:         {http.StatusNotFound, "Resource not found"},
+	CodeMethodNotAllowed: {http.StatusMethodNotAllowed, "Method not allowed"},
+	CodeInternal:         {http.StatusInternalServerError, "Internal server error"},
+	CodeTimeout:          {http.StatusGatewayTimeout, "Operation timed out, please retry"},
+	CodeRateLimited:      {http.StatusTooManyRequests, "Too many requests, please slow down"},
+	CodeUnavailable:      {http.StatusServiceUnavailable, "Service temporarily unavailable"},
+
+	CodeUnauthorized:       {http.StatusUnauthorized, "Authentication required"},
+	CodeInvalidToken:       {http.StatusUnauthorized, "Invalid token"},
+	CodeTokenRevoked:       {http.StatusUnauthorized, "Token revoked"},
+	CodeForbidden:          {http.StatusForbidden, "Forbidden"},
+	CodeEmptyCredentials:   {http.StatusBadRequest, "Login and password cannot be empty"},
+	CodeInvalidCredentials: {http.StatusUnauthorized, "Invalid login or password"},
+	CodeUserBanned:         {http.StatusForbidden, "Account is banned"},
+	CodeTooManyAttempts:    {http.StatusTooManyRequests, "Too many failed login attempts"},
+	CodeInvalidMFAToken:    {http.StatusUnauthorized, "Invalid MFA token"},
+	CodeInvalidMFACode:     {http.StatusBadRequest, "Invalid two-factor authentication code"},
+	CodeMFAAlreadyEnabled:  {http.StatusConflict, "Two-factor authentication is already enabled"},
+	CodeMFANotEnrolled:     {http.StatusBadRequest, "Two-factor authentication enrollment not started"},
+	CodeMFANotEnabled:      {http.StatusConflict, "Two-factor authentication is not enabled"},
+
+	CodeUserNotFound:        {http.StatusNotFound, "User not found"},
+	CodeLoginAlreadyExists:  {http.StatusConflict, "Login already exists"},
+	CodeInvalidRole:         {http.StatusBadRequest, "Invalid user role"},
+	CodeCannotTargetSelf:    {http.StatusConflict, "Administrators cannot apply this action to themselves"},
+	CodeChatAccessDenied:    {http.StatusForbidden, "Chat not found or you are not a participant"},
+	CodeSelfChat:            {http.StatusBadRequest, "Cannot create chat with yourself"},
+	CodeEmptyMessage:        {http.StatusBadRequest, "Message text cannot be empty"},
+	CodeUnknownMessageType:  {http.StatusBadRequest, "Unknown message type"},
+	CodeUnsupportedProtocol: {http.StatusBadRequest, "Unsupported WebSocket protocol version"},
+}
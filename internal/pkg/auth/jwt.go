@@ -11,13 +11,19 @@ var (
 	ErrInvalidToken = errors.New("invalid token")
 )
 
+// PurposeMFAPending - назначение токена, выданного после проверки пароля до ввода кода 2FA
+const PurposeMFAPending = "mfa_pending"
+
 type Claims struct {
-	UserID string `json:"user_id"`
-	Role   string `json:"role"`
+	UserID  string `json:"user_id"`
+	Role    string `json:"role"`
+	Login   string `json:"login,omitempty"`   // Заполняется только в промежуточных токенах
+	Purpose string `json:"purpose,omitempty"` // Пусто для обычного токена доступа
 	jwt.RegisteredClaims
 }
 
-func GenerateJWT(user *models.User, secret string, expiresIn time.Duration) (string, error) {
+// GenerateJWT создает токен доступа, подписанный активным ключом набора
+func GenerateJWT(user *models.User, keys *KeySet, expiresIn time.Duration) (string, error) {
 	claims := Claims{
 		UserID: user.ID,
 		Role:   user.Role,
@@ -27,14 +33,12 @@ func GenerateJWT(user *models.User, secret string, expiresIn time.Duration) (str
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(secret))
+	return keys.Sign(claims)
 }
 
-func ParseJWT(tokenString, secret string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		return []byte(secret), nil
-	})
+// ParseJWT проверяет подпись токена ключами набора и возвращает claims
+func ParseJWT(tokenString string, keys *KeySet) (*Claims, error) {
+	token, err := keys.Parse(tokenString, &Claims{})
 
 	if err != nil {
 		return nil, err
@@ -47,7 +51,42 @@ func ParseJWT(tokenString, secret string) (*Claims, error) {
 	return nil, ErrInvalidToken
 }
 
-// ValidateToken проверяет JWT токен и возвращает claims
-func ValidateToken(tokenString, secret string) (*Claims, error) {
-	return ParseJWT(tokenString, secret)
+// GenerateMFAPendingJWT создает короткоживущий токен, который можно обменять на обычный только вместе с кодом 2FA
+func GenerateMFAPendingJWT(user *models.User, keys *KeySet, expiresIn time.Duration) (string, error) {
+	claims := Claims{
+		UserID:  user.ID,
+		Login:   user.Login,
+		Purpose: PurposeMFAPending,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiresIn)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	return keys.Sign(claims)
+}
+
+// ValidateToken проверяет JWT токен доступа и возвращает claims
+// Промежуточные токены (например, ожидающие 2FA) доступа не дают
+func ValidateToken(tokenString string, keys *KeySet) (*Claims, error) {
+	claims, err := ParseJWT(tokenString, keys)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Purpose != "" {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
+}
+
+// ValidateMFAPendingToken проверяет промежуточный токен, выданный до ввода кода 2FA
+func ValidateMFAPendingToken(tokenString string, keys *KeySet) (*Claims, error) {
+	claims, err := ParseJWT(tokenString, keys)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Purpose != PurposeMFAPending {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
 }
@@ -0,0 +1,253 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+var (
+	ErrUnknownKey         = errors.New("unknown signing key")
+	ErrUnsupportedKeyType = errors.New("unsupported key type (allowed: RSA, Ed25519)")
+)
+
+// signingKey - ключ подписи с идентификатором kid и алгоритмом
+type signingKey struct {
+	id      string
+	method  jwt.SigningMethod
+	private crypto.Signer
+	public  crypto.PublicKey
+}
+
+// KeySet хранит ключи подписи JWT
+// Токены подписываются активным ключом (RS256 или EdDSA) с заголовком kid,
+// проверяются любым ключом из набора, что позволяет ротацию без разлогина пользователей.
+// На время миграции можно принимать старые токены HS256, подписанные общим секретом.
+type KeySet struct {
+	mu          sync.RWMutex
+	keys        map[string]*signingKey
+	activeID    string
+	legacy      []byte
+	legacyUntil time.Time
+	now         func() time.Time
+}
+
+// LoadKeySet загружает ключи из PEM-файлов каталога keysDir (kid = имя файла без расширения)
+// Если keysDir пуст, токены подписываются HS256 общим секретом legacySecret (режим до миграции).
+// Токены HS256 принимаются до legacyUntil; нулевое значение означает, что они больше не принимаются.
+func LoadKeySet(keysDir, activeID, legacySecret string, legacyUntil time.Time) (*KeySet, error) {
+	ks := &KeySet{
+		keys:        make(map[string]*signingKey),
+		legacyUntil: legacyUntil,
+		now:         time.Now,
+	}
+	if legacySecret != "" {
+		ks.legacy = []byte(legacySecret)
+	}
+
+	if keysDir == "" {
+		if ks.legacy == nil {
+			return nil, errors.New("either JWT keys directory or legacy secret must be set")
+		}
+		return ks, nil
+	}
+
+	files, err := filepath.Glob(filepath.Join(keysDir, "*.pem"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list JWT keys: %w", err)
+	}
+	for _, file := range files {
+		id := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+		key, err := loadSigningKey(id, file)
+		if err != nil {
+			return nil, err
+		}
+		ks.keys[id] = key
+	}
+
+	if len(ks.keys) == 0 {
+		return nil, fmt.Errorf("no JWT keys found in %s", keysDir)
+	}
+	if err := ks.SetActive(activeID); err != nil {
+		return nil, err
+	}
+	return ks, nil
+}
+
+// AddKey добавляет ключ подписи в набор (например, при ротации без перезапуска)
+func (ks *KeySet) AddKey(id string, private crypto.Signer) error {
+	key, err := newSigningKey(id, private)
+	if err != nil {
+		return err
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	ks.keys[id] = key
+	return nil
+}
+
+// SetActive делает ключ с указанным kid активным для подписи новых токенов
+func (ks *KeySet) SetActive(id string) error {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	if _, ok := ks.keys[id]; !ok {
+		return fmt.Errorf("%w: %q", ErrUnknownKey, id)
+	}
+	ks.activeID = id
+	return nil
+}
+
+// RemoveKey удаляет ключ из набора; токены, подписанные им, перестают приниматься
+func (ks *KeySet) RemoveKey(id string) error {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	if id == ks.activeID {
+		return errors.New("cannot remove active signing key")
+	}
+	delete(ks.keys, id)
+	return nil
+}
+
+// Sign подписывает claims активным ключом
+func (ks *KeySet) Sign(claims jwt.Claims) (string, error) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	if ks.activeID == "" {
+		// Ключи не настроены - подписываем общим секретом, как до миграции
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(ks.legacy)
+	}
+
+	key := ks.keys[ks.activeID]
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = key.id
+	return token.SignedString(key.private)
+}
+
+// Parse проверяет подпись токена и заполняет claims
+// Алгоритм из заголовка должен совпадать с алгоритмом ключа, найденного по kid
+func (ks *KeySet) Parse(tokenString string, claims jwt.Claims) (*jwt.Token, error) {
+	return jwt.ParseWithClaims(tokenString, claims, ks.keyFunc)
+}
+
+func (ks *KeySet) keyFunc(token *jwt.Token) (interface{}, error) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	if token.Method == jwt.SigningMethodHS256 {
+		// Токены HS256 выпускались до появления ключей и kid не содержат; HS256 с kid - подмена алгоритма
+		if _, hasKid := token.Header["kid"]; hasKid {
+			return nil, fmt.Errorf("%w: HS256 token must not reference a key", ErrInvalidToken)
+		}
+		if ks.legacy == nil || (ks.activeID != "" && !ks.now().Before(ks.legacyUntil)) {
+			return nil, fmt.Errorf("%w: HS256 tokens are no longer accepted", ErrInvalidToken)
+		}
+		return ks.legacy, nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	key, ok := ks.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, kid)
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("%w: unexpected signing method %s", ErrInvalidToken, token.Method.Alg())
+	}
+	return key.public, nil
+}
+
+// JWK - открытый ключ в формате JSON Web Key (RFC 7517)
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	N         string `json:"n,omitempty"`   // RSA: модуль
+	E         string `json:"e,omitempty"`   // RSA: открытая экспонента
+	Curve     string `json:"crv,omitempty"` // OKP: кривая
+	X         string `json:"x,omitempty"`   // OKP: открытый ключ
+}
+
+// JWKS - набор открытых ключей для /.well-known/jwks.json
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS возвращает открытые ключи всех ключей набора, отсортированные по kid
+func (ks *KeySet) JWKS() JWKS {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	set := JWKS{Keys: make([]JWK, 0, len(ks.keys))}
+	for _, key := range ks.keys {
+		jwk := JWK{KeyID: key.id, Use: "sig", Algorithm: key.method.Alg()}
+		switch pub := key.public.(type) {
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.KeyType = "OKP"
+			jwk.Curve = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].KeyID < set.Keys[j].KeyID })
+	return set
+}
+
+// loadSigningKey читает закрытый ключ из PEM-файла (PKCS#8 или PKCS#1 для RSA)
+func loadSigningKey(id, path string) (*signingKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWT key %s: %w", path, err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("failed to decode PEM in %s", path)
+	}
+
+	var parsed interface{}
+	if parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes); err != nil {
+		if parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes); err != nil {
+			return nil, fmt.Errorf("failed to parse JWT key %s: %w", path, err)
+		}
+	}
+
+	signer, ok := parsed.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", path, ErrUnsupportedKeyType)
+	}
+	return newSigningKey(id, signer)
+}
+
+func newSigningKey(id string, private crypto.Signer) (*signingKey, error) {
+	key := &signingKey{id: id, private: private, public: private.Public()}
+	switch private.(type) {
+	case *rsa.PrivateKey:
+		key.method = jwt.SigningMethodRS256
+	case ed25519.PrivateKey:
+		key.method = jwt.SigningMethodEdDSA
+	default:
+		return nil, ErrUnsupportedKeyType
+	}
+	return key, nil
+}
@@ -0,0 +1,218 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const legacySecret = "legacy-secret"
+
+var keysetNow = time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+func generateRSA(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate RSA key: %v", err)
+	}
+	return key
+}
+
+func generateEd25519(t *testing.T) ed25519.PrivateKey {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate Ed25519 key: %v", err)
+	}
+	return key
+}
+
+// testKeySet возвращает набор с активным RSA-ключом "rsa", ключом Ed25519 "ed" и окном HS256 до legacyUntil
+func testKeySet(t *testing.T, rsaKey *rsa.PrivateKey, edKey ed25519.PrivateKey, legacyUntil time.Time) *KeySet {
+	t.Helper()
+	ks, err := LoadKeySet("", "", legacySecret, legacyUntil)
+	if err != nil {
+		t.Fatalf("LoadKeySet: %v", err)
+	}
+	ks.now = func() time.Time { return keysetNow }
+	if err := ks.AddKey("rsa", rsaKey); err != nil {
+		t.Fatalf("AddKey(rsa): %v", err)
+	}
+	if err := ks.AddKey("ed", edKey); err != nil {
+		t.Fatalf("AddKey(ed): %v", err)
+	}
+	if err := ks.SetActive("rsa"); err != nil {
+		t.Fatalf("SetActive: %v", err)
+	}
+	return ks
+}
+
+// signToken подписывает токен методом method и ключом key; kid == "" - без заголовка kid
+func signToken(t *testing.T, method jwt.SigningMethod, kid string, key interface{}) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, jwt.RegisteredClaims{
+		Subject:   "u1",
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	})
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("sign %s token: %v", method.Alg(), err)
+	}
+	return signed
+}
+
+func TestKeySetParse(t *testing.T) {
+	rsaKey, edKey := generateRSA(t), generateEd25519(t)
+	ks := testKeySet(t, rsaKey, edKey, keysetNow.Add(time.Hour))
+	closed := testKeySet(t, rsaKey, edKey, keysetNow)
+
+	// Открытый ключ RSA в DER - секрет HMAC в классической подмене RS256 -> HS256
+	rsaPublicDER, err := x509.MarshalPKIXPublicKey(rsaKey.Public())
+	if err != nil {
+		t.Fatalf("marshal public key: %v", err)
+	}
+	active, err := ks.Sign(jwt.RegisteredClaims{Subject: "u1", ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour))})
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		ks      *KeySet
+		token   string
+		wantErr error // nil - токен принимается
+	}{
+		{name: "active key", ks: ks, token: active},
+		{name: "RS256 by kid", ks: ks, token: signToken(t, jwt.SigningMethodRS256, "rsa", rsaKey)},
+		{name: "EdDSA by kid", ks: ks, token: signToken(t, jwt.SigningMethodEdDSA, "ed", edKey)},
+		{name: "unknown kid", ks: ks, token: signToken(t, jwt.SigningMethodRS256, "ghost", generateRSA(t)), wantErr: ErrUnknownKey},
+		{name: "RS256 without kid", ks: ks, token: signToken(t, jwt.SigningMethodRS256, "", rsaKey), wantErr: ErrUnknownKey},
+		{name: "HS256 with RSA kid signed by public key", ks: ks,
+			token: signToken(t, jwt.SigningMethodHS256, "rsa", rsaPublicDER), wantErr: ErrInvalidToken},
+		{name: "HS256 with RSA kid signed by legacy secret", ks: ks,
+			token: signToken(t, jwt.SigningMethodHS256, "rsa", []byte(legacySecret)), wantErr: ErrInvalidToken},
+		{name: "EdDSA with RSA kid", ks: ks, token: signToken(t, jwt.SigningMethodEdDSA, "rsa", edKey), wantErr: ErrInvalidToken},
+		{name: "RS256 with Ed25519 kid", ks: ks, token: signToken(t, jwt.SigningMethodRS256, "ed", rsaKey), wantErr: ErrInvalidToken},
+		{name: "HS256 without kid in legacy window", ks: ks, token: signToken(t, jwt.SigningMethodHS256, "", []byte(legacySecret))},
+		{name: "HS256 without kid after legacy window", ks: closed,
+			token: signToken(t, jwt.SigningMethodHS256, "", []byte(legacySecret)), wantErr: ErrInvalidToken},
+		{name: "HS256 with wrong secret", ks: ks, token: signToken(t, jwt.SigningMethodHS256, "", []byte("guess")), wantErr: jwt.ErrSignatureInvalid},
+		{name: "alg none", ks: ks,
+			token: signToken(t, jwt.SigningMethodNone, "", jwt.UnsafeAllowNoneSignatureType), wantErr: ErrUnknownKey},
+		{name: "alg none with kid", ks: ks,
+			token: signToken(t, jwt.SigningMethodNone, "rsa", jwt.UnsafeAllowNoneSignatureType), wantErr: ErrInvalidToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var claims jwt.RegisteredClaims
+			_, err := tt.ks.Parse(tt.token, &claims)
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Fatalf("Parse() = %v, want token accepted", err)
+				}
+				if claims.Subject != "u1" {
+					t.Errorf("sub = %q, want u1", claims.Subject)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("Parse() accepted the token")
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Parse() = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestKeySetLegacyOnly проверяет режим до миграции: ключей нет, HS256 принимается без ограничения по времени
+func TestKeySetLegacyOnly(t *testing.T) {
+	ks, err := LoadKeySet("", "", legacySecret, time.Time{})
+	if err != nil {
+		t.Fatalf("LoadKeySet: %v", err)
+	}
+	var claims jwt.RegisteredClaims
+	if _, err := ks.Parse(signToken(t, jwt.SigningMethodHS256, "", []byte(legacySecret)), &claims); err != nil {
+		t.Errorf("Parse() = %v, want HS256 token accepted", err)
+	}
+}
+
+// TestKeySetJWKS проверяет, что JWKS содержит только открытые части ключей и упорядочен по kid
+func TestKeySetJWKS(t *testing.T) {
+	ks, err := LoadKeySet("", "", legacySecret, time.Time{})
+	if err != nil {
+		t.Fatalf("LoadKeySet: %v", err)
+	}
+	rsaKey, edKey := generateRSA(t), generateEd25519(t)
+	for _, k := range []struct {
+		id  string
+		key crypto.Signer
+	}{{"k3", rsaKey}, {"k1", edKey}, {"k2", generateRSA(t)}} {
+		if err := ks.AddKey(k.id, k.key); err != nil {
+			t.Fatalf("AddKey(%s): %v", k.id, err)
+		}
+	}
+
+	set := ks.JWKS()
+	var ids []string
+	for _, key := range set.Keys {
+		ids = append(ids, key.KeyID)
+	}
+	if want := []string{"k1", "k2", "k3"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("kids = %v, want %v", ids, want)
+	}
+	for i := 0; i < 5; i++ {
+		if again := ks.JWKS(); !reflect.DeepEqual(again, set) {
+			t.Fatalf("JWKS() is not stable between calls")
+		}
+	}
+
+	data, err := json.Marshal(set)
+	if err != nil {
+		t.Fatalf("marshal JWKS: %v", err)
+	}
+	var raw struct {
+		Keys []map[string]interface{} `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		t.Fatalf("unmarshal JWKS: %v", err)
+	}
+	public := map[string]map[string]bool{
+		"RSA": {"kty": true, "kid": true, "use": true, "alg": true, "n": true, "e": true},
+		"OKP": {"kty": true, "kid": true, "use": true, "alg": true, "crv": true, "x": true},
+	}
+	for _, key := range raw.Keys {
+		allowed := public[key["kty"].(string)]
+		if allowed == nil {
+			t.Fatalf("unexpected kty in %v", key)
+		}
+		for field := range key {
+			if !allowed[field] {
+				t.Errorf("key %v exposes field %q", key["kid"], field)
+			}
+		}
+	}
+
+	// Тип ключа и алгоритм соответствуют закрытому ключу набора
+	ed := set.Keys[0]
+	if ed.KeyType != "OKP" || ed.Curve != "Ed25519" || ed.Algorithm != "EdDSA" {
+		t.Errorf("k1 = %+v, want Ed25519 EdDSA key", ed)
+	}
+	if rsaJWK := set.Keys[2]; rsaJWK.KeyType != "RSA" || rsaJWK.Algorithm != "RS256" || rsaJWK.E != "AQAB" {
+		t.Errorf("k3 = %+v, want RS256 key with e=AQAB", rsaJWK)
+	}
+}
@@ -0,0 +1,123 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Параметры TOTP по RFC 6238 (совместимы с Google Authenticator и аналогами)
+const (
+	TOTPPeriod     = 30 * time.Second // Длительность одного шага
+	TOTPDigits     = 6                // Количество цифр в коде
+	TOTPSkew       = 1                // Допустимое расхождение часов в шагах (в обе стороны)
+	totpSecretSize = 20               // Размер секрета в байтах (160 бит, как у HMAC-SHA1)
+
+	recoveryCodeSize = 10 // Символов в одном коде восстановления (без дефиса)
+)
+
+var (
+	ErrInvalidTOTPSecret = errors.New("invalid TOTP secret")
+
+	totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+)
+
+// GenerateTOTPSecret создает новый случайный секрет TOTP в кодировке base32
+func GenerateTOTPSecret() (string, error) {
+	secret := make([]byte, totpSecretSize)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("failed to generate TOTP secret: %w", err)
+	}
+	return totpEncoding.EncodeToString(secret), nil
+}
+
+// TOTPURI формирует otpauth:// URI для добавления секрета в приложение-аутентификатор
+func TOTPURI(issuer, account, secret string) string {
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", issuer)
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", fmt.Sprint(TOTPDigits))
+	params.Set("period", fmt.Sprint(int(TOTPPeriod.Seconds())))
+
+	label := url.PathEscape(issuer + ":" + account)
+	return "otpauth://totp/" + label + "?" + params.Encode()
+}
+
+// TOTPStep возвращает номер временного шага для момента t
+func TOTPStep(t time.Time) int64 {
+	return t.Unix() / int64(TOTPPeriod.Seconds())
+}
+
+// TOTPCode вычисляет код TOTP для указанного шага (HOTP по RFC 4226 со счетчиком = шаг)
+func TOTPCode(secret string, step int64) (string, error) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(strings.TrimRight(secret, "=")))
+	if err != nil {
+		return "", ErrInvalidTOTPSecret
+	}
+
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(step))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+
+	// Динамическое усечение (RFC 4226, раздел 5.3)
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < TOTPDigits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", TOTPDigits, value%mod), nil
+}
+
+// ValidateTOTP проверяет код для момента t с учетом TOTPSkew
+// Возвращает номер совпавшего шага, чтобы вызывающий мог запретить повторное использование кода
+func ValidateTOTP(secret, code string, t time.Time) (int64, bool) {
+	if len(code) != TOTPDigits {
+		return 0, false
+	}
+
+	current := TOTPStep(t)
+	for step := current - TOTPSkew; step <= current+TOTPSkew; step++ {
+		expected, err := TOTPCode(secret, step)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// GenerateRecoveryCodes создает n одноразовых кодов восстановления вида "abcde-fghij"
+func GenerateRecoveryCodes(n int) ([]string, error) {
+	codes := make([]string, 0, n)
+	buf := make([]byte, recoveryCodeSize)
+	for i := 0; i < n; i++ {
+		if _, err := rand.Read(buf); err != nil {
+			return nil, fmt.Errorf("failed to generate recovery code: %w", err)
+		}
+		raw := strings.ToLower(totpEncoding.EncodeToString(buf))[:recoveryCodeSize]
+		codes = append(codes, raw[:recoveryCodeSize/2]+"-"+raw[recoveryCodeSize/2:])
+	}
+	return codes, nil
+}
+
+// NormalizeRecoveryCode приводит введенный пользователем код восстановления к каноническому виду
+func NormalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.ReplaceAll(code, "-", "")
+}
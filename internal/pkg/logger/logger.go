@@ -0,0 +1,66 @@
+package logger
+
+import (
+	"context"
+	"cursach/internal/config"
+	"cursach/internal/pkg/reqctx"
+	"fmt"
+	"go.opentelemetry.io/otel/trace"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// New создает slog.Logger по конфигурации: формат json или text и минимальный уровень
+// Каждая запись, сделанная с контекстом запроса, дополняется request_id, а при активном спане - trace_id и span_id
+func New(cfg config.LogConfig, w io.Writer) (*slog.Logger, error) {
+	level, err := ParseLevel(cfg.Level)
+	if err != nil {
+		return nil, err
+	}
+	opts := &slog.HandlerOptions{Level: level}
+
+	var handler slog.Handler
+	switch strings.ToLower(cfg.Format) {
+	case "json":
+		handler = slog.NewJSONHandler(w, opts)
+	case "text":
+		handler = slog.NewTextHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("unknown log format %q (allowed: json, text)", cfg.Format)
+	}
+
+	return slog.New(contextHandler{handler}), nil
+}
+
+// ParseLevel разбирает уровень логирования: debug, info, warn или error
+func ParseLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(s)); err != nil {
+		return 0, fmt.Errorf("unknown log level %q (allowed: debug, info, warn, error)", s)
+	}
+	return level, nil
+}
+
+// contextHandler добавляет в запись метаданные запроса из контекста
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := reqctx.RequestID(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		r.AddAttrs(slog.String("trace_id", sc.TraceID().String()), slog.String("span_id", sc.SpanID().String()))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,65 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+)
+
+// Default - набор метрик приложения, отдаваемый по /metrics
+var Default = NewRegistry()
+
+// Метрики приложения
+// Скорость (например, сообщений в секунду) считается на стороне Prometheus через rate() по счетчикам
+var (
+	HTTPRequests = Default.NewCounter("messenger_http_requests_total",
+		"HTTP requests by method, route template and status code.", "method", "route", "status")
+	HTTPRequestDuration = Default.NewHistogram("messenger_http_request_duration_seconds",
+		"HTTP request latency by method and route template.", DefBuckets, "method", "route")
+
+	WebSocketConnections = Default.NewGauge("messenger_websocket_connections",
+		"WebSocket connections currently open on this node.")
+	MessagesSent = Default.NewCounter("messenger_messages_sent_total",
+		"Chat messages accepted and stored.")
+
+	DeprecatedAPIRequests = Default.NewCounter("messenger_deprecated_api_requests_total",
+		"Requests served through the deprecated unversioned /api alias by route template.", "route")
+
+	RateLimited = Default.NewCounter("messenger_rate_limited_total",
+		"Requests and WebSocket messages rejected by the rate limiter by operation.", "operation")
+
+	AuthFailures = Default.NewCounter("messenger_auth_failures_total",
+		"Rejected authentication attempts by reason.", "reason")
+	TokenRevocations = Default.NewCounter("messenger_token_revocations_total",
+		"Token revocations by scope: token (single logout) or user (all tokens of a user).", "scope")
+)
+
+// Handler отдает метрики приложения в текстовом формате Prometheus
+func Handler() http.Handler {
+	return Default.Handler()
+}
+
+// RegisterDBStats публикует статистику пула соединений db (sql.DB.Stats) на момент сбора
+func RegisterDBStats(db *sql.DB) {
+	stats := func(fn func(s sql.DBStats) float64) func() float64 {
+		return func() float64 { return fn(db.Stats()) }
+	}
+
+	Default.NewGaugeFunc("messenger_db_max_open_connections", "Maximum number of open connections to the database.",
+		stats(func(s sql.DBStats) float64 { return float64(s.MaxOpenConnections) }))
+	Default.NewGaugeFunc("messenger_db_open_connections", "Established connections, both in use and idle.",
+		stats(func(s sql.DBStats) float64 { return float64(s.OpenConnections) }))
+	Default.NewGaugeFunc("messenger_db_in_use_connections", "Connections currently in use.",
+		stats(func(s sql.DBStats) float64 { return float64(s.InUse) }))
+	Default.NewGaugeFunc("messenger_db_idle_connections", "Idle connections.",
+		stats(func(s sql.DBStats) float64 { return float64(s.Idle) }))
+	Default.NewCounterFunc("messenger_db_wait_count_total", "Total number of connections waited for.",
+		stats(func(s sql.DBStats) float64 { return float64(s.WaitCount) }))
+	Default.NewCounterFunc("messenger_db_wait_duration_seconds_total", "Total time blocked waiting for a new connection.",
+		stats(func(s sql.DBStats) float64 { return s.WaitDuration.Seconds() }))
+	Default.NewCounterFunc("messenger_db_max_idle_closed_total", "Connections closed due to SetMaxIdleConns.",
+		stats(func(s sql.DBStats) float64 { return float64(s.MaxIdleClosed) }))
+	Default.NewCounterFunc("messenger_db_max_idle_time_closed_total", "Connections closed due to SetConnMaxIdleTime.",
+		stats(func(s sql.DBStats) float64 { return float64(s.MaxIdleTimeClosed) }))
+	Default.NewCounterFunc("messenger_db_max_lifetime_closed_total", "Connections closed due to SetConnMaxLifetime.",
+		stats(func(s sql.DBStats) float64 { return float64(s.MaxLifetimeClosed) }))
+}
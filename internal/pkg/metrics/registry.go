@@ -0,0 +1,331 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefBuckets - границы гистограммы длительности по умолчанию (в секундах)
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// metric - метрика, умеющая вывести себя в текстовом формате Prometheus
+type metric interface {
+	write(w *bufio.Writer)
+}
+
+// Registry - набор метрик, отдаваемых по /metrics
+type Registry struct {
+	mu      sync.Mutex
+	names   map[string]struct{}
+	metrics []metric
+}
+
+// NewRegistry создает пустой набор метрик
+func NewRegistry() *Registry {
+	return &Registry{names: make(map[string]struct{})}
+}
+
+// register добавляет метрику; повторное имя - ошибка программиста
+func (r *Registry) register(name string, m metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.names[name]; ok {
+		panic(fmt.Sprintf("metrics: duplicate metric %s", name))
+	}
+	r.names[name] = struct{}{}
+	r.metrics = append(r.metrics, m)
+}
+
+// NewCounter создает счетчик с метками labels (без меток - один ряд)
+func (r *Registry) NewCounter(name, help string, labels ...string) *Counter {
+	c := &Counter{desc: desc{name: name, help: help, labels: labels}, values: make(map[string]*sample)}
+	r.register(name, c)
+	return c
+}
+
+// NewGauge создает метрику с произвольно изменяемым значением
+func (r *Registry) NewGauge(name, help string) *Gauge {
+	g := &Gauge{desc: desc{name: name, help: help}}
+	r.register(name, g)
+	return g
+}
+
+// NewHistogram создает гистограмму с границами buckets и метками labels
+func (r *Registry) NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	h := &Histogram{
+		desc:    desc{name: name, help: help, labels: labels},
+		buckets: append([]float64(nil), buckets...),
+		values:  make(map[string]*histogramSample),
+	}
+	sort.Float64s(h.buckets)
+	r.register(name, h)
+	return h
+}
+
+// NewGaugeFunc регистрирует gauge, значение которого вычисляется fn при каждом сборе
+func (r *Registry) NewGaugeFunc(name, help string, fn func() float64) {
+	r.register(name, &funcMetric{desc: desc{name: name, help: help}, typ: "gauge", fn: fn})
+}
+
+// NewCounterFunc регистрирует счетчик, значение которого вычисляется fn при каждом сборе
+// fn должна возвращать монотонно неубывающее значение
+func (r *Registry) NewCounterFunc(name, help string, fn func() float64) {
+	r.register(name, &funcMetric{desc: desc{name: name, help: help}, typ: "counter", fn: fn})
+}
+
+// Write выводит все метрики в текстовом формате Prometheus 0.0.4
+func (r *Registry) Write(w io.Writer) error {
+	r.mu.Lock()
+	metrics := append([]metric(nil), r.metrics...)
+	r.mu.Unlock()
+
+	bw := bufio.NewWriter(w)
+	for _, m := range metrics {
+		m.write(bw)
+	}
+	return bw.Flush()
+}
+
+// Handler отдает метрики по HTTP
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		r.Write(w)
+	})
+}
+
+// desc - общие для всех типов имя, описание и имена меток
+type desc struct {
+	name   string
+	help   string
+	labels []string
+}
+
+func (d desc) writeHeader(w *bufio.Writer, typ string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", d.name, escapeHelp(d.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", d.name, typ)
+}
+
+// key склеивает значения меток в ключ ряда; число значений должно совпадать с числом меток
+func (d desc) key(values []string) string {
+	if len(values) != len(d.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", d.name, len(d.labels), len(values)))
+	}
+	return strings.Join(values, "\xff")
+}
+
+// labelPairs выводит метки ряда в виде {a="1",b="2"}; extra добавляется последней (le у гистограмм)
+func (d desc) labelPairs(values []string, extra ...string) string {
+	if len(d.labels) == 0 && len(extra) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, name := range d.labels {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, `%s="%s"`, name, escapeLabel(values[i]))
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		if b.Len() > 1 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, `%s="%s"`, extra[i], escapeLabel(extra[i+1]))
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+type sample struct {
+	labels []string
+	value  float64
+}
+
+// Counter - монотонно растущий счетчик с необязательными метками
+type Counter struct {
+	desc
+	mu     sync.Mutex
+	values map[string]*sample
+}
+
+// Inc увеличивает ряд с указанными значениями меток на 1
+func (c *Counter) Inc(labels ...string) {
+	c.Add(1, labels...)
+}
+
+// Add увеличивает ряд на v (v не может быть отрицательным)
+func (c *Counter) Add(v float64, labels ...string) {
+	if v < 0 {
+		panic(fmt.Sprintf("metrics: counter %s cannot decrease", c.name))
+	}
+	key := c.key(labels)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s, ok := c.values[key]
+	if !ok {
+		s = &sample{labels: append([]string(nil), labels...)}
+		c.values[key] = s
+	}
+	s.value += v
+}
+
+func (c *Counter) write(w *bufio.Writer) {
+	c.writeHeader(w, "counter")
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.labels) == 0 && len(c.values) == 0 {
+		fmt.Fprintf(w, "%s 0\n", c.name)
+		return
+	}
+	for _, key := range sortedKeys(c.values) {
+		s := c.values[key]
+		fmt.Fprintf(w, "%s%s %s\n", c.name, c.labelPairs(s.labels), formatFloat(s.value))
+	}
+}
+
+// Gauge - значение, которое может как расти, так и уменьшаться
+type Gauge struct {
+	desc
+	mu    sync.Mutex
+	value float64
+}
+
+// Set устанавливает значение
+func (g *Gauge) Set(v float64) {
+	g.mu.Lock()
+	g.value = v
+	g.mu.Unlock()
+}
+
+// Inc увеличивает значение на 1
+func (g *Gauge) Inc() {
+	g.Add(1)
+}
+
+// Dec уменьшает значение на 1
+func (g *Gauge) Dec() {
+	g.Add(-1)
+}
+
+// Add изменяет значение на v
+func (g *Gauge) Add(v float64) {
+	g.mu.Lock()
+	g.value += v
+	g.mu.Unlock()
+}
+
+func (g *Gauge) write(w *bufio.Writer) {
+	g.writeHeader(w, "gauge")
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	fmt.Fprintf(w, "%s %s\n", g.name, formatFloat(g.value))
+}
+
+type histogramSample struct {
+	labels []string
+	counts []uint64 // Число наблюдений в каждом интервале (не накопленное)
+	sum    float64
+	count  uint64
+}
+
+// Histogram - распределение наблюдаемых значений по интервалам
+type Histogram struct {
+	desc
+	buckets []float64
+	mu      sync.Mutex
+	values  map[string]*histogramSample
+}
+
+// Observe добавляет наблюдение v в ряд с указанными значениями меток
+func (h *Histogram) Observe(v float64, labels ...string) {
+	key := h.key(labels)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s, ok := h.values[key]
+	if !ok {
+		s = &histogramSample{labels: append([]string(nil), labels...), counts: make([]uint64, len(h.buckets))}
+		h.values[key] = s
+	}
+	if i := sort.SearchFloat64s(h.buckets, v); i < len(h.buckets) {
+		s.counts[i]++
+	}
+	s.sum += v
+	s.count++
+}
+
+func (h *Histogram) write(w *bufio.Writer) {
+	h.writeHeader(w, "histogram")
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, key := range sortedKeys(h.values) {
+		s := h.values[key]
+		var cumulative uint64
+		for i, upper := range h.buckets {
+			cumulative += s.counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.labelPairs(s.labels, "le", formatFloat(upper)), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.labelPairs(s.labels, "le", "+Inf"), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, h.labelPairs(s.labels), formatFloat(s.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, h.labelPairs(s.labels), s.count)
+	}
+}
+
+// funcMetric - метрика без меток, значение которой берется в момент сбора
+type funcMetric struct {
+	desc
+	typ string
+	fn  func() float64
+}
+
+func (m *funcMetric) write(w *bufio.Writer) {
+	m.writeHeader(w, m.typ)
+	fmt.Fprintf(w, "%s %s\n", m.name, formatFloat(m.fn()))
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
+
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
@@ -0,0 +1,101 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Пакет ratelimit ограничивает частоту запросов алгоритмом token bucket:
+// корзина вмещает Burst жетонов и пополняется со скоростью Rate жетонов в секунду, каждый запрос забирает один
+
+// memorySweepSize - размер таблицы корзин, после которого удаляются заполненные (неактивные) корзины
+const memorySweepSize = 10000
+
+// Rule - ограничение для одного вида запросов
+type Rule struct {
+	Rate  float64 // Жетонов в секунду
+	Burst int     // Емкость корзины: сколько запросов можно сделать подряд
+}
+
+// Store хранит состояние корзин
+type Store interface {
+	// Take забирает жетон из корзины key; если жетона нет, возвращает false и время до появления следующего
+	Take(key string, rule Rule, now time.Time) (bool, time.Duration)
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	rule   Rule
+}
+
+// MemoryStore реализует Store в памяти процесса
+// Подходит для одного экземпляра сервера: у нескольких экземпляров ограничения независимы
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+// NewMemoryStore создает in-memory хранилище корзин
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]*bucket)}
+}
+
+func (s *MemoryStore) Take(key string, rule Rule, now time.Time) (bool, time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, ok := s.buckets[key]
+	if !ok {
+		if len(s.buckets) >= memorySweepSize {
+			s.sweep(now)
+		}
+		b = &bucket{tokens: float64(rule.Burst), last: now, rule: rule}
+		s.buckets[key] = b
+	}
+
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(float64(rule.Burst), b.tokens+elapsed*rule.Rate)
+		b.last = now
+	}
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	wait := (1 - b.tokens) / rule.Rate
+	return false, time.Duration(wait * float64(time.Second))
+}
+
+// sweep удаляет корзины, которые успели бы заполниться: их состояние не отличается от новой корзины
+func (s *MemoryStore) sweep(now time.Time) {
+	for key, b := range s.buckets {
+		if now.Sub(b.last).Seconds()*b.rule.Rate >= float64(b.rule.Burst) {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+// Limiter применяет ограничения по имени правила (operationId маршрута, сообщения WebSocket) и субъекту (пользователь или IP)
+// Для имен без своего правила используется общее правило с общей для всех таких запросов корзиной
+type Limiter struct {
+	store Store
+	def   Rule
+	rules map[string]Rule
+	now   func() time.Time
+}
+
+// New создает Limiter с общим правилом def и отдельными правилами rules
+func New(store Store, def Rule, rules map[string]Rule) *Limiter {
+	return &Limiter{store: store, def: def, rules: rules, now: time.Now}
+}
+
+// Allow расходует жетон субъекта subject для запроса вида name
+// Возвращает false и время, через которое стоит повторить, если лимит исчерпан
+func (l *Limiter) Allow(name, subject string) (bool, time.Duration) {
+	rule, ok := l.rules[name]
+	if !ok {
+		name, rule = "default", l.def
+	}
+	return l.store.Take(name+"|"+subject, rule, l.now())
+}
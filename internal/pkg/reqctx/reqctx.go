@@ -0,0 +1,35 @@
+package reqctx
+
+import "context"
+
+// Пакет reqctx хранит метаданные HTTP-запроса в контексте,
+// чтобы usecase и репозитории могли использовать их, не завися от net/http
+
+type contextKey string
+
+const (
+	clientIPKey  contextKey = "client_ip"
+	requestIDKey contextKey = "request_id"
+)
+
+// WithClientIP возвращает контекст с IP-адресом клиента
+func WithClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, clientIPKey, ip)
+}
+
+// ClientIP возвращает IP-адрес клиента из контекста (пустая строка, если не задан)
+func ClientIP(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIPKey).(string)
+	return ip
+}
+
+// WithRequestID возвращает контекст с идентификатором запроса
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+// RequestID возвращает идентификатор запроса из контекста (пустая строка, если не задан)
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
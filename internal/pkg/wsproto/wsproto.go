@@ -0,0 +1,173 @@
+package wsproto
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"cursach/internal/models"
+	"cursach/internal/pkg/apperr"
+	"github.com/gorilla/websocket"
+)
+
+// Пакет wsproto описывает протокол WebSocket чата: кадры в обе стороны, коды закрытия и версию протокола
+// Машиночитаемое описание - asyncapi.json рядом с кодом; при изменении кадров его нужно обновить вместе с версией
+
+// Версии протокола, согласуемые через заголовок Sec-WebSocket-Protocol
+// messenger.v2 отличается от messenger.v1 только порядком сообщений в кадре history
+const (
+	ProtocolV1 = "messenger.v1"
+	ProtocolV2 = "messenger.v2"
+)
+
+// Supported - поддерживаемые версии протокола в порядке предпочтения сервера
+var Supported = []string{ProtocolV2, ProtocolV1}
+
+// ErrUnsupportedProtocol - клиент запросил только неизвестные версии протокола
+var ErrUnsupportedProtocol = errors.New("unsupported websocket subprotocol")
+
+// Типы кадров (поле type)
+const (
+	TypeMessage  = "message"   // Клиент -> сервер: отправка; сервер -> клиент: новое сообщение в чате
+	TypeChatInfo = "chat_info" // Сервер -> клиент: информация о чате после подключения
+	TypeHistory  = "history"   // Сервер -> клиент: последние сообщения после подключения
+	TypeError    = "error"     // Сервер -> клиент: ошибка обработки кадра
+)
+
+// Коды закрытия соединения, кроме стандартных 1012 (перезапуск сервера) и 1013 (повторить позже)
+const (
+	CloseAuthFailed        = 4001
+	CloseChatIDMissing     = 4002
+	CloseAccessDenied      = 4003
+	CloseSessionTerminated = 4004
+)
+
+//go:embed asyncapi.json
+var document []byte
+
+// Negotiate выбирает версию протокола по заголовку Sec-WebSocket-Protocol
+// Клиент без заголовка получает ProtocolV1 (так подключались до появления версий)
+func Negotiate(r *http.Request) (string, error) {
+	requested := websocket.Subprotocols(r)
+	if len(requested) == 0 {
+		return ProtocolV1, nil
+	}
+	for _, supported := range Supported {
+		for _, protocol := range requested {
+			if protocol == supported {
+				return protocol, nil
+			}
+		}
+	}
+	return "", ErrUnsupportedProtocol
+}
+
+// Handler отдает asyncapi.json
+// Метод: GET
+// Возвращает: application/json с описанием протокола AsyncAPI 2
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(document)
+	})
+}
+
+// Inbound - кадр клиента
+type Inbound struct {
+	Type string `json:"type"`
+	Text string `json:"text,omitempty"` // Для type=message
+}
+
+// Decode разбирает кадр клиента; тип кадра проверяет обработчик
+func Decode(data []byte) (*Inbound, error) {
+	var in Inbound
+	if err := json.Unmarshal(data, &in); err != nil {
+		return nil, err
+	}
+	return &in, nil
+}
+
+// Message - сообщение чата в кадрах history и message
+type Message struct {
+	ID          string    `json:"id"`
+	ChatID      string    `json:"chat_id"`
+	UserID      string    `json:"user_id"`
+	Login       string    `json:"login"`
+	Text        string    `json:"text"`
+	SendingTime time.Time `json:"sending_time"`
+}
+
+// FromModel переводит сообщение из модели БД в формат протокола
+func FromModel(m *models.Message) Message {
+	return Message{
+		ID:          m.ID,
+		ChatID:      m.ChatID,
+		UserID:      m.UserID,
+		Login:       m.Login,
+		Text:        m.Text,
+		SendingTime: m.SendingTime,
+	}
+}
+
+// ChatInfo - кадр chat_info
+type ChatInfo struct {
+	Type string `json:"type"`
+	Name string `json:"name"` // Логин собеседника
+}
+
+// NewChatInfo создает кадр chat_info
+func NewChatInfo(name string) ChatInfo {
+	return ChatInfo{Type: TypeChatInfo, Name: name}
+}
+
+// History - кадр history; порядок сообщений зависит от версии протокола (см. NewHistoryFor)
+type History struct {
+	Type     string    `json:"type"`
+	Messages []Message `json:"messages"`
+}
+
+// NewHistory создает кадр history с сообщениями в переданном порядке; пустая история передается как [], а не null
+func NewHistory(messages []*models.Message) History {
+	h := History{Type: TypeHistory, Messages: make([]Message, 0, len(messages))}
+	for _, m := range messages {
+		h.Messages = append(h.Messages, FromModel(m))
+	}
+	return h
+}
+
+// NewHistoryFor создает кадр history версии protocol из сообщений от старых к новым
+// messenger.v2 передает их в том же порядке, messenger.v1 - от новых к старым
+func NewHistoryFor(protocol string, messages []*models.Message) History {
+	if protocol != ProtocolV1 {
+		return NewHistory(messages)
+	}
+	reversed := make([]*models.Message, len(messages))
+	for i, m := range messages {
+		reversed[len(messages)-1-i] = m
+	}
+	return NewHistory(reversed)
+}
+
+// MessageEvent - кадр message от сервера: новое сообщение в чате
+type MessageEvent struct {
+	Type    string  `json:"type"`
+	Message Message `json:"message"`
+}
+
+// NewMessageEvent создает кадр message
+func NewMessageEvent(m *models.Message) MessageEvent {
+	return MessageEvent{Type: TypeMessage, Message: FromModel(m)}
+}
+
+// Error - кадр error с теми же кодами, что и ошибки REST
+type Error struct {
+	Type string `json:"type"`
+	*apperr.Error
+}
+
+// NewError создает кадр error
+func NewError(e *apperr.Error) Error {
+	return Error{Type: TypeError, Error: e}
+}
@@ -0,0 +1,154 @@
+package wsproto
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"cursach/internal/models"
+	"cursach/internal/pkg/apperr"
+)
+
+// update перезаписывает эталоны: go test ./internal/pkg/wsproto -update
+// Изменение эталона - изменение протокола: оно должно сопровождаться правкой asyncapi.json или новой версией
+var update = flag.Bool("update", false, "rewrite testdata/*.golden")
+
+var (
+	sentAt = time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+	first = &models.Message{
+		ID: "m1", ChatID: "c1", UserID: "u1", Login: "alice", Text: "Привет", SendingTime: sentAt,
+	}
+	second = &models.Message{
+		ID: "m2", ChatID: "c1", UserID: "u2", Login: "bob", Text: "Привет!", SendingTime: sentAt.Add(5 * time.Second),
+	}
+)
+
+// serverFrames - кадры сервера по имени эталона; new возвращает пустое значение того же типа для разбора
+var serverFrames = []struct {
+	golden string
+	frame  interface{}
+	new    func() interface{}
+}{
+	{"chat_info", NewChatInfo("bob"), func() interface{} { return new(ChatInfo) }},
+	{"history_v1", NewHistoryFor(ProtocolV1, []*models.Message{first, second}), func() interface{} { return new(History) }},
+	{"history_v2", NewHistoryFor(ProtocolV2, []*models.Message{first, second}), func() interface{} { return new(History) }},
+	{"history_empty", NewHistory(nil), func() interface{} { return new(History) }},
+	{"message", NewMessageEvent(second), func() interface{} { return new(MessageEvent) }},
+	{"error", NewError(apperr.New(apperr.CodeEmptyMessage)), func() interface{} { return new(Error) }},
+	{"error_details", NewError(apperr.New(apperr.CodeRateLimited).WithDetail("retry_after", 1)), func() interface{} { return new(Error) }},
+}
+
+// golden сравнивает got с testdata/<name>.golden (или перезаписывает эталон с -update)
+func golden(t *testing.T, name string, got []byte) []byte {
+	t.Helper()
+
+	path := filepath.Join("testdata", name+".golden")
+	if *update {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatalf("failed to update %s: %v", path, err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", path, err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("frame differs from %s\ngot:  %s\nwant: %s", path, got, want)
+	}
+	return want
+}
+
+// encode кодирует кадр так же, как websocket.Conn.WriteJSON
+func encode(t *testing.T, frame interface{}) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(frame); err != nil {
+		t.Fatalf("failed to encode frame: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// TestServerFramesGolden сверяет кадры сервера с эталонами и проверяет, что разбор эталона
+// и повторное кодирование дают те же байты
+func TestServerFramesGolden(t *testing.T) {
+	for _, tt := range serverFrames {
+		t.Run(tt.golden, func(t *testing.T) {
+			want := golden(t, tt.golden, encode(t, tt.frame))
+
+			decoded := tt.new()
+			if err := json.Unmarshal(want, decoded); err != nil {
+				t.Fatalf("failed to decode golden frame: %v", err)
+			}
+			if got := encode(t, decoded); !bytes.Equal(got, want) {
+				t.Errorf("round trip changed the frame\ngot:  %s\nwant: %s", got, want)
+			}
+		})
+	}
+}
+
+// TestClientFrameGolden проверяет разбор кадра клиента из эталона и обратное кодирование
+func TestClientFrameGolden(t *testing.T) {
+	frame := Inbound{Type: TypeMessage, Text: "Привет"}
+	want := golden(t, "client_message", encode(t, frame))
+
+	decoded, err := Decode(want)
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if !reflect.DeepEqual(*decoded, frame) {
+		t.Errorf("Decode() = %+v, want %+v", *decoded, frame)
+	}
+	if got := encode(t, decoded); !bytes.Equal(got, want) {
+		t.Errorf("round trip changed the frame\ngot:  %s\nwant: %s", got, want)
+	}
+}
+
+// TestDecodeInvalid проверяет, что битый кадр клиента - ошибка разбора, а не пустой кадр
+func TestDecodeInvalid(t *testing.T) {
+	if _, err := Decode([]byte(`{"type":`)); err == nil {
+		t.Error("Decode accepted malformed JSON")
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		name    string
+		offered []string
+		want    string
+		wantErr error
+	}{
+		{name: "none offered", want: ProtocolV1},
+		{name: "v1 only", offered: []string{ProtocolV1}, want: ProtocolV1},
+		{name: "v2 only", offered: []string{ProtocolV2}, want: ProtocolV2},
+		{name: "server preference wins", offered: []string{ProtocolV1, ProtocolV2}, want: ProtocolV2},
+		{name: "supported among unknown", offered: []string{"messenger.v9", ProtocolV1}, want: ProtocolV1},
+		{name: "unsupported", offered: []string{"messenger.v9"}, wantErr: ErrUnsupportedProtocol},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/ws/c1", nil)
+			if len(tt.offered) > 0 {
+				// Браузер передает все версии одним заголовком через запятую
+				r.Header.Set("Sec-WebSocket-Protocol", strings.Join(tt.offered, ", "))
+			}
+
+			got, err := Negotiate(r)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Negotiate() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Negotiate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"context"
+	"cursach/internal/models"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// auditWhere - условия фильтра событий аудита ($1..$6), пустые параметры не ограничивают выборку
+const auditWhere = `
+	WHERE ($1 = '' OR actor_id::TEXT = $1)
+		AND ($2 = '' OR action = $2)
+		AND ($3 = '' OR target_type = $3)
+		AND ($4 = '' OR target_id = $4)
+		AND ($5::TIMESTAMPTZ IS NULL OR created_at >= $5)
+		AND ($6::TIMESTAMPTZ IS NULL OR created_at < $6)`
+
+// AuditRepository определяет интерфейс журнала аудита
+// Журнал только дополняется: записи не изменяются и не удаляются
+type AuditRepository interface {
+	// Append добавляет событие в журнал и заполняет его ID
+	Append(ctx context.Context, event *models.AuditEvent) error
+
+	// Query возвращает события по фильтру, самые новые первыми
+	Query(ctx context.Context, filter models.AuditFilter) ([]*models.AuditEvent, error)
+
+	// Each вызывает fn для каждого события по фильтру в хронологическом порядке
+	// Используется для потоковой выгрузки без загрузки всего журнала в память
+	Each(ctx context.Context, filter models.AuditFilter, fn func(*models.AuditEvent) error) error
+}
+
+// auditRepository реализует интерфейс AuditRepository
+type auditRepository struct {
+	db *sql.DB
+}
+
+// NewAuditRepository создает новый экземпляр AuditRepository
+func NewAuditRepository(db *sql.DB) AuditRepository {
+	return &auditRepository{db: db}
+}
+
+func (r *auditRepository) Append(ctx context.Context, event *models.AuditEvent) error {
+	details := event.Details
+	if len(details) == 0 {
+		details = []byte("{}")
+	}
+
+	err := r.db.QueryRowContext(ctx,
+		`INSERT INTO audit_events (actor_id, action, target_type, target_id, ip, details, created_at)
+		VALUES (NULLIF($1, '')::UUID, $2, $3, $4, $5, $6, $7)
+		RETURNING id_audit_event`,
+		event.ActorID,
+		event.Action,
+		event.TargetType,
+		event.TargetID,
+		event.IP,
+		string(details),
+		event.CreatedAt,
+	).Scan(&event.ID)
+	if err != nil {
+		return fmt.Errorf("failed to append audit event: %w", err)
+	}
+	return nil
+}
+
+func (r *auditRepository) Query(ctx context.Context, filter models.AuditFilter) ([]*models.AuditEvent, error) {
+	var events []*models.AuditEvent
+	err := r.each(ctx, filter, "DESC", func(e *models.AuditEvent) error {
+		events = append(events, e)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func (r *auditRepository) Each(ctx context.Context, filter models.AuditFilter, fn func(*models.AuditEvent) error) error {
+	return r.each(ctx, filter, "ASC", fn)
+}
+
+func (r *auditRepository) each(ctx context.Context, filter models.AuditFilter, order string, fn func(*models.AuditEvent) error) error {
+	// LIMIT NULL в PostgreSQL означает отсутствие ограничения
+	var limit sql.NullInt64
+	if filter.Limit > 0 {
+		limit = sql.NullInt64{Int64: int64(filter.Limit), Valid: true}
+	}
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT id_audit_event, COALESCE(actor_id::TEXT, ''), action, target_type, target_id, ip, details, created_at
+		FROM audit_events`+auditWhere+`
+		ORDER BY created_at `+order+`, id_audit_event
+		LIMIT $7 OFFSET $8`,
+		filter.ActorID,
+		filter.Action,
+		filter.TargetType,
+		filter.TargetID,
+		nullTime(filter.From),
+		nullTime(filter.To),
+		limit,
+		filter.Offset,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to query audit events: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			e       models.AuditEvent
+			details []byte
+		)
+		if err := rows.Scan(
+			&e.ID,
+			&e.ActorID,
+			&e.Action,
+			&e.TargetType,
+			&e.TargetID,
+			&e.IP,
+			&details,
+			&e.CreatedAt,
+		); err != nil {
+			return fmt.Errorf("failed to scan audit event: %w", err)
+		}
+		e.Details = details
+		if err := fn(&e); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("rows error: %w", err)
+	}
+	return nil
+}
+
+// nullTime преобразует нулевое время в NULL для SQL-параметра
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
@@ -6,7 +6,6 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
-	"log"
 )
 
 // ChatRepository определяет интерфейс для работы с чатами
@@ -178,7 +177,6 @@ func (r *chatRepository) GetChatUsers(ctx context.Context, chatID string) ([]mod
 
 func (r *chatRepository) IsUserInChat(ctx context.Context, chatID, userID string) (bool, error) {
 	var exists bool
-	log.Println(chatID, userID, "is user in chat")
 	err := r.db.QueryRowContext(ctx,
 		`SELECT EXISTS(
             SELECT 1 FROM chat_users 
@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"context"
+	"cursach/internal/models"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// memoryAttemptsLimit - сколько последних попыток входа хранит in-memory журнал
+	memoryAttemptsLimit = 1000
+	// memoryThrottleSweepSize - размер таблицы счетчиков, после которого удаляются устаревшие записи
+	memoryThrottleSweepSize = 10000
+)
+
+// memoryLoginAttemptRepository реализует LoginAttemptRepository в памяти процесса
+// Подходит для одного экземпляра сервера; состояние теряется при перезапуске
+type memoryLoginAttemptRepository struct {
+	mu        sync.Mutex
+	throttles map[string]*models.LoginThrottle
+	attempts  []*models.LoginAttempt
+	nextID    int
+}
+
+// NewMemoryLoginAttemptRepository создает in-memory хранилище попыток входа
+func NewMemoryLoginAttemptRepository() LoginAttemptRepository {
+	return &memoryLoginAttemptRepository{
+		throttles: make(map[string]*models.LoginThrottle),
+	}
+}
+
+func (r *memoryLoginAttemptRepository) RegisterFailure(_ context.Context, key string, now time.Time, window time.Duration) (*models.LoginThrottle, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.throttles) >= memoryThrottleSweepSize {
+		r.sweep(now, window)
+	}
+
+	t, ok := r.throttles[key]
+	if !ok {
+		t = &models.LoginThrottle{Key: key}
+		r.throttles[key] = t
+	}
+	if t.LastFailure.Before(now.Add(-window)) {
+		t.Failures = 0
+	}
+	t.Failures++
+	t.LastFailure = now
+
+	result := *t
+	return &result, nil
+}
+
+func (r *memoryLoginAttemptRepository) GetThrottle(_ context.Context, key string) (*models.LoginThrottle, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t, ok := r.throttles[key]
+	if !ok {
+		return nil, nil
+	}
+	result := *t
+	return &result, nil
+}
+
+func (r *memoryLoginAttemptRepository) Lock(_ context.Context, key string, until time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if t, ok := r.throttles[key]; ok {
+		t.LockedUntil = until
+	}
+	return nil
+}
+
+func (r *memoryLoginAttemptRepository) Reset(_ context.Context, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.throttles, key)
+	return nil
+}
+
+func (r *memoryLoginAttemptRepository) RecordAttempt(_ context.Context, attempt *models.LoginAttempt) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nextID++
+	attempt.ID = strconv.Itoa(r.nextID)
+	stored := *attempt
+
+	// Храним только последние memoryAttemptsLimit записей
+	if len(r.attempts) >= memoryAttemptsLimit {
+		r.attempts = r.attempts[1:]
+	}
+	r.attempts = append(r.attempts, &stored)
+	return nil
+}
+
+func (r *memoryLoginAttemptRepository) ListAttempts(_ context.Context, login, ip string, limit int) ([]*models.LoginAttempt, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var attempts []*models.LoginAttempt
+	// Идем с конца, чтобы вернуть самые свежие попытки первыми
+	for i := len(r.attempts) - 1; i >= 0 && len(attempts) < limit; i-- {
+		a := r.attempts[i]
+		if (login != "" && a.Login != login) || (ip != "" && a.IP != ip) {
+			continue
+		}
+		result := *a
+		attempts = append(attempts, &result)
+	}
+	return attempts, nil
+}
+
+// sweep удаляет счетчики, у которых истекло окно и нет активной блокировки
+func (r *memoryLoginAttemptRepository) sweep(now time.Time, window time.Duration) {
+	for key, t := range r.throttles {
+		if t.LastFailure.Before(now.Add(-window)) && !t.LockedUntil.After(now) {
+			delete(r.throttles, key)
+		}
+	}
+}
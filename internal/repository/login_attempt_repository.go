@@ -0,0 +1,163 @@
+package repository
+
+import (
+	"context"
+	"cursach/internal/models"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// LoginAttemptRepository определяет интерфейс хранилища попыток входа
+// Используется для защиты от перебора паролей и для просмотра попыток администраторами
+type LoginAttemptRepository interface {
+	// RegisterFailure увеличивает счетчик неудач по ключу и возвращает новое состояние
+	// Если последняя неудача была раньше now-window, счетчик начинается заново
+	RegisterFailure(ctx context.Context, key string, now time.Time, window time.Duration) (*models.LoginThrottle, error)
+
+	// GetThrottle возвращает состояние счетчика по ключу (nil, если записей нет)
+	GetThrottle(ctx context.Context, key string) (*models.LoginThrottle, error)
+
+	// Lock устанавливает блокировку ключа до указанного времени
+	Lock(ctx context.Context, key string, until time.Time) error
+
+	// Reset сбрасывает счетчик и блокировку по ключу
+	Reset(ctx context.Context, key string) error
+
+	// RecordAttempt сохраняет попытку входа в журнал
+	RecordAttempt(ctx context.Context, attempt *models.LoginAttempt) error
+
+	// ListAttempts возвращает последние попытки входа, опционально отфильтрованные по логину и IP
+	ListAttempts(ctx context.Context, login, ip string, limit int) ([]*models.LoginAttempt, error)
+}
+
+// loginAttemptRepository реализует LoginAttemptRepository поверх PostgreSQL
+// Подходит для нескольких экземпляров сервера, использующих одну базу
+type loginAttemptRepository struct {
+	db *sql.DB
+}
+
+// NewLoginAttemptRepository создает новый экземпляр LoginAttemptRepository на основе PostgreSQL
+func NewLoginAttemptRepository(db *sql.DB) LoginAttemptRepository {
+	return &loginAttemptRepository{db: db}
+}
+
+func (r *loginAttemptRepository) RegisterFailure(ctx context.Context, key string, now time.Time, window time.Duration) (*models.LoginThrottle, error) {
+	var (
+		t           models.LoginThrottle
+		lockedUntil sql.NullTime
+	)
+	// Счетчик обновляется атомарно, чтобы параллельные экземпляры не теряли неудачные попытки
+	err := r.db.QueryRowContext(ctx,
+		`INSERT INTO login_throttle (throttle_key, failures, last_failure)
+		VALUES ($1, 1, $2)
+		ON CONFLICT (throttle_key) DO UPDATE SET
+			failures = CASE
+				WHEN login_throttle.last_failure < $3 THEN 1
+				ELSE login_throttle.failures + 1
+			END,
+			last_failure = EXCLUDED.last_failure
+		RETURNING throttle_key, failures, last_failure, locked_until`,
+		key, now, now.Add(-window),
+	).Scan(&t.Key, &t.Failures, &t.LastFailure, &lockedUntil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to register login failure: %w", err)
+	}
+	if lockedUntil.Valid {
+		t.LockedUntil = lockedUntil.Time
+	}
+	return &t, nil
+}
+
+func (r *loginAttemptRepository) GetThrottle(ctx context.Context, key string) (*models.LoginThrottle, error) {
+	var (
+		t           models.LoginThrottle
+		lockedUntil sql.NullTime
+	)
+	err := r.db.QueryRowContext(ctx,
+		`SELECT throttle_key, failures, last_failure, locked_until
+		FROM login_throttle
+		WHERE throttle_key = $1`,
+		key,
+	).Scan(&t.Key, &t.Failures, &t.LastFailure, &lockedUntil)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get login throttle: %w", err)
+	}
+	if lockedUntil.Valid {
+		t.LockedUntil = lockedUntil.Time
+	}
+	return &t, nil
+}
+
+func (r *loginAttemptRepository) Lock(ctx context.Context, key string, until time.Time) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE login_throttle SET locked_until = $1 WHERE throttle_key = $2`,
+		until, key,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to lock login throttle: %w", err)
+	}
+	return nil
+}
+
+func (r *loginAttemptRepository) Reset(ctx context.Context, key string) error {
+	_, err := r.db.ExecContext(ctx,
+		`DELETE FROM login_throttle WHERE throttle_key = $1`,
+		key,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to reset login throttle: %w", err)
+	}
+	return nil
+}
+
+func (r *loginAttemptRepository) RecordAttempt(ctx context.Context, attempt *models.LoginAttempt) error {
+	err := r.db.QueryRowContext(ctx,
+		`INSERT INTO login_attempts (login, ip, success, attempted_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id_login_attempt`,
+		attempt.Login,
+		attempt.IP,
+		attempt.Success,
+		attempt.AttemptedAt,
+	).Scan(&attempt.ID)
+	if err != nil {
+		return fmt.Errorf("failed to record login attempt: %w", err)
+	}
+	return nil
+}
+
+func (r *loginAttemptRepository) ListAttempts(ctx context.Context, login, ip string, limit int) ([]*models.LoginAttempt, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT id_login_attempt, login, ip, success, attempted_at
+		FROM login_attempts
+		WHERE ($1 = '' OR login = $1) AND ($2 = '' OR ip = $2)
+		ORDER BY attempted_at DESC
+		LIMIT $3`,
+		login, ip, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list login attempts: %w", err)
+	}
+	defer rows.Close()
+
+	var attempts []*models.LoginAttempt
+	for rows.Next() {
+		var a models.LoginAttempt
+		if err := rows.Scan(&a.ID, &a.Login, &a.IP, &a.Success, &a.AttemptedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan login attempt: %w", err)
+		}
+		attempts = append(attempts, &a)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return attempts, nil
+}
@@ -9,7 +9,7 @@ import (
 
 // MessageRepository определяет интерфейс для работы с сообщениями
 type MessageRepository interface {
-	// Create создает новое сообщение и возвращает его ID
+	// Create создает новое сообщение и возвращает его ID; время отправки и номер записываются в message
 	Create(ctx context.Context, message *models.Message) (string, error)
 
 	// GetByID возвращает сообщение по его ID
@@ -18,6 +18,9 @@ type MessageRepository interface {
 	// GetByChat возвращает сообщения для указанного чата
 	GetByChat(ctx context.Context, chatID string, limit int) ([]*models.Message, error)
 
+	// GetByChatAfter возвращает сообщения чата с номером больше after, в порядке номеров
+	GetByChatAfter(ctx context.Context, chatID string, after int64, limit int) ([]*models.Message, error)
+
 	// Update обновляет текст сообщения - не используется, возможно в дальнейшем при развитии проекта
 	Update(ctx context.Context, messageID, newText string) error
 
@@ -36,26 +39,48 @@ func NewMessageRepository(db *sql.DB) MessageRepository {
 }
 
 func (r *messageRepository) Create(ctx context.Context, message *models.Message) (string, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Блокировка строки чата держится до фиксации: следующее сообщение чата получит больший номер
+	// и станет видимым только после этого
+	err = tx.QueryRowContext(ctx,
+		`UPDATE chats SET last_message_seq = last_message_seq + 1
+		WHERE id_chat = $1
+		RETURNING last_message_seq`,
+		message.ChatID,
+	).Scan(&message.Seq)
+	if err != nil {
+		return "", fmt.Errorf("failed to allocate message seq: %w", err)
+	}
+
 	var messageID string
-	err := r.db.QueryRowContext(ctx,
-		`INSERT INTO messages (id_chat, id_user, message_text) 
-		VALUES ($1, $2, $3) 
-		RETURNING id_message`,
+	err = tx.QueryRowContext(ctx,
+		`INSERT INTO messages (id_chat, id_user, message_text, seq) 
+		VALUES ($1, $2, $3, $4) 
+		RETURNING id_message, sending_time`,
 		message.ChatID,
 		message.UserID,
 		message.Text,
-	).Scan(&messageID)
+		message.Seq,
+	).Scan(&messageID, &message.SendingTime)
 
 	if err != nil {
 		return "", fmt.Errorf("failed to create message: %w", err)
 	}
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("failed to commit message: %w", err)
+	}
 	return messageID, nil
 }
 
 func (r *messageRepository) GetByID(ctx context.Context, messageID string) (*models.Message, error) {
 	var msg models.Message
 	err := r.db.QueryRowContext(ctx,
-		`SELECT id_message, id_chat, id_user, message_text, sending_time, updated_at
+		`SELECT id_message, id_chat, id_user, message_text, sending_time, seq, updated_at
 		FROM messages
 		WHERE id_message = $1`,
 		messageID,
@@ -65,6 +90,7 @@ func (r *messageRepository) GetByID(ctx context.Context, messageID string) (*mod
 		&msg.UserID,
 		&msg.Text,
 		&msg.SendingTime,
+		&msg.Seq,
 		&msg.UpdatedAt,
 	)
 
@@ -83,11 +109,12 @@ func (r *messageRepository) GetByChat(ctx context.Context, chatID string, limit
 			u.login,  -- Добавлен логин пользователя
 			m.message_text, 
 			m.sending_time, 
+			m.seq,
 			m.updated_at
 		FROM messages m
 		JOIN users u ON m.id_user = u.id_user
 		WHERE m.id_chat = $1
-		ORDER BY m.sending_time DESC
+		ORDER BY m.seq DESC
 		LIMIT $2`,
 		chatID,
 		limit,
@@ -108,6 +135,7 @@ func (r *messageRepository) GetByChat(ctx context.Context, chatID string, limit
 			&msg.Login, // Сканируем логин
 			&msg.Text,
 			&msg.SendingTime,
+			&msg.Seq,
 			&msg.UpdatedAt,
 		)
 		if err != nil {
@@ -123,6 +151,37 @@ func (r *messageRepository) GetByChat(ctx context.Context, chatID string, limit
 	return messages, nil
 }
 
+func (r *messageRepository) GetByChatAfter(ctx context.Context, chatID string, after int64, limit int) ([]*models.Message, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT m.id_message, m.id_chat, m.id_user, u.login, m.message_text, m.sending_time, m.seq, m.updated_at
+		FROM messages m
+		JOIN users u ON m.id_user = u.id_user
+		WHERE m.id_chat = $1 AND m.seq > $2
+		ORDER BY m.seq ASC
+		LIMIT $3`,
+		chatID,
+		after,
+		limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get messages after seq %d: %w", after, err)
+	}
+	defer rows.Close()
+
+	var messages []*models.Message
+	for rows.Next() {
+		var msg models.Message
+		if err := rows.Scan(&msg.ID, &msg.ChatID, &msg.UserID, &msg.Login, &msg.Text, &msg.SendingTime, &msg.Seq, &msg.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan message: %w", err)
+		}
+		messages = append(messages, &msg)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return messages, nil
+}
+
 func (r *messageRepository) Update(ctx context.Context, messageID, newText string) error {
 	_, err := r.db.ExecContext(ctx,
 		`UPDATE messages 
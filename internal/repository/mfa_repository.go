@@ -0,0 +1,172 @@
+package repository
+
+import (
+	"context"
+	"cursach/internal/models"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// MFARepository определяет интерфейс для работы с двухфакторной аутентификацией
+type MFARepository interface {
+	// GetMFA возвращает настройки 2FA пользователя (nil, если 2FA не подключалась)
+	GetMFA(ctx context.Context, userID string) (*models.UserMFA, error)
+
+	// SaveSecret сохраняет новый секрет TOTP; 2FA остается выключенной до подтверждения
+	SaveSecret(ctx context.Context, userID, secret string) error
+
+	// Enable включает 2FA и заменяет коды восстановления на переданные хеши
+	Enable(ctx context.Context, userID string, step int64, recoveryCodeHashes []string) error
+
+	// Disable выключает 2FA и удаляет секрет и коды восстановления
+	Disable(ctx context.Context, userID string) error
+
+	// UseStep помечает шаг TOTP как использованный
+	// Возвращает false, если этот или более поздний шаг уже использовался
+	UseStep(ctx context.Context, userID string, step int64) (bool, error)
+
+	// UseRecoveryCode помечает код восстановления как использованный
+	// Возвращает false, если кода нет или он уже использован
+	UseRecoveryCode(ctx context.Context, userID, codeHash string) (bool, error)
+}
+
+// mfaRepository реализует интерфейс MFARepository
+type mfaRepository struct {
+	db *sql.DB
+}
+
+// NewMFARepository создает новый экземпляр MFARepository
+func NewMFARepository(db *sql.DB) MFARepository {
+	return &mfaRepository{db: db}
+}
+
+func (r *mfaRepository) GetMFA(ctx context.Context, userID string) (*models.UserMFA, error) {
+	var mfa models.UserMFA
+	err := r.db.QueryRowContext(ctx,
+		`SELECT id_user, totp_secret, enabled, last_used_step, created_at, confirmed_at
+		FROM user_mfa
+		WHERE id_user = $1`,
+		userID,
+	).Scan(
+		&mfa.UserID,
+		&mfa.Secret,
+		&mfa.Enabled,
+		&mfa.LastUsedStep,
+		&mfa.CreatedAt,
+		&mfa.ConfirmedAt,
+	)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user mfa: %w", err)
+	}
+	return &mfa, nil
+}
+
+func (r *mfaRepository) SaveSecret(ctx context.Context, userID, secret string) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO user_mfa (id_user, totp_secret)
+		VALUES ($1, $2)
+		ON CONFLICT (id_user) DO UPDATE SET
+			totp_secret = EXCLUDED.totp_secret,
+			enabled = FALSE,
+			last_used_step = 0,
+			created_at = NOW(),
+			confirmed_at = NULL`,
+		userID, secret,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save mfa secret: %w", err)
+	}
+	return nil
+}
+
+func (r *mfaRepository) Enable(ctx context.Context, userID string, step int64, recoveryCodeHashes []string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx,
+		`UPDATE user_mfa
+		SET enabled = TRUE, last_used_step = $1, confirmed_at = NOW()
+		WHERE id_user = $2`,
+		step, userID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to enable mfa: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`DELETE FROM mfa_recovery_codes WHERE id_user = $1`,
+		userID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+
+	for _, hash := range recoveryCodeHashes {
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO mfa_recovery_codes (id_user, code_hash) VALUES ($1, $2)`,
+			userID, hash,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to save recovery code: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func (r *mfaRepository) Disable(ctx context.Context, userID string) error {
+	// Коды восстановления удаляются каскадно вместе с записью user_mfa
+	_, err := r.db.ExecContext(ctx,
+		`DELETE FROM user_mfa WHERE id_user = $1`,
+		userID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to disable mfa: %w", err)
+	}
+	return nil
+}
+
+func (r *mfaRepository) UseStep(ctx context.Context, userID string, step int64) (bool, error) {
+	res, err := r.db.ExecContext(ctx,
+		`UPDATE user_mfa
+		SET last_used_step = $1
+		WHERE id_user = $2 AND last_used_step < $1`,
+		step, userID,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to use totp step: %w", err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to use totp step: %w", err)
+	}
+	return affected == 1, nil
+}
+
+func (r *mfaRepository) UseRecoveryCode(ctx context.Context, userID, codeHash string) (bool, error) {
+	res, err := r.db.ExecContext(ctx,
+		`UPDATE mfa_recovery_codes
+		SET used_at = NOW()
+		WHERE id_user = $1 AND code_hash = $2 AND used_at IS NULL`,
+		userID, codeHash,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to use recovery code: %w", err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to use recovery code: %w", err)
+	}
+	return affected > 0, nil
+}
@@ -3,13 +3,24 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"time"
 )
 
 // TokenRepository определяет интерфейс для работы с токенами
 type TokenRepository interface {
 	RevokeToken(ctx context.Context, token, userID string) error
 	IsTokenRevoked(ctx context.Context, token string) (bool, error)
+
+	// RevokeUserTokens отзывает все токены пользователя, выданные не позже before
+	// Момент отзыва хранится с точностью iat (до секунды, с округлением вниз): токены, выданные в ту же
+	// секунду, что и отзыв, отзываются, включая выданные сразу после него. Выбрана надежность:
+	// иначе уцелели бы токены, выданные в начале секунды до отзыва, а повторный вход лишь откладывается до следующей секунды
+	RevokeUserTokens(ctx context.Context, userID string, before time.Time) error
+
+	// IsUserTokenRevoked проверяет, отозваны ли токены пользователя, выданные в момент issuedAt
+	IsUserTokenRevoked(ctx context.Context, userID string, issuedAt time.Time) (bool, error)
 }
 
 type tokenRepository struct {
@@ -45,3 +56,34 @@ func (r *tokenRepository) IsTokenRevoked(ctx context.Context, token string) (boo
 	}
 	return exists, nil
 }
+
+func (r *tokenRepository) RevokeUserTokens(ctx context.Context, userID string, before time.Time) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO user_token_revocations (id_user, revoked_before)
+		VALUES ($1, $2)
+		ON CONFLICT (id_user) DO UPDATE SET revoked_before = GREATEST(user_token_revocations.revoked_before, EXCLUDED.revoked_before)`,
+		userID,
+		before.Truncate(time.Second),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to revoke user tokens: %w", err)
+	}
+	return nil
+}
+
+func (r *tokenRepository) IsUserTokenRevoked(ctx context.Context, userID string, issuedAt time.Time) (bool, error) {
+	var revokedBefore time.Time
+	err := r.db.QueryRowContext(ctx,
+		`SELECT revoked_before FROM user_token_revocations WHERE id_user = $1`,
+		userID,
+	).Scan(&revokedBefore)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to check user token revocation: %w", err)
+	}
+	// revoked_before и iat оба с точностью до секунды: токен той же секунды, что и отзыв, отозван
+	return !issuedAt.After(revokedBefore), nil
+}
@@ -6,7 +6,6 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
-	"log"
 )
 
 // UserRepository определяет интерфейс для работы с пользователями системы
@@ -37,6 +36,18 @@ type UserRepository interface {
 
 	// SearchUsersByLogin ищет и получает модель пользователя по его ID
 	SearchUsersByLogin(ctx context.Context, login string) ([]*models.User, error)
+
+	// ListUsers возвращает страницу пользователей по фильтру и общее количество подходящих
+	ListUsers(ctx context.Context, filter models.UserFilter) ([]*models.User, int, error)
+
+	// GetUserStats возвращает статистику активности пользователя
+	GetUserStats(ctx context.Context, userID string) (*models.UserStats, error)
+
+	// UpdateRole изменяет роль пользователя
+	UpdateRole(ctx context.Context, userID, role string) error
+
+	// SetBanned блокирует или разблокирует пользователя
+	SetBanned(ctx context.Context, userID string, banned bool) error
 }
 
 // userRepository реализует интерфейс UserRepository
@@ -67,7 +78,7 @@ func (r *userRepository) CreateUser(ctx context.Context, login, passwordHash, ro
 func (r *userRepository) GetUserByID(ctx context.Context, userID string) (*models.User, error) {
 	var user models.User
 	err := r.db.QueryRowContext(ctx,
-		`SELECT id_user, login, password_hash, role, created_at, updated_at
+		`SELECT id_user, login, password_hash, role, created_at, updated_at, banned_at
 		FROM users
 		WHERE id_user = $1`,
 		userID,
@@ -78,6 +89,7 @@ func (r *userRepository) GetUserByID(ctx context.Context, userID string) (*model
 		&user.Role,
 		&user.CreatedAt,
 		&user.UpdatedAt,
+		&user.BannedAt,
 	)
 
 	if errors.Is(err, sql.ErrNoRows) {
@@ -156,14 +168,13 @@ func (r *userRepository) GetUserByID(ctx context.Context, userID string) (*model
 	}
 
 	user.Chats = chats
-	log.Println("чаты", chats)
 	return &user, nil
 }
 
 func (r *userRepository) GetUserByLogin(ctx context.Context, login string) (*models.User, error) {
 	var user models.User
 	err := r.db.QueryRowContext(ctx,
-		`SELECT id_user, login, password_hash, role, created_at, updated_at
+		`SELECT id_user, login, password_hash, role, created_at, updated_at, banned_at
 		FROM users
 		WHERE login = $1`,
 		login,
@@ -174,6 +185,7 @@ func (r *userRepository) GetUserByLogin(ctx context.Context, login string) (*mod
 		&user.Role,
 		&user.CreatedAt,
 		&user.UpdatedAt,
+		&user.BannedAt,
 	)
 
 	if errors.Is(err, sql.ErrNoRows) {
@@ -289,3 +301,104 @@ func (r *userRepository) SearchUsersByLogin(ctx context.Context, login string) (
 
 	return users, nil
 }
+
+func (r *userRepository) ListUsers(ctx context.Context, filter models.UserFilter) ([]*models.User, int, error) {
+	// Параметры фильтра: пустые значения отключают соответствующее условие
+	var banned sql.NullBool
+	if filter.Banned != nil {
+		banned = sql.NullBool{Bool: *filter.Banned, Valid: true}
+	}
+	const where = `
+		WHERE ($1 = '' OR login LIKE $1 || '%')
+			AND ($2 = '' OR role = $2)
+			AND ($3::BOOLEAN IS NULL OR (banned_at IS NOT NULL) = $3)`
+
+	var total int
+	err := r.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM users`+where,
+		filter.LoginPrefix, filter.Role, banned,
+	).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count users: %w", err)
+	}
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT id_user, login, role, created_at, updated_at, banned_at
+		FROM users`+where+`
+		ORDER BY created_at, id_user
+		LIMIT $4 OFFSET $5`,
+		filter.LoginPrefix, filter.Role, banned, filter.Limit, filter.Offset,
+	)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list users: %w", err)
+	}
+	defer rows.Close()
+
+	var users []*models.User
+	for rows.Next() {
+		var user models.User
+		if err := rows.Scan(
+			&user.ID,
+			&user.Login,
+			&user.Role,
+			&user.CreatedAt,
+			&user.UpdatedAt,
+			&user.BannedAt,
+		); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, &user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("rows error: %w", err)
+	}
+
+	return users, total, nil
+}
+
+func (r *userRepository) GetUserStats(ctx context.Context, userID string) (*models.UserStats, error) {
+	var stats models.UserStats
+	err := r.db.QueryRowContext(ctx,
+		`SELECT
+			(SELECT COUNT(*) FROM chat_users WHERE id_user = $1),
+			(SELECT COUNT(*) FROM messages WHERE id_user = $1),
+			(SELECT MAX(sending_time) FROM messages WHERE id_user = $1)`,
+		userID,
+	).Scan(&stats.ChatsCount, &stats.MessagesCount, &stats.LastMessageAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user stats: %w", err)
+	}
+	return &stats, nil
+}
+
+func (r *userRepository) UpdateRole(ctx context.Context, userID, role string) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE users
+		SET role = $1, updated_at = NOW()
+		WHERE id_user = $2`,
+		role,
+		userID,
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to update user role: %w", err)
+	}
+	return nil
+}
+
+func (r *userRepository) SetBanned(ctx context.Context, userID string, banned bool) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE users
+		SET banned_at = CASE WHEN $1 THEN COALESCE(banned_at, NOW()) ELSE NULL END,
+			updated_at = NOW()
+		WHERE id_user = $2`,
+		banned,
+		userID,
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to update user ban: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"cursach/internal/models"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrTicketNotFound - билета нет, он уже погашен или истек
+var ErrTicketNotFound = errors.New("ws ticket not found")
+
+// WSTicketRepository определяет интерфейс хранилища одноразовых билетов WebSocket
+// Хранится в PostgreSQL: билет выдается через REST и может погашаться на другом экземпляре сервера
+type WSTicketRepository interface {
+	// CreateTicket сохраняет билет и удаляет истекшие
+	CreateTicket(ctx context.Context, ticket *models.WSTicket) error
+
+	// ConsumeTicket атомарно удаляет билет по хешу и возвращает его
+	// Истекший или уже погашенный билет - ErrTicketNotFound
+	ConsumeTicket(ctx context.Context, hash string, now time.Time) (*models.WSTicket, error)
+}
+
+type wsTicketRepository struct {
+	db *sql.DB
+}
+
+// NewWSTicketRepository создает новый экземпляр WSTicketRepository на основе PostgreSQL
+func NewWSTicketRepository(db *sql.DB) WSTicketRepository {
+	return &wsTicketRepository{db: db}
+}
+
+func (r *wsTicketRepository) CreateTicket(ctx context.Context, ticket *models.WSTicket) error {
+	// Билеты живут секунды, поэтому отдельная очистка не нужна: истекшие удаляются при выдаче новых
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM ws_tickets WHERE expires_at < $1`, ticket.IssuedAt); err != nil {
+		return fmt.Errorf("failed to delete expired ws tickets: %w", err)
+	}
+
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO ws_tickets (ticket_hash, id_user, issued_at, expires_at) VALUES ($1, $2, $3, $4)`,
+		ticket.Hash,
+		ticket.UserID,
+		ticket.IssuedAt,
+		ticket.ExpiresAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create ws ticket: %w", err)
+	}
+	return nil
+}
+
+func (r *wsTicketRepository) ConsumeTicket(ctx context.Context, hash string, now time.Time) (*models.WSTicket, error) {
+	ticket := models.WSTicket{Hash: hash}
+	// Удаление с RETURNING гарантирует, что билет погасит только одно подключение
+	err := r.db.QueryRowContext(ctx,
+		`DELETE FROM ws_tickets WHERE ticket_hash = $1 RETURNING id_user, issued_at, expires_at`,
+		hash,
+	).Scan(&ticket.UserID, &ticket.IssuedAt, &ticket.ExpiresAt)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrTicketNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to consume ws ticket: %w", err)
+	}
+	if !now.Before(ticket.ExpiresAt) {
+		return nil, ErrTicketNotFound
+	}
+	return &ticket, nil
+}
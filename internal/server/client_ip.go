@@ -0,0 +1,135 @@
+package server
+
+import (
+	"cursach/internal/pkg/reqctx"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// TrustedProxyUnix - элемент списка доверенных прокси, означающий соединения через unix-сокет (server.unix_socket)
+const TrustedProxyUnix = "unix"
+
+// ProxyPolicy определяет, от каких соединений принимаются заголовки X-Forwarded-For и X-Real-IP
+// Заголовки других клиентов не учитываются, так как клиент может их подделать
+type ProxyPolicy struct {
+	networks []*net.IPNet
+	unix     bool // Соединениям через unix-сокет доверяем: к нему подключается локальный обратный прокси
+}
+
+// NewProxyPolicy создает политику по списку адресов прокси: CIDR, отдельные IP и TrustedProxyUnix
+func NewProxyPolicy(trusted []string) (*ProxyPolicy, error) {
+	p := &ProxyPolicy{}
+	for _, entry := range trusted {
+		if entry == TrustedProxyUnix {
+			p.unix = true
+			continue
+		}
+		network, err := ParseTrustedProxy(entry)
+		if err != nil {
+			return nil, err
+		}
+		p.networks = append(p.networks, network)
+	}
+	return p, nil
+}
+
+// ParseTrustedProxy разбирает адрес доверенного прокси: CIDR или отдельный IP
+func ParseTrustedProxy(entry string) (*net.IPNet, error) {
+	if _, network, err := net.ParseCIDR(entry); err == nil {
+		return network, nil
+	}
+	ip := net.ParseIP(entry)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid trusted proxy %q: expected CIDR, IP or %q", entry, TrustedProxyUnix)
+	}
+	bits := 8 * net.IPv6len
+	if ip4 := ip.To4(); ip4 != nil {
+		ip, bits = ip4, 8*net.IPv4len
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+}
+
+// ClientIP определяет IP клиента запроса; пустая строка - адрес неизвестен
+// Если соединение пришло от доверенного прокси, адрес берется из X-Forwarded-For (справа налево,
+// до первого недоверенного адреса), иначе из X-Real-IP. Политика nil не доверяет никому
+func (p *ProxyPolicy) ClientIP(r *http.Request) string {
+	peer := peerIP(r)
+	if !p.trustsPeer(peer) {
+		return ipString(peer)
+	}
+
+	var forwarded []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		forwarded = append(forwarded, strings.Split(header, ",")...)
+	}
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		ip := net.ParseIP(strings.TrimSpace(forwarded[i]))
+		if ip == nil {
+			break // Цепочку после испорченного элемента составил не наш прокси
+		}
+		if !p.trusts(ip) || i == 0 {
+			return ip.String()
+		}
+	}
+	if ip := net.ParseIP(strings.TrimSpace(r.Header.Get("X-Real-IP"))); ip != nil {
+		return ip.String()
+	}
+	return ipString(peer)
+}
+
+// trustsPeer проверяет непосредственного собеседника; nil - соединение не по TCP (unix-сокет)
+func (p *ProxyPolicy) trustsPeer(peer net.IP) bool {
+	if p == nil {
+		return false
+	}
+	if peer == nil {
+		return p.unix
+	}
+	return p.trusts(peer)
+}
+
+func (p *ProxyPolicy) trusts(ip net.IP) bool {
+	for _, network := range p.networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// peerIP возвращает IP из RemoteAddr; для unix-сокета RemoteAddr не содержит IP, и возвращается nil
+func peerIP(r *http.Request) net.IP {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return net.ParseIP(host)
+}
+
+func ipString(ip net.IP) string {
+	if ip == nil {
+		return ""
+	}
+	return ip.String()
+}
+
+// ClientIPMiddleware определяет IP клиента по политике прокси и сохраняет его в контексте
+// для ограничений входа, частоты запросов и журнала аудита
+func ClientIPMiddleware(proxies *ProxyPolicy) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := reqctx.WithClientIP(r.Context(), proxies.ClientIP(r))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// ClientIP возвращает IP клиента, определенный ClientIPMiddleware; пустая строка - адрес неизвестен
+// Ключи ограничений по IP для пустого адреса не создаются, чтобы все такие клиенты не делили один счетчик
+func ClientIP(r *http.Request) string {
+	return reqctx.ClientIP(r.Context())
+}
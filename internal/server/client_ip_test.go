@@ -0,0 +1,71 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestProxyPolicyClientIP(t *testing.T) {
+	tests := []struct {
+		name       string
+		trusted    []string
+		remoteAddr string
+		forwarded  []string // Значения X-Forwarded-For, по одному заголовку на элемент
+		realIP     string
+		want       string
+	}{
+		{name: "direct client", remoteAddr: "203.0.113.7:5000", want: "203.0.113.7"},
+		{name: "headers from untrusted peer ignored", remoteAddr: "203.0.113.7:5000",
+			forwarded: []string{"198.51.100.1"}, realIP: "198.51.100.2", want: "203.0.113.7"},
+		{name: "nil policy trusts nobody", remoteAddr: "10.0.0.1:5000", forwarded: []string{"198.51.100.1"}, want: "10.0.0.1"},
+		{name: "trusted proxy", trusted: []string{"10.0.0.0/8"}, remoteAddr: "10.0.0.1:5000",
+			forwarded: []string{"198.51.100.1"}, want: "198.51.100.1"},
+		{name: "spoofed entries left of the client ignored", trusted: []string{"10.0.0.0/8"}, remoteAddr: "10.0.0.1:5000",
+			forwarded: []string{"1.2.3.4, 198.51.100.1"}, want: "198.51.100.1"},
+		{name: "proxy chain", trusted: []string{"10.0.0.0/8", "192.0.2.10"}, remoteAddr: "10.0.0.1:5000",
+			forwarded: []string{"198.51.100.1, 192.0.2.10", "10.0.0.2"}, want: "198.51.100.1"},
+		{name: "all hops trusted", trusted: []string{"10.0.0.0/8"}, remoteAddr: "10.0.0.1:5000",
+			forwarded: []string{"10.0.0.3, 10.0.0.2"}, want: "10.0.0.3"},
+		{name: "X-Real-IP", trusted: []string{"10.0.0.1"}, remoteAddr: "10.0.0.1:5000", realIP: "198.51.100.2", want: "198.51.100.2"},
+		{name: "trusted proxy without headers", trusted: []string{"10.0.0.0/8"}, remoteAddr: "10.0.0.1:5000", want: "10.0.0.1"},
+		{name: "ipv6", trusted: []string{"fd00::/8"}, remoteAddr: "[fd00::1]:5000",
+			forwarded: []string{"2001:db8::1"}, want: "2001:db8::1"},
+		{name: "unix socket untrusted", remoteAddr: "@", forwarded: []string{"198.51.100.1"}, want: ""},
+		{name: "unix socket trusted", trusted: []string{TrustedProxyUnix}, remoteAddr: "@",
+			forwarded: []string{"198.51.100.1"}, want: "198.51.100.1"},
+		{name: "unix socket without headers", trusted: []string{TrustedProxyUnix}, remoteAddr: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var p *ProxyPolicy
+			if tt.trusted != nil {
+				var err error
+				if p, err = NewProxyPolicy(tt.trusted); err != nil {
+					t.Fatalf("NewProxyPolicy: %v", err)
+				}
+			}
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			for _, value := range tt.forwarded {
+				r.Header.Add("X-Forwarded-For", value)
+			}
+			if tt.realIP != "" {
+				r.Header.Set("X-Real-IP", tt.realIP)
+			}
+
+			if got := p.ClientIP(r); got != tt.want {
+				t.Errorf("ClientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewProxyPolicyRejectsInvalidEntry(t *testing.T) {
+	for _, entry := range []string{"proxy.local", "10.0.0.0/33", ""} {
+		if _, err := NewProxyPolicy([]string{entry}); err == nil {
+			t.Errorf("NewProxyPolicy(%q) succeeded, want error", entry)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package server
+
+import (
+	"cursach/internal/config"
+	"cursach/internal/pkg/apperr"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// OriginPolicy решает, каким origin разрешены кросс-доменные запросы и подключения к WebSocket
+type OriginPolicy struct {
+	origins  map[string]bool
+	any      bool // В списке есть "*"
+	loopback bool // Режим разработки: разрешены localhost, 127.0.0.1 и [::1] с любым портом
+}
+
+// NewOriginPolicy создает политику по списку origin из конфигурации CORS
+func NewOriginPolicy(cfg config.CORSConfig) *OriginPolicy {
+	p := &OriginPolicy{origins: make(map[string]bool, len(cfg.AllowedOrigins)), loopback: cfg.DevMode}
+	for _, origin := range cfg.AllowedOrigins {
+		if origin == "*" {
+			p.any = true
+			continue
+		}
+		p.origins[strings.ToLower(strings.TrimSuffix(origin, "/"))] = true
+	}
+	return p
+}
+
+// Allowed проверяет origin по списку (без учета совпадения с адресом сервера)
+func (p *OriginPolicy) Allowed(origin string) bool {
+	if origin == "" {
+		return false
+	}
+	if p.any || p.origins[strings.ToLower(origin)] {
+		return true
+	}
+	return p.loopback && isLoopbackOrigin(origin)
+}
+
+// AllowWebSocket проверяет Origin запроса на подключение к WebSocket
+// Без заголовка Origin подключаются не браузеры: от подделки межсайтового подключения защищает только проверка Origin,
+// поэтому браузерные подключения с чужих страниц отклоняются, а страницы самого сервера разрешены всегда
+func (p *OriginPolicy) AllowWebSocket(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	return p.Allowed(origin)
+}
+
+func isLoopbackOrigin(origin string) bool {
+	u, err := url.Parse(origin)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return false
+	}
+	host := u.Hostname()
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// CORSMiddleware добавляет заголовки CORS для разрешенных origin и отвечает на preflight-запросы
+// Оборачивает весь маршрутизатор, а не подключается через Use: preflight OPTIONS не совпадает
+// с маршрутами, зарегистрированными для конкретных методов, и до middleware mux не дошел бы
+func CORSMiddleware(cfg config.CORSConfig, policy *OriginPolicy) func(http.Handler) http.Handler {
+	allowMethods := strings.Join(cfg.AllowedMethods, ", ")
+	allowHeaders := strings.Join(cfg.AllowedHeaders, ", ")
+	exposeHeaders := strings.Join(cfg.ExposedHeaders, ", ")
+	maxAge := strconv.Itoa(int(cfg.MaxAge.Seconds()))
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			if origin == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			w.Header().Add("Vary", "Origin")
+
+			preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+			if !policy.Allowed(origin) {
+				if preflight {
+					apperr.Write(w, r, apperr.New(apperr.CodeForbidden).WithMessage("Origin not allowed").WithDetail("origin", origin))
+					return
+				}
+				// Запрос выполняется как обычно, но без заголовков CORS браузер не отдаст ответ скрипту
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			h := w.Header()
+			// С учетными данными "*" запрещен спецификацией, поэтому origin всегда возвращается явно
+			h.Set("Access-Control-Allow-Origin", origin)
+			if cfg.AllowCredentials {
+				h.Set("Access-Control-Allow-Credentials", "true")
+			}
+
+			if preflight {
+				h.Add("Vary", "Access-Control-Request-Method")
+				h.Add("Vary", "Access-Control-Request-Headers")
+				h.Set("Access-Control-Allow-Methods", allowMethods)
+				h.Set("Access-Control-Allow-Headers", allowHeaders)
+				if cfg.MaxAge > 0 {
+					h.Set("Access-Control-Max-Age", maxAge)
+				}
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			if exposeHeaders != "" {
+				h.Set("Access-Control-Expose-Headers", exposeHeaders)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package server
+
+import (
+	"cursach/internal/pkg/metrics"
+	"github.com/gorilla/mux"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DeprecationMiddleware помечает ответы устаревшего префикса API заголовками
+// Deprecation (RFC 9745), Sunset (RFC 8594) и Link на тот же путь в новой версии
+// Обращения считаются в метрике, чтобы было видно, кто еще не перешел на новую версию
+func DeprecationMiddleware(prefix, successorPrefix string, deprecatedAt, sunset time.Time) mux.MiddlewareFunc {
+	deprecation := "@" + strconv.FormatInt(deprecatedAt.Unix(), 10)
+	sunsetDate := sunset.UTC().Format(http.TimeFormat)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			successor := successorPrefix + strings.TrimPrefix(r.URL.Path, prefix)
+			w.Header().Set("Deprecation", deprecation)
+			w.Header().Set("Sunset", sunsetDate)
+			w.Header().Add("Link", "<"+successor+`>; rel="successor-version"`)
+			metrics.DeprecatedAPIRequests.Inc(RouteTemplate(r))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
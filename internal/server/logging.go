@@ -0,0 +1,118 @@
+package server
+
+import (
+	"bufio"
+	"crypto/rand"
+	"cursach/internal/pkg/reqctx"
+	"encoding/hex"
+	"errors"
+	"github.com/gorilla/mux"
+	"log/slog"
+	"net"
+	"net/http"
+	"regexp"
+	"time"
+)
+
+// RequestIDHeader - заголовок, в котором передается и возвращается идентификатор запроса
+const RequestIDHeader = "X-Request-ID"
+
+// validRequestID ограничивает идентификатор от клиента, чтобы он не ломал логи
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,128}$`)
+
+// RequestIDMiddleware присваивает запросу идентификатор (или берет корректный из заголовка X-Request-ID),
+// кладет его в контекст и возвращает в заголовке ответа
+func RequestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID.MatchString(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(RequestIDHeader, id)
+
+		ctx := reqctx.WithRequestID(r.Context(), id)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// AccessLogMiddleware пишет в лог по строке на каждый запрос
+// Путь логируется шаблоном маршрута, чтобы идентификаторы чатов и пользователей не попадали в логи
+func AccessLogMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		next.ServeHTTP(rec, r)
+
+		slog.InfoContext(r.Context(), "http request",
+			"method", r.Method,
+			"route", RouteTemplate(r),
+			"status", rec.status,
+			"bytes", rec.bytes,
+			"duration_ms", time.Since(start).Milliseconds(),
+		)
+	})
+}
+
+// RouteTemplate возвращает шаблон маршрута mux (например, /api/chats/{chat_id}) или путь, если маршрут не найден
+func RouteTemplate(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if tmpl, err := route.GetPathTemplate(); err == nil {
+			return tmpl
+		}
+	}
+	return r.URL.Path
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
+
+// statusRecorder запоминает код ответа и размер тела
+// Пробрасывает Flush и Hijack, чтобы не ломать потоковую выгрузку и WebSocket
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	bytes       int
+	wroteHeader bool
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += n
+	return n, err
+}
+
+func (r *statusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (r *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := r.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not support hijacking")
+	}
+	// После hijack ответ пишется напрямую в соединение (101 Switching Protocols для WebSocket)
+	r.status = http.StatusSwitchingProtocols
+	r.wroteHeader = true
+	return h.Hijack()
+}
+
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
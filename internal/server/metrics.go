@@ -0,0 +1,25 @@
+package server
+
+import (
+	"cursach/internal/pkg/metrics"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// MetricsMiddleware считает запросы и их длительность по шаблону маршрута
+// Для WebSocket (101 Switching Protocols) длительность не наблюдается: это время жизни сессии, а не запроса
+func MetricsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		next.ServeHTTP(rec, r)
+
+		route := RouteTemplate(r)
+		metrics.HTTPRequests.Inc(r.Method, route, strconv.Itoa(rec.status))
+		if rec.status != http.StatusSwitchingProtocols {
+			metrics.HTTPRequestDuration.Observe(time.Since(start).Seconds(), r.Method, route)
+		}
+	})
+}
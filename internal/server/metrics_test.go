@@ -0,0 +1,146 @@
+package server
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"cursach/internal/pkg/metrics"
+
+	"github.com/gorilla/mux"
+)
+
+var (
+	metricNameRe = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+	sampleRe     = regexp.MustCompile(`^([a-zA-Z_:][a-zA-Z0-9_:]*)(\{(?:[a-zA-Z_][a-zA-Z0-9_]*="(?:[^"\\]|\\.)*",?)*\})? (\S+)$`)
+)
+
+// scrape запрашивает /metrics, проверяет формат экспозиции Prometheus и возвращает значения рядов
+func scrape(t *testing.T, srv *httptest.Server) map[string]float64 {
+	t.Helper()
+
+	resp, err := http.Get(srv.URL + "/metrics")
+	if err != nil {
+		t.Fatalf("scrape: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("scrape status = %d", resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type = %q, want text exposition format 0.0.4", ct)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read scrape: %v", err)
+	}
+
+	types := map[string]string{}
+	samples := map[string]float64{}
+	sc := bufio.NewScanner(strings.NewReader(string(body)))
+	for sc.Scan() {
+		line := sc.Text()
+		if strings.HasPrefix(line, "# ") {
+			fields := strings.SplitN(line, " ", 4)
+			if len(fields) < 4 || !metricNameRe.MatchString(fields[2]) {
+				t.Errorf("malformed comment line %q", line)
+				continue
+			}
+			switch fields[1] {
+			case "HELP":
+			case "TYPE":
+				switch fields[3] {
+				case "counter", "gauge", "histogram":
+					types[fields[2]] = fields[3]
+				default:
+					t.Errorf("unknown metric type in %q", line)
+				}
+			default:
+				t.Errorf("unknown comment line %q", line)
+			}
+			continue
+		}
+
+		m := sampleRe.FindStringSubmatch(line)
+		if m == nil {
+			t.Errorf("malformed sample line %q", line)
+			continue
+		}
+		family := m[1]
+		if types[family] == "" {
+			for _, suffix := range []string{"_bucket", "_sum", "_count"} {
+				if base := strings.TrimSuffix(family, suffix); base != family && types[base] == "histogram" {
+					family = base
+				}
+			}
+		}
+		if types[family] == "" {
+			t.Errorf("sample %q has no preceding TYPE line", line)
+		}
+		v, err := strconv.ParseFloat(m[3], 64)
+		if err != nil {
+			t.Errorf("sample %q has invalid value: %v", line, err)
+		}
+		samples[m[1]+m[2]] = v
+	}
+	return samples
+}
+
+// TestMetricsScrape проверяет, что запрос через MetricsMiddleware меняет счетчик и гистограмму
+// по шаблону маршрута, а /metrics отдает их в формате Prometheus
+func TestMetricsScrape(t *testing.T) {
+	r := mux.NewRouter()
+	r.Use(MetricsMiddleware)
+	r.Handle("/metrics", metrics.Handler()).Methods("GET")
+	r.HandleFunc("/items/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}).Methods("POST")
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	const (
+		counter  = `messenger_http_requests_total{method="POST",route="/items/{id}",status="201"}`
+		count    = `messenger_http_request_duration_seconds_count{method="POST",route="/items/{id}"}`
+		infinity = `messenger_http_request_duration_seconds_bucket{method="POST",route="/items/{id}",le="+Inf"}`
+	)
+
+	before := scrape(t, srv)
+	for i := 0; i < 2; i++ {
+		resp, err := http.Post(srv.URL+"/items/"+strconv.Itoa(i), "text/plain", nil)
+		if err != nil {
+			t.Fatalf("request: %v", err)
+		}
+		resp.Body.Close()
+	}
+	after := scrape(t, srv)
+
+	if got := after[counter] - before[counter]; got != 2 {
+		t.Errorf("%s grew by %v, want 2", counter, got)
+	}
+	if got := after[count] - before[count]; got != 2 {
+		t.Errorf("%s grew by %v, want 2", count, got)
+	}
+	if after[infinity] != after[count] {
+		t.Errorf("+Inf bucket = %v, want it equal to _count %v", after[infinity], after[count])
+	}
+
+	// Интервалы гистограммы накопительные: значение не убывает с ростом le
+	prev := -1.0
+	for _, le := range metrics.DefBuckets {
+		series := `messenger_http_request_duration_seconds_bucket{method="POST",route="/items/{id}",le="` +
+			strconv.FormatFloat(le, 'g', -1, 64) + `"}`
+		v, ok := after[series]
+		if !ok {
+			t.Fatalf("series %s is missing", series)
+		}
+		if v < prev {
+			t.Errorf("bucket le=%v = %v is less than the previous bucket %v", le, v, prev)
+		}
+		prev = v
+	}
+}
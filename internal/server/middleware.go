@@ -2,10 +2,13 @@ package server
 
 import (
 	"context"
+	"cursach/internal/pkg/apperr"
 	"cursach/internal/pkg/auth"
+	"cursach/internal/pkg/metrics"
 	"cursach/internal/repository"
+	"errors"
 	"github.com/gorilla/mux"
-	"log"
+	"log/slog"
 	"net/http"
 	"strings"
 )
@@ -13,74 +16,85 @@ import (
 type contextKey string
 
 const (
-	UserIDKey   contextKey = "user_id"
-	UserRoleKey contextKey = "user_role"
+	principalKey contextKey = "principal"
 )
 
-func JWTAuthMiddleware(secret string, tokenRepo repository.TokenRepository) mux.MiddlewareFunc {
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			log.Printf("Processing request: %s %s", r.Method, r.URL.Path)
-			// Для WebSocket проверяем token в query-параметре
-			if strings.HasPrefix(r.URL.Path, "/api/ws/") {
-				token := r.URL.Query().Get("token")
-				if token == "" {
-					http.Error(w, "Token required", http.StatusUnauthorized)
-					return
-				}
+var (
+	ErrTokenRevoked = errors.New("token revoked")
+)
 
-				// Проверка отозван ли токен
-				revoked, err := tokenRepo.IsTokenRevoked(r.Context(), token)
-				if err != nil || revoked {
-					http.Error(w, "Token revoked", http.StatusUnauthorized)
-					return
-				}
+// AuthenticateToken проверяет подпись токена доступа и то, что он не отозван
+// Токен считается отозванным, если он отозван при выходе или если отозваны все токены пользователя
+func AuthenticateToken(ctx context.Context, tokenString string, keys *auth.KeySet, tokenRepo repository.TokenRepository) (*auth.Claims, error) {
+	// Проверка отозван ли токен
+	revoked, err := tokenRepo.IsTokenRevoked(ctx, tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, ErrTokenRevoked
+	}
 
-				// Валидация токена
-				claims, err := auth.ValidateToken(token, secret)
-				if err != nil {
-					http.Error(w, "Invalid token", http.StatusUnauthorized)
-					return
-				}
+	// Валидация токена
+	claims, err := auth.ValidateToken(tokenString, keys)
+	if err != nil {
+		return nil, err
+	}
 
-				// Добавляем claims в контекст
-				ctx := context.WithValue(r.Context(), "user_id", claims.UserID)
-				next.ServeHTTP(w, r.WithContext(ctx))
-				return
-			}
+	// Проверка принудительного выхода (блокировка, смена роли администратором)
+	if claims.IssuedAt != nil {
+		revoked, err = tokenRepo.IsUserTokenRevoked(ctx, claims.UserID, claims.IssuedAt.Time)
+		if err != nil {
+			return nil, err
+		}
+		if revoked {
+			return nil, ErrTokenRevoked
+		}
+	}
 
-			// Стандартная проверка для других запросов
+	return claims, nil
+}
+
+// writeTokenError отвечает 401 с причиной отказа в доступе
+func writeTokenError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, ErrTokenRevoked) {
+		metrics.AuthFailures.Inc("token_revoked")
+		apperr.Write(w, r, apperr.Wrap(apperr.CodeTokenRevoked, err))
+		return
+	}
+	metrics.AuthFailures.Inc("invalid_token")
+	apperr.Write(w, r, apperr.Wrap(apperr.CodeInvalidToken, err))
+}
+
+func JWTAuthMiddleware(keys *auth.KeySet, tokenRepo repository.TokenRepository) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Токен принимается только из заголовка: в строке запроса он попал бы в журналы и историю браузера
 			authHeader := r.Header.Get("Authorization")
 			if authHeader == "" {
-				http.Error(w, "Authorization header required", http.StatusUnauthorized)
+				metrics.AuthFailures.Inc("missing_token")
+				apperr.Write(w, r, apperr.New(apperr.CodeUnauthorized).WithMessage("Authorization header required"))
 				return
 			}
 
 			splitToken := strings.Split(authHeader, "Bearer ")
 			if len(splitToken) != 2 {
-				http.Error(w, "Invalid token format", http.StatusUnauthorized)
+				metrics.AuthFailures.Inc("invalid_token")
+				apperr.Write(w, r, apperr.New(apperr.CodeInvalidToken).WithMessage("Invalid token format"))
 				return
 			}
 
 			tokenString := splitToken[1]
 
-			// Проверка отозван ли токен
-			revoked, err := tokenRepo.IsTokenRevoked(r.Context(), tokenString)
-			if err != nil || revoked {
-				http.Error(w, "Token revoked", http.StatusUnauthorized)
-				return
-			}
-
-			// Валидация токена
-			claims, err := auth.ValidateToken(tokenString, secret)
+			claims, err := AuthenticateToken(r.Context(), tokenString, keys, tokenRepo)
 			if err != nil {
-				http.Error(w, "Invalid token", http.StatusUnauthorized)
+				writeTokenError(w, r, err)
 				return
 			}
 
-			log.Printf("Authenticated user: %s", claims.UserID)
-			// Добавляем claims в контекст
-			ctx := context.WithValue(r.Context(), "user_id", claims.UserID)
+			slog.DebugContext(r.Context(), "Authenticated request", "user_id", claims.UserID)
+			// Добавляем пользователя в контекст
+			ctx := WithPrincipal(r.Context(), &Principal{UserID: claims.UserID, Role: claims.Role})
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
@@ -0,0 +1,118 @@
+package server
+
+import (
+	"context"
+	"net/http"
+
+	"cursach/internal/pkg/apperr"
+	"github.com/gorilla/mux"
+)
+
+// Роли пользователей (совпадают с CHECK-ограничением users.role)
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// Permission - право на выполнение группы действий
+type Permission string
+
+const (
+	PermissionManageUsers       Permission = "users:manage"
+	PermissionViewLoginAttempts Permission = "login_attempts:read"
+	PermissionViewAudit         Permission = "audit:read"
+)
+
+// rolePermissions - права, выдаваемые каждой роли
+var rolePermissions = map[string][]Permission{
+	RoleUser: {},
+	RoleAdmin: {
+		PermissionManageUsers,
+		PermissionViewLoginAttempts,
+		PermissionViewAudit,
+	},
+}
+
+// Principal - аутентифицированный пользователь, от имени которого выполняется запрос
+type Principal struct {
+	UserID string
+	Role   string
+}
+
+// Can проверяет, есть ли у пользователя указанное право
+func (p *Principal) Can(perm Permission) bool {
+	for _, granted := range rolePermissions[p.Role] {
+		if granted == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// HasRole проверяет, совпадает ли роль пользователя с одной из указанных
+func (p *Principal) HasRole(roles ...string) bool {
+	for _, role := range roles {
+		if p.Role == role {
+			return true
+		}
+	}
+	return false
+}
+
+// WithPrincipal возвращает контекст с информацией о пользователе
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey, p)
+}
+
+// PrincipalFromContext возвращает пользователя, установленного JWTAuthMiddleware
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey).(*Principal)
+	return p, ok && p != nil && p.UserID != ""
+}
+
+// UserIDFromContext возвращает ID текущего пользователя
+func UserIDFromContext(ctx context.Context) (string, bool) {
+	p, ok := PrincipalFromContext(ctx)
+	if !ok {
+		return "", false
+	}
+	return p.UserID, true
+}
+
+// RequireRole пропускает запрос, только если роль пользователя входит в список
+// Должен подключаться после JWTAuthMiddleware
+func RequireRole(roles ...string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			p, ok := PrincipalFromContext(r.Context())
+			if !ok {
+				apperr.Write(w, r, apperr.New(apperr.CodeUnauthorized))
+				return
+			}
+			if !p.HasRole(roles...) {
+				apperr.Write(w, r, apperr.New(apperr.CodeForbidden))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequirePermission пропускает запрос, только если роль пользователя дает указанное право
+// Должен подключаться после JWTAuthMiddleware
+func RequirePermission(perm Permission) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			p, ok := PrincipalFromContext(r.Context())
+			if !ok {
+				apperr.Write(w, r, apperr.New(apperr.CodeUnauthorized))
+				return
+			}
+			if !p.Can(perm) {
+				apperr.Write(w, r, apperr.New(apperr.CodeForbidden))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
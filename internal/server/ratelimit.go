@@ -0,0 +1,59 @@
+package server
+
+import (
+	"cursach/internal/pkg/apperr"
+	"cursach/internal/pkg/metrics"
+	"cursach/internal/pkg/ratelimit"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// RateLimitMiddleware ограничивает частоту запросов по правилу операции (operation возвращает ее имя)
+// Субъект - пользователь из контекста, поэтому для защищенных маршрутов подключается после JWTAuthMiddleware;
+// анонимные запросы ограничиваются по IP (ClientIPMiddleware), а с неизвестным IP не ограничиваются.
+// limiter == nil - ограничение выключено
+func RateLimitMiddleware(limiter *ratelimit.Limiter, operation func(r *http.Request) string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		if limiter == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			subject := RateLimitSubject(r)
+			if subject == "" {
+				// Общая корзина для всех клиентов с неизвестным адресом позволила бы одному исчерпать ее за всех
+				next.ServeHTTP(w, r)
+				return
+			}
+			name := operation(r)
+			if ok, retryAfter := limiter.Allow(name, subject); !ok {
+				metrics.RateLimited.Inc(name)
+				seconds := RetryAfterSeconds(retryAfter)
+				w.Header().Set("Retry-After", strconv.Itoa(seconds))
+				apperr.Write(w, r, apperr.New(apperr.CodeRateLimited).WithDetail("retry_after", seconds))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RateLimitSubject возвращает ключ субъекта ограничения: user:<id> для аутентифицированных запросов, иначе ip:<адрес>
+// Пустая строка - субъект неизвестен (анонимный запрос без IP клиента)
+func RateLimitSubject(r *http.Request) string {
+	if userID, ok := UserIDFromContext(r.Context()); ok && userID != "" {
+		return "user:" + userID
+	}
+	if ip := ClientIP(r); ip != "" {
+		return "ip:" + ip
+	}
+	return ""
+}
+
+// RetryAfterSeconds округляет ожидание вверх до целых секунд для заголовка Retry-After
+func RetryAfterSeconds(d time.Duration) int {
+	return int(math.Max(1, math.Ceil(d.Seconds())))
+}
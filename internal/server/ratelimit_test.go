@@ -0,0 +1,52 @@
+package server
+
+import (
+	"cursach/internal/pkg/ratelimit"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+// TestRateLimitBehindProxy проверяет, что клиенты за одним доверенным прокси получают отдельные корзины,
+// а анонимные запросы с неизвестным адресом (unix-сокет без доверенного прокси) не делят одну общую
+func TestRateLimitBehindProxy(t *testing.T) {
+	proxies, err := NewProxyPolicy([]string{"10.0.0.0/8"})
+	if err != nil {
+		t.Fatalf("NewProxyPolicy: %v", err)
+	}
+	limiter := ratelimit.New(ratelimit.NewMemoryStore(), ratelimit.Rule{Rate: 0.001, Burst: 1}, nil)
+
+	r := mux.NewRouter()
+	r.Use(ClientIPMiddleware(proxies), RateLimitMiddleware(limiter, func(*http.Request) string { return "login" }))
+	r.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {}).Methods("POST")
+
+	request := func(remoteAddr, forwardedFor string) int {
+		req := httptest.NewRequest(http.MethodPost, "/login", nil)
+		req.RemoteAddr = remoteAddr
+		if forwardedFor != "" {
+			req.Header.Set("X-Forwarded-For", forwardedFor)
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	const proxy = "10.0.0.1:40000"
+	if code := request(proxy, "198.51.100.1"); code != http.StatusOK {
+		t.Fatalf("first client, first request: status %d, want %d", code, http.StatusOK)
+	}
+	if code := request(proxy, "198.51.100.1"); code != http.StatusTooManyRequests {
+		t.Fatalf("first client, second request: status %d, want %d", code, http.StatusTooManyRequests)
+	}
+	if code := request(proxy, "198.51.100.2"); code != http.StatusOK {
+		t.Errorf("second client behind the same proxy: status %d, want %d", code, http.StatusOK)
+	}
+
+	for i := 0; i < 3; i++ {
+		if code := request("@", ""); code != http.StatusOK {
+			t.Errorf("unknown client, request %d: status %d, want %d", i+1, code, http.StatusOK)
+		}
+	}
+}
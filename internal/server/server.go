@@ -2,48 +2,286 @@ package server
 
 import (
 	"context"
-	"log"
+	"crypto/tls"
+	"cursach/internal/config"
+	"cursach/internal/pkg/apperr"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
 )
 
-// Start запускает HTTP сервер с поддержкой graceful shutdown
-// Принимает обработчик запросов и адрес для прослушивания
-func Start(handler http.Handler, address string) {
-	// Создаем HTTP сервер с настройками
-	srv := &http.Server{
-		Addr:         address,
-		Handler:      handler,
-		ReadTimeout:  10 * time.Second,
-		WriteTimeout: 10 * time.Second,
-		IdleTimeout:  60 * time.Second,
+// Server - HTTP сервер приложения: TCP (HTTP или HTTPS), unix-сокет и перенаправление HTTP -> HTTPS
+type Server struct {
+	cfg      config.ServerConfig
+	srv      *http.Server
+	redirect *http.Server
+	certs    *certReloader
+
+	onDrain    []func()
+	onShutdown []func(ctx context.Context) error
+}
+
+// New создает сервер по конфигурации; при включенном TLS сразу загружает сертификат
+func New(handler http.Handler, cfg config.ServerConfig) (*Server, error) {
+	s := &Server{
+		cfg: cfg,
+		srv: &http.Server{
+			Handler:           handler,
+			ReadTimeout:       cfg.ReadTimeout,
+			ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+			WriteTimeout:      cfg.WriteTimeout,
+			IdleTimeout:       cfg.IdleTimeout,
+		},
+	}
+
+	if cfg.TLS.Enabled() {
+		certs, err := newCertReloader(cfg.TLS.CertFile, cfg.TLS.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		s.certs = certs
+		s.srv.TLSConfig = &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: certs.GetCertificate,
+		}
+	}
+
+	if cfg.TLS.RedirectAddr != "" {
+		s.redirect = &http.Server{
+			Addr:              cfg.TLS.RedirectAddr,
+			Handler:           redirectToHTTPS(cfg.Addr),
+			ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+			IdleTimeout:       cfg.IdleTimeout,
+		}
+	}
+
+	return s, nil
+}
+
+// RegisterOnDrain добавляет действие, выполняемое сразу после сигнала завершения, до задержки DrainDelay
+// Используется, чтобы проверка готовности начала отвечать not-ready, пока слушатели еще принимают запросы
+func (s *Server) RegisterOnDrain(fn func()) {
+	s.onDrain = append(s.onDrain, fn)
+}
+
+// RegisterOnShutdown добавляет действие при остановке сервера
+// Действия выполняются параллельно с остановкой HTTP и ограничены тем же ShutdownTimeout
+// Нужны для соединений, которые http.Server не отслеживает (например, WebSocket после hijack)
+func (s *Server) RegisterOnShutdown(fn func(ctx context.Context) error) {
+	s.onShutdown = append(s.onShutdown, fn)
+}
+
+// Run запускает все слушатели и блокируется до сигнала завершения или ошибки слушателя
+// Все адреса занимаются до начала обслуживания: если один из них недоступен, уже открытые закрываются
+func (s *Server) Run() error {
+	ls, err := s.listen()
+	if err != nil {
+		return err
+	}
+
+	errCh := make(chan error, 3)
+	stopWatch := make(chan struct{})
+	defer close(stopWatch)
+
+	if ls.tcp != nil {
+		go func() {
+			if s.certs != nil {
+				slog.Info("Starting HTTPS server", "addr", s.cfg.Addr)
+				errCh <- s.srv.ServeTLS(ls.tcp, "", "")
+				return
+			}
+			slog.Info("Starting server", "addr", s.cfg.Addr)
+			errCh <- s.srv.Serve(ls.tcp)
+		}()
+	}
+
+	if ls.unix != nil {
+		go func() {
+			slog.Info("Starting server on unix socket", "path", s.cfg.UnixSocket)
+			errCh <- s.srv.Serve(ls.unix)
+		}()
+	}
+
+	if ls.redirect != nil {
+		go func() {
+			slog.Info("Redirecting HTTP to HTTPS", "addr", s.redirect.Addr)
+			errCh <- s.redirect.Serve(ls.redirect)
+		}()
+	}
+
+	if s.certs != nil {
+		go s.certs.watch(s.cfg.TLS.ReloadInterval, stopWatch)
 	}
 
 	// Канал для graceful shutdown
 	done := make(chan os.Signal, 1)
 	signal.Notify(done, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(done)
+
+	// Ожидание сигнала завершения или падения одного из слушателей
+	select {
+	case <-done:
+		s.drain(done)
+	case err := <-errCh:
+		if !errors.Is(err, http.ErrServerClosed) {
+			s.shutdown()
+			return fmt.Errorf("server failed: %w", err)
+		}
+	}
 
-	// Запуск сервера в отдельной горутине
-	go func() {
-		log.Printf("Starting server on %s", address)
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Server failed: %v", err)
+	return s.shutdown()
+}
+
+// listeners - открытые слушатели сервера; nil, если слушатель не настроен
+type listeners struct {
+	tcp      net.Listener
+	unix     net.Listener
+	redirect net.Listener
+}
+
+// close закрывает открытые слушатели; unix-сокет при закрытии удаляет свой файл
+func (l *listeners) close() {
+	for _, ln := range []net.Listener{l.tcp, l.unix, l.redirect} {
+		if ln != nil {
+			ln.Close()
 		}
-	}()
+	}
+}
 
-	// Ожидание сигнала завершения в канале
-	<-done
-	log.Println("Shutting down server...")
+// listen занимает все настроенные адреса; при ошибке закрывает уже открытые слушатели
+func (s *Server) listen() (*listeners, error) {
+	ls := &listeners{}
+	var err error
 
-	// Graceful shutdown с таймаутом 5 секунд
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	if s.cfg.Addr != "" {
+		if ls.tcp, err = net.Listen("tcp", s.cfg.Addr); err != nil {
+			return nil, fmt.Errorf("failed to listen on %s: %w", s.cfg.Addr, err)
+		}
+	}
+
+	if s.cfg.UnixSocket != "" {
+		if ls.unix, err = listenUnix(s.cfg.UnixSocket); err != nil {
+			ls.close()
+			return nil, err
+		}
+	}
+
+	if s.redirect != nil {
+		if ls.redirect, err = net.Listen("tcp", s.redirect.Addr); err != nil {
+			ls.close()
+			return nil, fmt.Errorf("failed to listen on %s: %w", s.redirect.Addr, err)
+		}
+	}
+
+	return ls, nil
+}
+
+// drain сообщает о предстоящей остановке и ждет DrainDelay, продолжая обслуживать запросы,
+// чтобы балансировщик успел заметить not-ready и перестать направлять трафик
+// Повторный сигнал прерывает ожидание
+func (s *Server) drain(signals <-chan os.Signal) {
+	for _, fn := range s.onDrain {
+		fn()
+	}
+	if s.cfg.DrainDelay <= 0 {
+		return
+	}
+
+	slog.Info("Draining before shutdown", "delay", s.cfg.DrainDelay)
+	timer := time.NewTimer(s.cfg.DrainDelay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-signals:
+	}
+}
+
+// shutdown останавливает слушатели, дожидаясь активных запросов не дольше ShutdownTimeout
+func (s *Server) shutdown() error {
+	slog.Info("Shutting down server")
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.ShutdownTimeout)
 	defer cancel()
 
-	if err := srv.Shutdown(ctx); err != nil {
-		log.Fatalf("Server shutdown failed: %v", err)
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	run := func(fn func(ctx context.Context) error) {
+		defer wg.Done()
+		if err := fn(ctx); err != nil {
+			mu.Lock()
+			errs = append(errs, err)
+			mu.Unlock()
+		}
+	}
+
+	if s.redirect != nil {
+		wg.Add(1)
+		go run(s.redirect.Shutdown)
+	}
+	wg.Add(1)
+	go run(s.srv.Shutdown)
+	for _, fn := range s.onShutdown {
+		wg.Add(1)
+		go run(fn)
+	}
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("server shutdown failed: %w", err)
+	}
+
+	slog.Info("Server stopped gracefully")
+	return nil
+}
+
+// listenUnix слушает unix-сокет, удаляя оставшийся от прошлого запуска файл
+// Права 0660: доступ есть у владельца и группы (например, у обратного прокси)
+func listenUnix(path string) (net.Listener, error) {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to remove stale unix socket %s: %w", path, err)
 	}
-	log.Println("Server stopped gracefully")
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on unix socket %s: %w", path, err)
+	}
+	if err := os.Chmod(path, 0o660); err != nil {
+		ln.Close()
+		return nil, fmt.Errorf("failed to set unix socket permissions: %w", err)
+	}
+	return ln, nil
+}
+
+// redirectToHTTPS перенаправляет запросы на тот же хост по HTTPS (порт берется из адреса HTTPS сервера)
+func redirectToHTTPS(httpsAddr string) http.Handler {
+	_, port, _ := net.SplitHostPort(httpsAddr)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if host == "" {
+			apperr.Write(w, r, apperr.New(apperr.CodeBadRequest).WithMessage("Host header required"))
+			return
+		}
+		if port != "" && port != "443" {
+			host = net.JoinHostPort(host, port)
+		} else if strings.Contains(host, ":") {
+			host = "[" + host + "]" // IPv6 без порта
+		}
+
+		target := "https://" + host + r.URL.RequestURI()
+		http.Redirect(w, r, target, http.StatusPermanentRedirect)
+	})
 }
@@ -0,0 +1,67 @@
+package server
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"cursach/internal/config"
+)
+
+// freeAddr возвращает свободный локальный TCP адрес
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+	return addr
+}
+
+// TestRunReleasesListenersOnBindFailure проверяет, что при недоступном адресе Run возвращает ошибку,
+// не начав обслуживание, и освобождает уже занятые адреса
+func TestRunReleasesListenersOnBindFailure(t *testing.T) {
+	busy, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer busy.Close()
+
+	tests := []struct {
+		name       string
+		unixSocket string
+		redirect   string
+	}{
+		{name: "unix socket", unixSocket: filepath.Join(t.TempDir(), "missing", "server.sock")},
+		{name: "redirect", unixSocket: filepath.Join(t.TempDir(), "server.sock"), redirect: busy.Addr().String()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.ServerConfig{Addr: freeAddr(t), UnixSocket: tt.unixSocket}
+			cfg.TLS.RedirectAddr = tt.redirect
+			srv, err := New(http.NotFoundHandler(), cfg)
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+
+			if err := srv.Run(); err == nil {
+				t.Fatal("Run succeeded, want bind error")
+			}
+
+			ln, err := net.Listen("tcp", cfg.Addr)
+			if err != nil {
+				t.Fatalf("TCP address is still bound after failure: %v", err)
+			}
+			ln.Close()
+			if _, err := os.Stat(tt.unixSocket); !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("unix socket file left after failure: %v", err)
+			}
+		})
+	}
+}
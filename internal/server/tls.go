@@ -0,0 +1,101 @@
+package server
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// certReloader отдает TLS сертификат и перечитывает его с диска при изменении файлов
+// Позволяет обновлять сертификат (например, certbot) без перезапуска сервера
+type certReloader struct {
+	certFile string
+	keyFile  string
+
+	mu      sync.RWMutex
+	cert    *tls.Certificate
+	modTime time.Time
+}
+
+// newCertReloader загружает сертификат; ошибка загрузки при старте фатальна
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	r := &certReloader{certFile: certFile, keyFile: keyFile}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// GetCertificate используется в tls.Config и возвращает текущий сертификат
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+// watch проверяет файлы сертификата каждые interval до закрытия stop
+// Если новый сертификат не загружается, продолжает использоваться прежний
+func (r *certReloader) watch(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			modTime, err := r.latestModTime()
+			if err != nil {
+				slog.Warn("TLS certificate check failed", "error", err)
+				continue
+			}
+			r.mu.RLock()
+			changed := modTime.After(r.modTime)
+			r.mu.RUnlock()
+			if !changed {
+				continue
+			}
+			if err := r.reload(); err != nil {
+				slog.Error("TLS certificate reload failed, keeping previous certificate", "error", err)
+				continue
+			}
+			slog.Info("TLS certificate reloaded", "cert_file", r.certFile)
+		}
+	}
+}
+
+// reload читает пару сертификат/ключ с диска
+func (r *certReloader) reload() error {
+	modTime, err := r.latestModTime()
+	if err != nil {
+		return err
+	}
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+
+	r.mu.Lock()
+	r.cert = &cert
+	r.modTime = modTime
+	r.mu.Unlock()
+	return nil
+}
+
+// latestModTime возвращает время последнего изменения сертификата или ключа
+func (r *certReloader) latestModTime() (time.Time, error) {
+	var latest time.Time
+	for _, path := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(path)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("failed to stat %s: %w", path, err)
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
@@ -0,0 +1,266 @@
+package admin
+
+import (
+	"context"
+	"cursach/internal/models"
+	"cursach/internal/pkg/metrics"
+	"cursach/internal/repository"
+	"cursach/internal/usecase/audit"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 200
+)
+
+var (
+	ErrUserNotFound     = errors.New("user not found")
+	ErrInvalidRole      = errors.New("invalid user role")
+	ErrCannotTargetSelf = errors.New("administrators cannot apply this action to themselves")
+)
+
+// SessionTerminator закрывает активные подключения пользователя (например, WebSocket)
+type SessionTerminator interface {
+	DisconnectUser(userID string)
+}
+
+// UserPage - страница списка пользователей
+type UserPage struct {
+	Users  []*models.User `json:"users"`
+	Total  int            `json:"total"`
+	Limit  int            `json:"limit"`
+	Offset int            `json:"offset"`
+}
+
+// UserDetails - полная информация о пользователе для администратора
+type UserDetails struct {
+	User  *models.User           `json:"user"`
+	Chats []*models.ChatWithUser `json:"chats"`
+	Stats *models.UserStats      `json:"stats"`
+}
+
+// UserAdministrator реализует действия администратора над пользователями
+// Каждое изменяющее действие записывается в журнал аудита
+type UserAdministrator struct {
+	userRepo  repository.UserRepository
+	chatRepo  repository.ChatRepository
+	tokenRepo repository.TokenRepository
+	sessions  SessionTerminator
+	audit     audit.AuditRecorder
+	now       func() time.Time
+}
+
+// NewUserAdministrator создает новый экземпляр UserAdministrator
+func NewUserAdministrator(
+	userRepo repository.UserRepository,
+	chatRepo repository.ChatRepository,
+	tokenRepo repository.TokenRepository,
+	sessions SessionTerminator,
+	auditRecorder audit.AuditRecorder,
+) *UserAdministrator {
+	return &UserAdministrator{
+		userRepo:  userRepo,
+		chatRepo:  chatRepo,
+		tokenRepo: tokenRepo,
+		sessions:  sessions,
+		audit:     auditRecorder,
+		now:       time.Now,
+	}
+}
+
+// ListUsers возвращает страницу пользователей по фильтру
+func (uc *UserAdministrator) ListUsers(ctx context.Context, filter models.UserFilter) (*UserPage, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultPageSize
+	}
+	if filter.Limit > MaxPageSize {
+		filter.Limit = MaxPageSize
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+
+	users, total, err := uc.userRepo.ListUsers(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if users == nil {
+		users = []*models.User{}
+	}
+
+	return &UserPage{
+		Users:  users,
+		Total:  total,
+		Limit:  filter.Limit,
+		Offset: filter.Offset,
+	}, nil
+}
+
+// GetUserDetails возвращает пользователя, его чаты и статистику активности
+func (uc *UserAdministrator) GetUserDetails(ctx context.Context, userID string) (*UserDetails, error) {
+	user, err := uc.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	// Личные чаты возвращаются отдельным полем
+	user.Chats = nil
+
+	chats, err := uc.chatRepo.GetUserChats(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if chats == nil {
+		chats = []*models.ChatWithUser{}
+	}
+
+	stats, err := uc.userRepo.GetUserStats(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &UserDetails{User: user, Chats: chats, Stats: stats}, nil
+}
+
+// ChangeRole изменяет роль пользователя
+// Все токены пользователя отзываются, чтобы новая роль применилась при следующем входе
+func (uc *UserAdministrator) ChangeRole(ctx context.Context, actorID, userID, role string) error {
+	if role != "user" && role != "admin" {
+		return ErrInvalidRole
+	}
+	if actorID == userID {
+		return ErrCannotTargetSelf
+	}
+
+	user, err := uc.getUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := uc.userRepo.UpdateRole(ctx, userID, role); err != nil {
+		return err
+	}
+	if err := uc.revokeUserTokens(ctx, userID); err != nil {
+		return err
+	}
+
+	uc.record(ctx, actorID, audit.ActionAdminRoleChanged, userID, map[string]interface{}{
+		"old_role": user.Role,
+		"new_role": role,
+	})
+	return nil
+}
+
+// Ban блокирует пользователя: запрещает вход, отзывает токены и закрывает активные соединения
+func (uc *UserAdministrator) Ban(ctx context.Context, actorID, userID, reason string) error {
+	if actorID == userID {
+		return ErrCannotTargetSelf
+	}
+	if _, err := uc.getUser(ctx, userID); err != nil {
+		return err
+	}
+
+	if err := uc.userRepo.SetBanned(ctx, userID, true); err != nil {
+		return err
+	}
+	if err := uc.revokeUserTokens(ctx, userID); err != nil {
+		return err
+	}
+	uc.sessions.DisconnectUser(userID)
+
+	uc.record(ctx, actorID, audit.ActionAdminUserBanned, userID, map[string]interface{}{
+		"reason": reason,
+	})
+	return nil
+}
+
+// Unban снимает блокировку пользователя
+func (uc *UserAdministrator) Unban(ctx context.Context, actorID, userID string) error {
+	if _, err := uc.getUser(ctx, userID); err != nil {
+		return err
+	}
+
+	if err := uc.userRepo.SetBanned(ctx, userID, false); err != nil {
+		return err
+	}
+
+	uc.record(ctx, actorID, audit.ActionAdminUserUnban, userID, nil)
+	return nil
+}
+
+// ForceLogout отзывает все токены пользователя и закрывает активные соединения
+func (uc *UserAdministrator) ForceLogout(ctx context.Context, actorID, userID string) error {
+	if _, err := uc.getUser(ctx, userID); err != nil {
+		return err
+	}
+
+	if err := uc.revokeUserTokens(ctx, userID); err != nil {
+		return err
+	}
+	uc.sessions.DisconnectUser(userID)
+
+	uc.record(ctx, actorID, audit.ActionAdminLogout, userID, nil)
+	return nil
+}
+
+// DeleteUser удаляет аккаунт пользователя, отзывает его токены и закрывает активные соединения
+// Токены отзываются до удаления: если отзыв не удался, аккаунт не удаляется
+func (uc *UserAdministrator) DeleteUser(ctx context.Context, actorID, userID string) error {
+	if actorID == userID {
+		return ErrCannotTargetSelf
+	}
+	user, err := uc.getUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := uc.revokeUserTokens(ctx, userID); err != nil {
+		return err
+	}
+	if err := uc.userRepo.DeleteUser(ctx, userID); err != nil {
+		return err
+	}
+	uc.sessions.DisconnectUser(userID)
+
+	uc.record(ctx, actorID, audit.ActionAdminUserDeleted, userID, map[string]interface{}{
+		"login": user.Login,
+	})
+	return nil
+}
+
+func (uc *UserAdministrator) getUser(ctx context.Context, userID string) (*models.User, error) {
+	user, err := uc.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	return user, nil
+}
+
+// revokeUserTokens отзывает все выданные пользователю токены
+func (uc *UserAdministrator) revokeUserTokens(ctx context.Context, userID string) error {
+	if err := uc.tokenRepo.RevokeUserTokens(ctx, userID, uc.now()); err != nil {
+		return err
+	}
+	metrics.TokenRevocations.Inc("user")
+	return nil
+}
+
+// record записывает действие в журнал аудита
+// Ошибка записи журнала не отменяет уже выполненное действие, поэтому только логируется
+func (uc *UserAdministrator) record(ctx context.Context, actorID, action, userID string, details map[string]interface{}) {
+	audit.TryRecord(ctx, uc.audit, audit.Event{
+		ActorID:    actorID,
+		Action:     action,
+		TargetType: audit.TargetUser,
+		TargetID:   userID,
+		Details:    details,
+	})
+}
@@ -0,0 +1,111 @@
+package admin
+
+import (
+	"context"
+	"cursach/internal/models"
+	"cursach/internal/repository"
+	"cursach/internal/usecase/audit"
+	"errors"
+	"testing"
+	"time"
+)
+
+// fakeUserRepository хранит пользователей в памяти; остальные методы интерфейса не используются
+type fakeUserRepository struct {
+	repository.UserRepository
+	users map[string]*models.User
+}
+
+func (r *fakeUserRepository) GetUserByID(_ context.Context, userID string) (*models.User, error) {
+	return r.users[userID], nil
+}
+
+func (r *fakeUserRepository) DeleteUser(_ context.Context, userID string) error {
+	delete(r.users, userID)
+	return nil
+}
+
+// fakeTokenRepository повторяет семантику отзыва токенов Postgres-реализации
+type fakeTokenRepository struct {
+	repository.TokenRepository
+	revokedBefore map[string]time.Time
+	err           error
+}
+
+func (r *fakeTokenRepository) RevokeUserTokens(_ context.Context, userID string, before time.Time) error {
+	if r.err != nil {
+		return r.err
+	}
+	r.revokedBefore[userID] = before.Truncate(time.Second)
+	return nil
+}
+
+func (r *fakeTokenRepository) IsUserTokenRevoked(_ context.Context, userID string, issuedAt time.Time) (bool, error) {
+	before, ok := r.revokedBefore[userID]
+	return ok && !issuedAt.After(before), nil
+}
+
+type fakeSessions struct{ disconnected []string }
+
+func (s *fakeSessions) DisconnectUser(userID string) { s.disconnected = append(s.disconnected, userID) }
+
+type fakeAudit struct{ events []audit.Event }
+
+func (a *fakeAudit) Record(_ context.Context, event audit.Event) error {
+	a.events = append(a.events, event)
+	return nil
+}
+
+func newTestAdministrator(tokens *fakeTokenRepository, now time.Time) (*UserAdministrator, *fakeUserRepository, *fakeSessions, *fakeAudit) {
+	users := &fakeUserRepository{users: map[string]*models.User{
+		"admin": {ID: "admin", Login: "admin", Role: "admin"},
+		"u1":    {ID: "u1", Login: "alice", Role: "user"},
+	}}
+	sessions, recorder := &fakeSessions{}, &fakeAudit{}
+	uc := NewUserAdministrator(users, nil, tokens, sessions, recorder)
+	uc.now = func() time.Time { return now }
+	return uc, users, sessions, recorder
+}
+
+func TestDeleteUserRevokesTokens(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 700_000_000, time.UTC)
+	tokens := &fakeTokenRepository{revokedBefore: map[string]time.Time{}}
+	uc, users, sessions, recorder := newTestAdministrator(tokens, now)
+
+	if err := uc.DeleteUser(context.Background(), "admin", "u1"); err != nil {
+		t.Fatalf("DeleteUser: %v", err)
+	}
+	if _, ok := users.users["u1"]; ok {
+		t.Error("user was not deleted")
+	}
+
+	// iat токена - целые секунды; токен, выданный в секунду удаления, тоже отозван
+	for _, issuedAt := range []time.Time{now.Add(-time.Hour), now.Truncate(time.Second)} {
+		revoked, _ := tokens.IsUserTokenRevoked(context.Background(), "u1", issuedAt)
+		if !revoked {
+			t.Errorf("token issued at %v is still valid after deletion", issuedAt)
+		}
+	}
+	if len(sessions.disconnected) != 1 || sessions.disconnected[0] != "u1" {
+		t.Errorf("disconnected = %v, want [u1]", sessions.disconnected)
+	}
+	if len(recorder.events) != 1 || recorder.events[0].Action != audit.ActionAdminUserDeleted {
+		t.Errorf("audit events = %+v, want one %s", recorder.events, audit.ActionAdminUserDeleted)
+	}
+}
+
+func TestDeleteUserKeepsAccountWhenRevocationFails(t *testing.T) {
+	errDB := errors.New("db is down")
+	tokens := &fakeTokenRepository{revokedBefore: map[string]time.Time{}, err: errDB}
+	uc, users, sessions, _ := newTestAdministrator(tokens, time.Now())
+
+	if err := uc.DeleteUser(context.Background(), "admin", "u1"); !errors.Is(err, errDB) {
+		t.Fatalf("DeleteUser() = %v, want %v", err, errDB)
+	}
+	if _, ok := users.users["u1"]; !ok {
+		t.Error("user was deleted although tokens were not revoked")
+	}
+	if len(sessions.disconnected) != 0 {
+		t.Errorf("disconnected = %v, want none", sessions.disconnected)
+	}
+}
@@ -0,0 +1,46 @@
+package audit
+
+import (
+	"context"
+	"cursach/internal/models"
+	"cursach/internal/repository"
+)
+
+const (
+	DefaultPageSize = 100
+	MaxPageSize     = 1000
+)
+
+// EventReader предоставляет администратору доступ к журналу аудита
+type EventReader struct {
+	auditRepo repository.AuditRepository
+}
+
+// NewEventReader создает новый экземпляр EventReader
+func NewEventReader(auditRepo repository.AuditRepository) *EventReader {
+	return &EventReader{auditRepo: auditRepo}
+}
+
+// List возвращает страницу событий по фильтру, самые новые первыми
+func (uc *EventReader) List(ctx context.Context, filter models.AuditFilter) ([]*models.AuditEvent, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultPageSize
+	}
+	if filter.Limit > MaxPageSize {
+		filter.Limit = MaxPageSize
+	}
+
+	events, err := uc.auditRepo.Query(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if events == nil {
+		events = []*models.AuditEvent{}
+	}
+	return events, nil
+}
+
+// Export передает в fn все события по фильтру в хронологическом порядке
+func (uc *EventReader) Export(ctx context.Context, filter models.AuditFilter, fn func(*models.AuditEvent) error) error {
+	return uc.auditRepo.Each(ctx, filter, fn)
+}
@@ -0,0 +1,98 @@
+package audit
+
+import (
+	"context"
+	"cursach/internal/models"
+	"cursach/internal/pkg/reqctx"
+	"cursach/internal/repository"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// Типы объектов действий
+const (
+	TargetUser = "user"
+	TargetChat = "chat"
+)
+
+// Действия пользователей
+const (
+	ActionLogin           = "user.login"             // Вход завершен, токен доступа выдан
+	ActionLoginMFAPending = "user.login.mfa_pending" // Пароль верен, вход ожидает кода 2FA
+	ActionLoginFailed     = "user.login_failed"
+	ActionMFAFailed       = "user.mfa_failed"
+	ActionLogout          = "user.logout"
+	ActionLoginChanged    = "user.login_changed"
+	ActionUserDeleted     = "user.deleted"
+	ActionChatDeleted     = "chat.deleted"
+)
+
+// Действия администратора
+const (
+	ActionAdminRoleChanged = "admin.user.role_changed"
+	ActionAdminUserBanned  = "admin.user.banned"
+	ActionAdminUserUnban   = "admin.user.unbanned"
+	ActionAdminLogout      = "admin.user.logout_forced"
+	ActionAdminUserDeleted = "admin.user.deleted"
+)
+
+// AuditRecorder записывает события в журнал аудита
+// Используется usecase-ами; реализация может писать в БД или в другое хранилище
+type AuditRecorder interface {
+	Record(ctx context.Context, event Event) error
+}
+
+// Event описывает действие, которое нужно записать в журнал
+type Event struct {
+	ActorID    string
+	Action     string
+	TargetType string
+	TargetID   string
+	Details    map[string]interface{}
+}
+
+// Recorder реализует AuditRecorder поверх AuditRepository
+type Recorder struct {
+	auditRepo repository.AuditRepository
+	now       func() time.Time
+}
+
+// NewRecorder создает новый экземпляр Recorder
+func NewRecorder(auditRepo repository.AuditRepository) *Recorder {
+	return &Recorder{
+		auditRepo: auditRepo,
+		now:       time.Now,
+	}
+}
+
+// Record сохраняет событие; IP клиента берется из контекста запроса
+func (r *Recorder) Record(ctx context.Context, event Event) error {
+	var details json.RawMessage
+	if len(event.Details) > 0 {
+		raw, err := json.Marshal(event.Details)
+		if err != nil {
+			return fmt.Errorf("failed to encode audit details: %w", err)
+		}
+		details = raw
+	}
+
+	return r.auditRepo.Append(ctx, &models.AuditEvent{
+		ActorID:    event.ActorID,
+		Action:     event.Action,
+		TargetType: event.TargetType,
+		TargetID:   event.TargetID,
+		IP:         reqctx.ClientIP(ctx),
+		Details:    details,
+		CreatedAt:  r.now(),
+	})
+}
+
+// TryRecord записывает событие и логирует ошибку записи
+// Используется, когда сбой журнала не должен отменять уже выполненное действие
+func TryRecord(ctx context.Context, recorder AuditRecorder, event Event) {
+	if err := recorder.Record(ctx, event); err != nil {
+		slog.ErrorContext(ctx, "Failed to record audit event", "action", event.Action, "error", err)
+	}
+}
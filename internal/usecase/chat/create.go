@@ -2,6 +2,7 @@ package chat
 
 import (
 	"context"
+	"cursach/internal/pkg/tracing"
 	"cursach/internal/repository"
 	"errors"
 	"fmt"
@@ -12,6 +13,7 @@ var (
 	ErrUserNotFound    = errors.New("user not found")
 	ErrUserCheckFailed = errors.New("failed to verify user existence")
 	ErrChatCreation    = errors.New("failed to create chat")
+	ErrSelfChat        = errors.New("cannot create chat with yourself")
 )
 
 // ChatCreator определяет интерфейс для создания чатов
@@ -33,7 +35,10 @@ func NewChatCreator(
 
 // Execute создает новый чат с указанными пользователями
 // Возвращает ID созданного чата или ошибку
-func (uc *ChatCreator) Execute(ctx context.Context, currentUserID, targetUserLogin string) (string, error) {
+func (uc *ChatCreator) Execute(ctx context.Context, currentUserID, targetUserLogin string) (_ string, err error) {
+	ctx, span := tracing.Start(ctx, "chat.ChatCreator.Execute")
+	defer func() { tracing.End(span, err) }()
+
 	targetUser, err := uc.userRepo.GetUserByLogin(ctx, targetUserLogin)
 	if err != nil {
 		return "", fmt.Errorf("failed to get user by login: %w", err)
@@ -44,7 +49,7 @@ func (uc *ChatCreator) Execute(ctx context.Context, currentUserID, targetUserLog
 
 	// Проверяем, что пользователи разные
 	if currentUserID == targetUser.ID {
-		return "", errors.New("cannot create chat with yourself")
+		return "", ErrSelfChat
 	}
 
 	// Создаем чат с двумя участниками (без проверки существующих чатов)
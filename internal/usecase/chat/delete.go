@@ -2,10 +2,11 @@ package chat
 
 import (
 	"context"
+	"cursach/internal/pkg/tracing"
 	"cursach/internal/repository"
+	"cursach/internal/usecase/audit"
 	"errors"
 	"fmt"
-	"log"
 )
 
 var (
@@ -17,18 +18,21 @@ var (
 // ChatDeleter определяет интерфейс для удаления чатов
 type ChatDeleter struct {
 	chatRepo repository.ChatRepository
+	audit    audit.AuditRecorder
 }
 
 // NewChatDeleter создает новый экземпляр ChatDeleter
-func NewChatDeleter(chatRepo repository.ChatRepository) *ChatDeleter {
-	return &ChatDeleter{chatRepo: chatRepo}
+func NewChatDeleter(chatRepo repository.ChatRepository, auditRecorder audit.AuditRecorder) *ChatDeleter {
+	return &ChatDeleter{chatRepo: chatRepo, audit: auditRecorder}
 }
 
 // Execute удаляет чат, если пользователь является его участником
-func (uc *ChatDeleter) Execute(ctx context.Context, chatID, userID string) error {
+func (uc *ChatDeleter) Execute(ctx context.Context, chatID, userID string) (err error) {
+	ctx, span := tracing.Start(ctx, "chat.ChatDeleter.Execute")
+	defer func() { tracing.End(span, err) }()
+
 	// Проверяем, что пользователь является участником чата
 	isMember, err := uc.chatRepo.IsUserInChat(ctx, chatID, userID)
-	log.Println(isMember, err)
 	if err != nil {
 		return fmt.Errorf("failed to check user membership: %w", err)
 	}
@@ -41,5 +45,11 @@ func (uc *ChatDeleter) Execute(ctx context.Context, chatID, userID string) error
 		return fmt.Errorf("%w: %v", ErrChatDeletion, err)
 	}
 
+	audit.TryRecord(ctx, uc.audit, audit.Event{
+		ActorID:    userID,
+		Action:     audit.ActionChatDeleted,
+		TargetType: audit.TargetChat,
+		TargetID:   chatID,
+	})
 	return nil
 }
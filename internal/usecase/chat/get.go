@@ -3,6 +3,7 @@ package chat
 import (
 	"context"
 	"cursach/internal/models"
+	"cursach/internal/pkg/tracing"
 	"cursach/internal/repository"
 )
 
@@ -14,7 +15,10 @@ func NewChatLister(chatRepo repository.ChatRepository) *ChatLister {
 	return &ChatLister{chatRepo: chatRepo}
 }
 
-func (uc *ChatLister) Execute(ctx context.Context, userID string) ([]*models.ChatWithUser, error) {
+func (uc *ChatLister) Execute(ctx context.Context, userID string) (_ []*models.ChatWithUser, err error) {
+	ctx, span := tracing.Start(ctx, "chat.ChatLister.Execute")
+	defer func() { tracing.End(span, err) }()
+
 	chats, err := uc.chatRepo.GetUserChats(ctx, userID)
 	if err != nil {
 		return nil, err
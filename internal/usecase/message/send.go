@@ -3,8 +3,11 @@ package message
 import (
 	"context"
 	"cursach/internal/models"
+	"cursach/internal/pkg/metrics"
+	"cursach/internal/pkg/tracing"
 	"cursach/internal/repository"
 	"errors"
+	"fmt"
 )
 
 var (
@@ -24,13 +27,19 @@ func NewSender(chatRepo repository.ChatRepository, messageRepo repository.Messag
 	}
 }
 
-func (uc *Sender) Execute(ctx context.Context, chatID, userID, text string) (*models.Message, error) {
+func (uc *Sender) Execute(ctx context.Context, chatID, userID, text string) (_ *models.Message, err error) {
+	ctx, span := tracing.Start(ctx, "message.Sender.Execute")
+	defer func() { tracing.End(span, err) }()
+
 	if text == "" {
 		return nil, ErrEmptyMessage
 	}
 
 	isMember, err := uc.chatRepo.IsUserInChat(ctx, chatID, userID)
-	if err != nil || !isMember {
+	if err != nil {
+		return nil, fmt.Errorf("failed to check chat membership: %w", err)
+	}
+	if !isMember {
 		return nil, ErrUserNotInChat
 	}
 
@@ -48,6 +57,7 @@ func (uc *Sender) Execute(ctx context.Context, chatID, userID, text string) (*mo
 
 	// Устанавливаем ID сообщения
 	msg.ID = messageID
+	metrics.MessagesSent.Inc()
 
 	// Возвращаем полную модель сообщения
 	return msg, nil
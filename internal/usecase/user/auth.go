@@ -4,38 +4,85 @@ import (
 	"context"
 	"cursach/internal/models"
 	"cursach/internal/pkg/auth"
+	"cursach/internal/pkg/metrics"
 	"cursach/internal/repository"
+	"cursach/internal/usecase/audit"
+	"fmt"
 )
 
 // Authenticator отвечает за аутентификацию пользователей
 // Проверяет соответствие предоставленных учетных данных данным в системе
 type Authenticator struct {
 	userRepo repository.UserRepository
+	mfaRepo  repository.MFARepository
 	salt     string
+	audit    audit.AuditRecorder
 }
 
 // NewAuthenticator создает новый экземпляр аутентификатора
 // Возвращает инициализированный объект Authenticator
-func NewAuthenticator(userRepo repository.UserRepository, salt string) *Authenticator {
+func NewAuthenticator(userRepo repository.UserRepository, mfaRepo repository.MFARepository, salt string, auditRecorder audit.AuditRecorder) *Authenticator {
 	return &Authenticator{
 		userRepo: userRepo,
+		mfaRepo:  mfaRepo,
 		salt:     salt,
+		audit:    auditRecorder,
 	}
 }
 
 // Authenticate выполняет аутентификацию пользователя по логину и паролю
-func (uc *Authenticator) Authenticate(ctx context.Context, login, password string) (*models.User, error) {
-	user, err := uc.userRepo.GetUserByLogin(ctx, login)
+// mfaRequired - у пользователя включена 2FA, и вход завершается кодом (MFAManager.CompleteLogin);
+// до этого в журнал аудита пишется только user.login.mfa_pending
+func (uc *Authenticator) Authenticate(ctx context.Context, login, password string) (user *models.User, mfaRequired bool, err error) {
+	user, err = uc.userRepo.GetUserByLogin(ctx, login)
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 	if user == nil {
-		return nil, ErrInvalidCredentials
+		uc.recordFailure(ctx, "", login, "unknown_login")
+		return nil, false, ErrInvalidCredentials
 	}
 
 	if !auth.VerifyPassword(password, uc.salt, user.Password) {
-		return nil, ErrInvalidCredentials
+		uc.recordFailure(ctx, user.ID, login, "invalid_password")
+		return nil, false, ErrInvalidCredentials
 	}
 
-	return user, nil
+	// Заблокированный администратором пользователь не может войти даже с верным паролем
+	if user.BannedAt.Valid {
+		uc.recordFailure(ctx, user.ID, login, "banned")
+		return nil, false, ErrUserBanned
+	}
+
+	mfa, err := uc.mfaRepo.GetMFA(ctx, user.ID)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to check MFA status: %w", err)
+	}
+	mfaRequired = mfa != nil && mfa.Enabled
+
+	action := audit.ActionLogin
+	if mfaRequired {
+		action = audit.ActionLoginMFAPending
+	}
+	audit.TryRecord(ctx, uc.audit, audit.Event{
+		ActorID:    user.ID,
+		Action:     action,
+		TargetType: audit.TargetUser,
+		TargetID:   user.ID,
+	})
+	return user, mfaRequired, nil
+}
+
+// recordFailure записывает неудачную попытку входа в журнал аудита и метрики
+func (uc *Authenticator) recordFailure(ctx context.Context, userID, login, reason string) {
+	metrics.AuthFailures.Inc(reason)
+	audit.TryRecord(ctx, uc.audit, audit.Event{
+		Action:     audit.ActionLoginFailed,
+		TargetType: audit.TargetUser,
+		TargetID:   userID,
+		Details: map[string]interface{}{
+			"login":  login,
+			"reason": reason,
+		},
+	})
 }
@@ -0,0 +1,104 @@
+package user
+
+import (
+	"context"
+	"cursach/internal/models"
+	"cursach/internal/pkg/auth"
+	"cursach/internal/repository"
+	"cursach/internal/usecase/audit"
+	"testing"
+	"time"
+)
+
+// fakeUserRepository хранит одного пользователя; остальные методы интерфейса не используются
+type fakeUserRepository struct {
+	repository.UserRepository
+	user *models.User
+}
+
+func (r *fakeUserRepository) GetUserByLogin(_ context.Context, login string) (*models.User, error) {
+	if r.user.Login != login {
+		return nil, nil
+	}
+	return r.user, nil
+}
+
+func (r *fakeUserRepository) GetUserByID(_ context.Context, userID string) (*models.User, error) {
+	if r.user.ID != userID {
+		return nil, nil
+	}
+	return r.user, nil
+}
+
+type fakeAuditRecorder struct{ actions []string }
+
+func (a *fakeAuditRecorder) Record(_ context.Context, event audit.Event) error {
+	a.actions = append(a.actions, event.Action)
+	return nil
+}
+
+func newFakeUserRepository(t *testing.T) *fakeUserRepository {
+	t.Helper()
+	hash, err := auth.HashPassword("secret", "salt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &fakeUserRepository{user: &models.User{ID: "u1", Login: "alice", Password: hash}}
+}
+
+func TestAuthenticateAuditWithoutMFA(t *testing.T) {
+	recorder := &fakeAuditRecorder{}
+	uc := NewAuthenticator(newFakeUserRepository(t), newFakeMFARepository(), "salt", recorder)
+
+	user, mfaRequired, err := uc.Authenticate(context.Background(), "alice", "secret")
+	if err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	if user.ID != "u1" || mfaRequired {
+		t.Errorf("Authenticate() = %q, mfaRequired %v; want u1 without MFA", user.ID, mfaRequired)
+	}
+	if len(recorder.actions) != 1 || recorder.actions[0] != audit.ActionLogin {
+		t.Errorf("audit actions = %v, want [%s]", recorder.actions, audit.ActionLogin)
+	}
+}
+
+// TestAuthenticateAuditWithMFA проверяет, что при включенной 2FA вход записывается только после проверки кода
+func TestAuthenticateAuditWithMFA(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)}
+	_, mfaRepo := enabledMFA(t, clock)
+	users := newFakeUserRepository(t)
+	recorder := &fakeAuditRecorder{}
+	authUC := NewAuthenticator(users, mfaRepo, "salt", recorder)
+	mfaUC := NewMFAManager(mfaRepo, users, recorder, "salt", "test").WithClock(clock.Now)
+	ctx := context.Background()
+
+	_, mfaRequired, err := authUC.Authenticate(ctx, "alice", "secret")
+	if err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	if !mfaRequired {
+		t.Fatal("mfaRequired = false, want true")
+	}
+	if len(recorder.actions) != 1 || recorder.actions[0] != audit.ActionLoginMFAPending {
+		t.Fatalf("after password: audit actions = %v, want [%s]", recorder.actions, audit.ActionLoginMFAPending)
+	}
+
+	if _, err := mfaUC.CompleteLogin(ctx, "u1", "000000"); err == nil {
+		t.Fatal("CompleteLogin accepted a wrong code")
+	}
+	clock.Advance(auth.TOTPPeriod)
+	if _, err := mfaUC.CompleteLogin(ctx, "u1", totpCode(t, clock.Now())); err != nil {
+		t.Fatalf("CompleteLogin: %v", err)
+	}
+
+	want := []string{audit.ActionLoginMFAPending, audit.ActionMFAFailed, audit.ActionLogin}
+	if len(recorder.actions) != len(want) {
+		t.Fatalf("audit actions = %v, want %v", recorder.actions, want)
+	}
+	for i := range want {
+		if recorder.actions[i] != want[i] {
+			t.Errorf("audit actions = %v, want %v", recorder.actions, want)
+			break
+		}
+	}
+}
@@ -12,6 +12,7 @@ var (
 	ErrEmptyCredentials   = errors.New("login and password cannot be empty")
 	ErrInvalidCredentials = errors.New("invalid login or password")
 	ErrInvalidRole        = errors.New("invalid user role")
+	ErrUserBanned         = errors.New("user is banned")
 )
 
 // UserManager определяет интерфейс для управления пользователями
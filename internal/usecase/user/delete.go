@@ -2,9 +2,13 @@ package user
 
 import (
 	"context"
+	"cursach/internal/pkg/metrics"
+	"cursach/internal/pkg/tracing"
 	"cursach/internal/repository"
+	"cursach/internal/usecase/audit"
 	"errors"
 	"fmt"
+	"time"
 )
 
 var (
@@ -14,16 +18,21 @@ var (
 
 // UserDeleter определяет интерфейс для удаления пользователей
 type UserDeleter struct {
-	userRepo repository.UserRepository
+	userRepo  repository.UserRepository
+	tokenRepo repository.TokenRepository
+	audit     audit.AuditRecorder
 }
 
 // NewUserDeleter создает новый экземпляр UserDeleter
-func NewUserDeleter(userRepo repository.UserRepository) *UserDeleter {
-	return &UserDeleter{userRepo: userRepo}
+func NewUserDeleter(userRepo repository.UserRepository, tokenRepo repository.TokenRepository, auditRecorder audit.AuditRecorder) *UserDeleter {
+	return &UserDeleter{userRepo: userRepo, tokenRepo: tokenRepo, audit: auditRecorder}
 }
 
-// Execute удаляет пользователя по ID (без проверки прав)
-func (uc *UserDeleter) Execute(ctx context.Context, userID string) error {
+// Execute удаляет пользователя по ID (без проверки прав) и отзывает его токены
+func (uc *UserDeleter) Execute(ctx context.Context, userID string) (err error) {
+	ctx, span := tracing.Start(ctx, "user.UserDeleter.Execute")
+	defer func() { tracing.End(span, err) }()
+
 	// Проверка существования пользователя
 	exists, err := uc.userRepo.UserExists(ctx, userID)
 	if err != nil {
@@ -33,10 +42,22 @@ func (uc *UserDeleter) Execute(ctx context.Context, userID string) error {
 		return ErrUserNotFound
 	}
 
+	// Токены удаленного аккаунта не должны оставаться действительными до истечения срока
+	if err := uc.tokenRepo.RevokeUserTokens(ctx, userID, time.Now()); err != nil {
+		return fmt.Errorf("failed to revoke user tokens: %w", err)
+	}
+	metrics.TokenRevocations.Inc("user")
+
 	// Удаление пользователя
 	if err := uc.userRepo.DeleteUser(ctx, userID); err != nil {
 		return fmt.Errorf("failed to delete user: %w", err)
 	}
 
+	audit.TryRecord(ctx, uc.audit, audit.Event{
+		ActorID:    userID,
+		Action:     audit.ActionUserDeleted,
+		TargetType: audit.TargetUser,
+		TargetID:   userID,
+	})
 	return nil
 }
@@ -0,0 +1,215 @@
+package user
+
+import (
+	"context"
+	"cursach/internal/config"
+	"cursach/internal/models"
+	"cursach/internal/repository"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrTooManyAttempts = errors.New("too many failed login attempts")
+)
+
+// LockedError возвращается, когда вход временно заблокирован из-за перебора паролей
+// Сравнивается с ErrTooManyAttempts через errors.Is
+type LockedError struct {
+	RetryAfter time.Duration // Через сколько можно повторить попытку
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("%s, retry after %s", ErrTooManyAttempts, e.RetryAfter)
+}
+
+func (e *LockedError) Unwrap() error {
+	return ErrTooManyAttempts
+}
+
+// LoginGuard отслеживает неудачные попытки входа по логину и по IP клиента,
+// а также неудачные проверки кода 2FA уже вошедшего пользователя (по его ID)
+// После превышения лимита блокирует вход с экспоненциально растущей длительностью
+type LoginGuard struct {
+	attemptRepo repository.LoginAttemptRepository
+	cfg         config.LoginLimitConfig
+	now         func() time.Time
+}
+
+// NewLoginGuard создает новый экземпляр LoginGuard
+func NewLoginGuard(attemptRepo repository.LoginAttemptRepository, cfg config.LoginLimitConfig) *LoginGuard {
+	return &LoginGuard{
+		attemptRepo: attemptRepo,
+		cfg:         cfg,
+		now:         time.Now,
+	}
+}
+
+// WithClock заменяет источник текущего времени, по которому считаются окно неудач и блокировки (для тестов)
+func (g *LoginGuard) WithClock(now func() time.Time) *LoginGuard {
+	g.now = now
+	return g
+}
+
+// Check проверяет, не заблокирован ли вход для логина или IP
+// Возвращает *LockedError, если попытку нужно отклонить
+func (g *LoginGuard) Check(ctx context.Context, login, ip string) error {
+	return g.check(ctx, g.keys(login, ip))
+}
+
+// CheckUser проверяет, не заблокирована ли проверка кода 2FA для пользователя (например, при выключении 2FA)
+// Возвращает *LockedError, если попытку нужно отклонить
+func (g *LoginGuard) CheckUser(ctx context.Context, userID string) error {
+	return g.check(ctx, []string{g.userKey(userID)})
+}
+
+func (g *LoginGuard) check(ctx context.Context, keys []string) error {
+	now := g.now()
+	var retryAfter time.Duration
+
+	for _, key := range keys {
+		t, err := g.attemptRepo.GetThrottle(ctx, key)
+		if err != nil {
+			return fmt.Errorf("failed to check login throttle: %w", err)
+		}
+		if t != nil && t.LockedUntil.After(now) {
+			if d := t.LockedUntil.Sub(now); d > retryAfter {
+				retryAfter = d
+			}
+		}
+	}
+
+	if retryAfter > 0 {
+		return &LockedError{RetryAfter: retryAfter}
+	}
+	return nil
+}
+
+// RegisterFailure записывает неудачную попытку входа и при необходимости блокирует вход
+// Возвращает *LockedError, если после этой попытки вход оказался заблокирован
+func (g *LoginGuard) RegisterFailure(ctx context.Context, login, ip string) error {
+	now := g.now()
+	if err := g.record(ctx, login, ip, false, now); err != nil {
+		return err
+	}
+
+	limits := map[string]int{}
+	if login != "" {
+		limits[g.loginKey(login)] = g.cfg.MaxFailuresPerLogin
+	}
+	if ip != "" {
+		limits[g.ipKey(ip)] = g.cfg.MaxFailuresPerIP
+	}
+	return g.registerFailure(ctx, limits, now)
+}
+
+// RegisterUserFailure записывает неверный код 2FA пользователя и при необходимости блокирует проверку кодов
+// Лимит тот же, что для логина. Возвращает *LockedError, если после этой попытки проверка заблокирована
+func (g *LoginGuard) RegisterUserFailure(ctx context.Context, userID string) error {
+	return g.registerFailure(ctx, map[string]int{g.userKey(userID): g.cfg.MaxFailuresPerLogin}, g.now())
+}
+
+// RegisterUserSuccess сбрасывает счетчик неверных кодов 2FA пользователя
+func (g *LoginGuard) RegisterUserSuccess(ctx context.Context, userID string) error {
+	if err := g.attemptRepo.Reset(ctx, g.userKey(userID)); err != nil {
+		return fmt.Errorf("failed to reset user throttle: %w", err)
+	}
+	return nil
+}
+
+// registerFailure увеличивает счетчики ключей и блокирует те, что превысили свой лимит
+func (g *LoginGuard) registerFailure(ctx context.Context, limits map[string]int, now time.Time) error {
+	var retryAfter time.Duration
+	for key, limit := range limits {
+		if limit <= 0 {
+			continue
+		}
+
+		t, err := g.attemptRepo.RegisterFailure(ctx, key, now, g.cfg.FailureWindow)
+		if err != nil {
+			return fmt.Errorf("failed to register login failure: %w", err)
+		}
+		if t.Failures < limit {
+			continue
+		}
+
+		lockout := g.lockoutDuration(t.Failures - limit)
+		if err := g.attemptRepo.Lock(ctx, key, now.Add(lockout)); err != nil {
+			return fmt.Errorf("failed to lock login: %w", err)
+		}
+		if lockout > retryAfter {
+			retryAfter = lockout
+		}
+	}
+
+	if retryAfter > 0 {
+		return &LockedError{RetryAfter: retryAfter}
+	}
+	return nil
+}
+
+// RegisterSuccess записывает успешный вход и сбрасывает счетчик неудач по логину
+// Счетчик по IP не сбрасывается, чтобы один известный пароль не открывал перебор остальных
+func (g *LoginGuard) RegisterSuccess(ctx context.Context, login, ip string) error {
+	if err := g.record(ctx, login, ip, true, g.now()); err != nil {
+		return err
+	}
+	if err := g.attemptRepo.Reset(ctx, g.loginKey(login)); err != nil {
+		return fmt.Errorf("failed to reset login throttle: %w", err)
+	}
+	return nil
+}
+
+// ListAttempts возвращает последние попытки входа для просмотра администратором
+func (g *LoginGuard) ListAttempts(ctx context.Context, login, ip string, limit int) ([]*models.LoginAttempt, error) {
+	return g.attemptRepo.ListAttempts(ctx, login, ip, limit)
+}
+
+// lockoutDuration вычисляет длительность блокировки: BaseLockout * 2^excess, но не больше MaxLockout
+func (g *LoginGuard) lockoutDuration(excess int) time.Duration {
+	lockout := g.cfg.BaseLockout
+	for i := 0; i < excess && lockout < g.cfg.MaxLockout; i++ {
+		lockout *= 2
+	}
+	if lockout > g.cfg.MaxLockout {
+		lockout = g.cfg.MaxLockout
+	}
+	return lockout
+}
+
+func (g *LoginGuard) record(ctx context.Context, login, ip string, success bool, at time.Time) error {
+	err := g.attemptRepo.RecordAttempt(ctx, &models.LoginAttempt{
+		Login:       login,
+		IP:          ip,
+		Success:     success,
+		AttemptedAt: at,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record login attempt: %w", err)
+	}
+	return nil
+}
+
+func (g *LoginGuard) keys(login, ip string) []string {
+	var keys []string
+	if login != "" {
+		keys = append(keys, g.loginKey(login))
+	}
+	if ip != "" {
+		keys = append(keys, g.ipKey(ip))
+	}
+	return keys
+}
+
+func (g *LoginGuard) loginKey(login string) string {
+	return "login:" + login
+}
+
+func (g *LoginGuard) ipKey(ip string) string {
+	return "ip:" + ip
+}
+
+func (g *LoginGuard) userKey(userID string) string {
+	return "user:" + userID
+}
@@ -0,0 +1,147 @@
+package user
+
+import (
+	"context"
+	"cursach/internal/config"
+	"cursach/internal/repository"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// fakeClock - часы, которые двигаются только вручную
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) Now() time.Time          { return c.t }
+func (c *fakeClock) Advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func testLoginGuard(clock *fakeClock) *LoginGuard {
+	cfg := config.LoginLimitConfig{
+		MaxFailuresPerLogin: 3,
+		MaxFailuresPerIP:    10,
+		FailureWindow:       15 * time.Minute,
+		BaseLockout:         time.Minute,
+		MaxLockout:          time.Hour,
+	}
+	return NewLoginGuard(repository.NewMemoryLoginAttemptRepository(), cfg).WithClock(clock.Now)
+}
+
+// failUntilLocked регистрирует неудачные попытки, пока вход не будет заблокирован
+func failUntilLocked(t *testing.T, g *LoginGuard, login, ip string, attempts int) *LockedError {
+	t.Helper()
+	var err error
+	for i := 0; i < attempts; i++ {
+		err = g.RegisterFailure(context.Background(), login, ip)
+	}
+	var locked *LockedError
+	if !errors.As(err, &locked) {
+		t.Fatalf("RegisterFailure() after %d attempts = %v, want *LockedError", attempts, err)
+	}
+	return locked
+}
+
+func TestLoginGuardLockoutExpires(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)}
+	g := testLoginGuard(clock)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if err := g.RegisterFailure(ctx, "alice", "10.0.0.1"); err != nil {
+			t.Fatalf("failure %d: RegisterFailure() = %v, want nil", i+1, err)
+		}
+	}
+	locked := failUntilLocked(t, g, "alice", "10.0.0.1", 1)
+	if locked.RetryAfter != time.Minute {
+		t.Fatalf("RetryAfter = %s, want %s", locked.RetryAfter, time.Minute)
+	}
+
+	clock.Advance(59 * time.Second)
+	var err error
+	if err = g.Check(ctx, "alice", "10.0.0.2"); !errors.Is(err, ErrTooManyAttempts) {
+		t.Fatalf("before expiry: Check() = %v, want %v", err, ErrTooManyAttempts)
+	}
+	var lockedErr *LockedError
+	if errors.As(err, &lockedErr) && lockedErr.RetryAfter != time.Second {
+		t.Fatalf("before expiry: RetryAfter = %s, want 1s", lockedErr.RetryAfter)
+	}
+
+	clock.Advance(time.Second)
+	if err := g.Check(ctx, "alice", "10.0.0.2"); err != nil {
+		t.Fatalf("after expiry: Check() = %v, want nil", err)
+	}
+}
+
+func TestLoginGuardBackoffDoubles(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)}
+	g := testLoginGuard(clock)
+
+	want := []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute}
+	for i, w := range want {
+		attempts := 1
+		if i == 0 {
+			attempts = 3
+		}
+		locked := failUntilLocked(t, g, "bob", "", attempts)
+		if locked.RetryAfter != w {
+			t.Fatalf("lockout %d: RetryAfter = %s, want %s", i+1, locked.RetryAfter, w)
+		}
+		clock.Advance(locked.RetryAfter)
+	}
+}
+
+func TestLoginGuardFailureWindowResets(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)}
+	g := testLoginGuard(clock)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if err := g.RegisterFailure(ctx, "carol", ""); err != nil {
+			t.Fatalf("RegisterFailure() = %v", err)
+		}
+	}
+	// Неудачи старше окна не считаются
+	clock.Advance(16 * time.Minute)
+	if err := g.RegisterFailure(ctx, "carol", ""); err != nil {
+		t.Fatalf("after window: RegisterFailure() = %v, want nil", err)
+	}
+}
+
+func TestLoginGuardSuccessResetsLoginCounter(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)}
+	g := testLoginGuard(clock)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if err := g.RegisterFailure(ctx, "dave", ""); err != nil {
+			t.Fatalf("RegisterFailure() = %v", err)
+		}
+	}
+	if err := g.RegisterSuccess(ctx, "dave", ""); err != nil {
+		t.Fatalf("RegisterSuccess() = %v", err)
+	}
+	if err := g.RegisterFailure(ctx, "dave", ""); err != nil {
+		t.Fatalf("after success: RegisterFailure() = %v, want nil", err)
+	}
+}
+
+// TestLoginGuardSkipsUnknownIP проверяет, что неудачи клиентов с неизвестным адресом (пустой IP)
+// не копятся в общем счетчике по IP и не блокируют вход остальным
+func TestLoginGuardSkipsUnknownIP(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)}
+	g := testLoginGuard(clock)
+	ctx := context.Background()
+
+	// Больше неудач, чем MaxFailuresPerIP, но с разными логинами и не больше MaxFailuresPerLogin на каждый
+	for i := 0; i < 12; i++ {
+		login := "attacker" + strconv.Itoa(i)
+		for j := 0; j < 2; j++ {
+			if err := g.RegisterFailure(ctx, login, ""); err != nil {
+				t.Fatalf("RegisterFailure(%s) = %v, want nil", login, err)
+			}
+		}
+	}
+	if err := g.Check(ctx, "alice", ""); err != nil {
+		t.Errorf("Check() for another login = %v, want nil", err)
+	}
+}
@@ -3,17 +3,31 @@ package user
 import (
 	"context"
 
+	"cursach/internal/pkg/metrics"
 	"cursach/internal/repository"
+	"cursach/internal/usecase/audit"
 )
 
 type Logouter struct {
 	tokenRepo repository.TokenRepository
+	audit     audit.AuditRecorder
 }
 
-func NewLogouter(tokenRepo repository.TokenRepository) *Logouter {
-	return &Logouter{tokenRepo: tokenRepo}
+func NewLogouter(tokenRepo repository.TokenRepository, auditRecorder audit.AuditRecorder) *Logouter {
+	return &Logouter{tokenRepo: tokenRepo, audit: auditRecorder}
 }
 
 func (uc *Logouter) Logout(ctx context.Context, token, userID string) error {
-	return uc.tokenRepo.RevokeToken(ctx, token, userID)
+	if err := uc.tokenRepo.RevokeToken(ctx, token, userID); err != nil {
+		return err
+	}
+	metrics.TokenRevocations.Inc("token")
+
+	audit.TryRecord(ctx, uc.audit, audit.Event{
+		ActorID:    userID,
+		Action:     audit.ActionLogout,
+		TargetType: audit.TargetUser,
+		TargetID:   userID,
+	})
+	return nil
 }
@@ -0,0 +1,226 @@
+package user
+
+import (
+	"context"
+	"cursach/internal/models"
+	"cursach/internal/pkg/auth"
+	"cursach/internal/pkg/metrics"
+	"cursach/internal/repository"
+	"cursach/internal/usecase/audit"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const recoveryCodesCount = 10
+
+var (
+	ErrMFAAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrMFANotEnrolled    = errors.New("two-factor authentication enrollment not started")
+	ErrMFANotEnabled     = errors.New("two-factor authentication is not enabled")
+	ErrInvalidMFACode    = errors.New("invalid two-factor authentication code")
+)
+
+// MFAEnrollment содержит данные для добавления секрета в приложение-аутентификатор
+type MFAEnrollment struct {
+	Secret string `json:"secret"`      // Секрет в base32 для ручного ввода
+	URI    string `json:"otpauth_uri"` // otpauth:// URI для QR-кода
+}
+
+// MFAManager управляет двухфакторной аутентификацией по TOTP (RFC 6238)
+type MFAManager struct {
+	mfaRepo  repository.MFARepository
+	userRepo repository.UserRepository
+	audit    audit.AuditRecorder
+	salt     string
+	issuer   string
+	now      func() time.Time
+}
+
+// NewMFAManager создает новый экземпляр MFAManager
+// issuer отображается в приложении-аутентификаторе как название сервиса
+func NewMFAManager(
+	mfaRepo repository.MFARepository,
+	userRepo repository.UserRepository,
+	auditRecorder audit.AuditRecorder,
+	salt, issuer string,
+) *MFAManager {
+	return &MFAManager{
+		mfaRepo:  mfaRepo,
+		userRepo: userRepo,
+		audit:    auditRecorder,
+		salt:     salt,
+		issuer:   issuer,
+		now:      time.Now,
+	}
+}
+
+// WithClock заменяет источник текущего времени, по которому проверяются коды TOTP (для тестов)
+func (uc *MFAManager) WithClock(now func() time.Time) *MFAManager {
+	uc.now = now
+	return uc
+}
+
+// IsEnabled проверяет, включена ли у пользователя 2FA
+func (uc *MFAManager) IsEnabled(ctx context.Context, userID string) (bool, error) {
+	mfa, err := uc.mfaRepo.GetMFA(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return mfa != nil && mfa.Enabled, nil
+}
+
+// BeginEnrollment генерирует новый секрет TOTP для пользователя
+// 2FA не включается, пока пользователь не подтвердит ее первым кодом
+func (uc *MFAManager) BeginEnrollment(ctx context.Context, userID string) (*MFAEnrollment, error) {
+	user, err := uc.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	enabled, err := uc.IsEnabled(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := uc.mfaRepo.SaveSecret(ctx, userID, secret); err != nil {
+		return nil, err
+	}
+
+	return &MFAEnrollment{
+		Secret: secret,
+		URI:    auth.TOTPURI(uc.issuer, user.Login, secret),
+	}, nil
+}
+
+// ConfirmEnrollment включает 2FA после проверки первого кода
+// Возвращает коды восстановления; в открытом виде они показываются только один раз
+func (uc *MFAManager) ConfirmEnrollment(ctx context.Context, userID, code string) ([]string, error) {
+	mfa, err := uc.mfaRepo.GetMFA(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if mfa == nil {
+		return nil, ErrMFANotEnrolled
+	}
+	if mfa.Enabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	step, ok := auth.ValidateTOTP(mfa.Secret, code, uc.now())
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	codes, err := auth.GenerateRecoveryCodes(recoveryCodesCount)
+	if err != nil {
+		return nil, err
+	}
+	hashes := make([]string, 0, len(codes))
+	for _, c := range codes {
+		hash, err := auth.HashPassword(auth.NormalizeRecoveryCode(c), uc.salt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash recovery code: %w", err)
+		}
+		hashes = append(hashes, hash)
+	}
+
+	if err := uc.mfaRepo.Enable(ctx, userID, step, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Disable выключает 2FA; требует действующий код TOTP или код восстановления
+func (uc *MFAManager) Disable(ctx context.Context, userID, code string) error {
+	if err := uc.Verify(ctx, userID, code); err != nil {
+		return err
+	}
+	return uc.mfaRepo.Disable(ctx, userID)
+}
+
+// Verify проверяет код TOTP или одноразовый код восстановления
+// Каждый код принимается только один раз
+func (uc *MFAManager) Verify(ctx context.Context, userID, code string) error {
+	mfa, err := uc.mfaRepo.GetMFA(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if mfa == nil || !mfa.Enabled {
+		return ErrMFANotEnabled
+	}
+
+	if step, ok := auth.ValidateTOTP(mfa.Secret, code, uc.now()); ok {
+		used, err := uc.mfaRepo.UseStep(ctx, userID, step)
+		if err != nil {
+			return err
+		}
+		if !used {
+			return ErrInvalidMFACode
+		}
+		return nil
+	}
+
+	normalized := auth.NormalizeRecoveryCode(code)
+	if normalized == "" {
+		return ErrInvalidMFACode
+	}
+	hash, err := auth.HashPassword(normalized, uc.salt)
+	if err != nil {
+		return fmt.Errorf("failed to hash recovery code: %w", err)
+	}
+	used, err := uc.mfaRepo.UseRecoveryCode(ctx, userID, hash)
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidMFACode
+	}
+	return nil
+}
+
+// CompleteLogin завершает двухэтапный вход: проверяет код и возвращает пользователя для выдачи токена
+func (uc *MFAManager) CompleteLogin(ctx context.Context, userID, code string) (*models.User, error) {
+	if err := uc.Verify(ctx, userID, code); err != nil {
+		if errors.Is(err, ErrInvalidMFACode) {
+			metrics.AuthFailures.Inc("invalid_mfa_code")
+			audit.TryRecord(ctx, uc.audit, audit.Event{
+				Action:     audit.ActionMFAFailed,
+				TargetType: audit.TargetUser,
+				TargetID:   userID,
+			})
+		}
+		return nil, err
+	}
+
+	user, err := uc.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	if user.BannedAt.Valid {
+		return nil, ErrUserBanned
+	}
+
+	// Вход с 2FA считается состоявшимся только после проверки кода
+	audit.TryRecord(ctx, uc.audit, audit.Event{
+		ActorID:    userID,
+		Action:     audit.ActionLogin,
+		TargetType: audit.TargetUser,
+		TargetID:   userID,
+		Details:    map[string]interface{}{"mfa": true},
+	})
+	return user, nil
+}
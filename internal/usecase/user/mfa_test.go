@@ -0,0 +1,188 @@
+package user
+
+import (
+	"context"
+	"cursach/internal/models"
+	"cursach/internal/pkg/auth"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeMFARepository хранит настройки 2FA в памяти с той же семантикой UseStep, что и Postgres-реализация
+type fakeMFARepository struct {
+	mu       sync.Mutex
+	mfa      map[string]*models.UserMFA
+	recovery map[string]map[string]bool // userID -> хеш -> использован
+}
+
+func newFakeMFARepository() *fakeMFARepository {
+	return &fakeMFARepository{mfa: map[string]*models.UserMFA{}, recovery: map[string]map[string]bool{}}
+}
+
+func (r *fakeMFARepository) GetMFA(_ context.Context, userID string) (*models.UserMFA, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	m, ok := r.mfa[userID]
+	if !ok {
+		return nil, nil
+	}
+	result := *m
+	return &result, nil
+}
+
+func (r *fakeMFARepository) SaveSecret(_ context.Context, userID, secret string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.mfa[userID] = &models.UserMFA{UserID: userID, Secret: secret}
+	return nil
+}
+
+func (r *fakeMFARepository) Enable(_ context.Context, userID string, step int64, hashes []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	m := r.mfa[userID]
+	m.Enabled = true
+	m.LastUsedStep = step
+	r.recovery[userID] = map[string]bool{}
+	for _, h := range hashes {
+		r.recovery[userID][h] = false
+	}
+	return nil
+}
+
+func (r *fakeMFARepository) Disable(_ context.Context, userID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.mfa, userID)
+	delete(r.recovery, userID)
+	return nil
+}
+
+func (r *fakeMFARepository) UseStep(_ context.Context, userID string, step int64) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	m := r.mfa[userID]
+	if m == nil || step <= m.LastUsedStep {
+		return false, nil
+	}
+	m.LastUsedStep = step
+	return true, nil
+}
+
+func (r *fakeMFARepository) UseRecoveryCode(_ context.Context, userID, hash string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	used, ok := r.recovery[userID][hash]
+	if !ok || used {
+		return false, nil
+	}
+	r.recovery[userID][hash] = true
+	return true, nil
+}
+
+const testSecret = "JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"
+
+// enabledMFA возвращает MFAManager с включенной 2FA у пользователя u1, подтвержденной кодом шага enabledAt
+func enabledMFA(t *testing.T, clock *fakeClock) (*MFAManager, *fakeMFARepository) {
+	t.Helper()
+	repo := newFakeMFARepository()
+	uc := NewMFAManager(repo, nil, nil, "salt", "test").WithClock(clock.Now)
+
+	if err := repo.SaveSecret(context.Background(), "u1", testSecret); err != nil {
+		t.Fatal(err)
+	}
+	code := totpCode(t, clock.Now())
+	if _, err := uc.ConfirmEnrollment(context.Background(), "u1", code); err != nil {
+		t.Fatalf("ConfirmEnrollment: %v", err)
+	}
+	return uc, repo
+}
+
+func totpCode(t *testing.T, at time.Time) string {
+	t.Helper()
+	code, err := auth.TOTPCode(testSecret, auth.TOTPStep(at))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code
+}
+
+func TestMFAVerifyClockSkew(t *testing.T) {
+	tests := []struct {
+		name   string
+		offset time.Duration // Смещение часов устройства относительно сервера
+		want   error
+	}{
+		{"same step", 0, nil},
+		{"one step behind", -auth.TOTPPeriod, nil},
+		{"one step ahead", auth.TOTPPeriod, nil},
+		{"two steps behind", -2 * auth.TOTPPeriod, ErrInvalidMFACode},
+		{"two steps ahead", 2 * auth.TOTPPeriod, ErrInvalidMFACode},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Подтверждение сделано заранее, чтобы проверяемый шаг был новее использованного
+			clock := &fakeClock{t: time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)}
+			uc, _ := enabledMFA(t, clock)
+			clock.Advance(10 * auth.TOTPPeriod)
+
+			code := totpCode(t, clock.Now().Add(tt.offset))
+			if err := uc.Verify(context.Background(), "u1", code); !errors.Is(err, tt.want) {
+				t.Fatalf("Verify() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestMFAVerifyRejectsReplay(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)}
+	uc, _ := enabledMFA(t, clock)
+	ctx := context.Background()
+
+	// Код подтверждения нельзя повторно использовать для входа
+	if err := uc.Verify(ctx, "u1", totpCode(t, clock.Now())); !errors.Is(err, ErrInvalidMFACode) {
+		t.Fatalf("enrollment code reused: Verify() = %v, want %v", err, ErrInvalidMFACode)
+	}
+
+	clock.Advance(auth.TOTPPeriod)
+	code := totpCode(t, clock.Now())
+	if err := uc.Verify(ctx, "u1", code); err != nil {
+		t.Fatalf("first use: Verify() = %v", err)
+	}
+	if err := uc.Verify(ctx, "u1", code); !errors.Is(err, ErrInvalidMFACode) {
+		t.Fatalf("replay: Verify() = %v, want %v", err, ErrInvalidMFACode)
+	}
+
+	// Код предыдущего шага все еще в окне расхождения, но шаг уже использован
+	clock.Advance(auth.TOTPPeriod)
+	if err := uc.Verify(ctx, "u1", code); !errors.Is(err, ErrInvalidMFACode) {
+		t.Fatalf("older step: Verify() = %v, want %v", err, ErrInvalidMFACode)
+	}
+}
+
+func TestMFARecoveryCodeSingleUse(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)}
+	repo := newFakeMFARepository()
+	uc := NewMFAManager(repo, nil, nil, "salt", "test").WithClock(clock.Now)
+	ctx := context.Background()
+
+	if err := repo.SaveSecret(ctx, "u1", testSecret); err != nil {
+		t.Fatal(err)
+	}
+	codes, err := uc.ConfirmEnrollment(ctx, "u1", totpCode(t, clock.Now()))
+	if err != nil {
+		t.Fatalf("ConfirmEnrollment: %v", err)
+	}
+	if len(codes) != recoveryCodesCount {
+		t.Fatalf("got %d recovery codes, want %d", len(codes), recoveryCodesCount)
+	}
+
+	if err := uc.Verify(ctx, "u1", codes[0]); err != nil {
+		t.Fatalf("first use: Verify() = %v", err)
+	}
+	if err := uc.Verify(ctx, "u1", codes[0]); !errors.Is(err, ErrInvalidMFACode) {
+		t.Fatalf("replay: Verify() = %v, want %v", err, ErrInvalidMFACode)
+	}
+}
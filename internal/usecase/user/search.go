@@ -3,6 +3,7 @@ package user
 import (
 	"context"
 	"cursach/internal/models"
+	"cursach/internal/pkg/tracing"
 	"cursach/internal/repository"
 )
 
@@ -14,7 +15,10 @@ func NewUserSearcher(userRepo repository.UserRepository) *UserSearcher {
 	return &UserSearcher{userRepo: userRepo}
 }
 
-func (uc *UserSearcher) Execute(ctx context.Context, login string) ([]*models.User, error) {
+func (uc *UserSearcher) Execute(ctx context.Context, login string) (_ []*models.User, err error) {
+	ctx, span := tracing.Start(ctx, "user.UserSearcher.Execute")
+	defer func() { tracing.End(span, err) }()
+
 	users, err := uc.userRepo.SearchUsersByLogin(ctx, login)
 	if err != nil {
 		return nil, err
@@ -5,6 +5,7 @@ import (
 	"errors"
 
 	"cursach/internal/repository"
+	"cursach/internal/usecase/audit"
 )
 
 var (
@@ -13,10 +14,11 @@ var (
 
 type LoginUpdater struct {
 	userRepo repository.UserRepository
+	audit    audit.AuditRecorder
 }
 
-func NewLoginUpdater(userRepo repository.UserRepository) *LoginUpdater {
-	return &LoginUpdater{userRepo: userRepo}
+func NewLoginUpdater(userRepo repository.UserRepository, auditRecorder audit.AuditRecorder) *LoginUpdater {
+	return &LoginUpdater{userRepo: userRepo, audit: auditRecorder}
 }
 
 func (uc *LoginUpdater) UpdateLogin(ctx context.Context, userID, newLogin string) error {
@@ -29,6 +31,26 @@ func (uc *LoginUpdater) UpdateLogin(ctx context.Context, userID, newLogin string
 		return ErrLoginAlreadyExists
 	}
 
+	// Старый логин нужен только для журнала аудита
+	var oldLogin string
+	if current, err := uc.userRepo.GetUserByID(ctx, userID); err == nil && current != nil {
+		oldLogin = current.Login
+	}
+
 	// Обновляем логин
-	return uc.userRepo.UpdateLogin(ctx, userID, newLogin)
+	if err := uc.userRepo.UpdateLogin(ctx, userID, newLogin); err != nil {
+		return err
+	}
+
+	audit.TryRecord(ctx, uc.audit, audit.Event{
+		ActorID:    userID,
+		Action:     audit.ActionLoginChanged,
+		TargetType: audit.TargetUser,
+		TargetID:   userID,
+		Details: map[string]interface{}{
+			"old_login": oldLogin,
+			"new_login": newLogin,
+		},
+	})
+	return nil
 }
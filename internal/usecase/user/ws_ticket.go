@@ -0,0 +1,84 @@
+package user
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"cursach/internal/models"
+	"cursach/internal/repository"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const wsTicketSize = 32
+
+var ErrInvalidWSTicket = errors.New("invalid or expired websocket ticket")
+
+// WSTicketManager выдает и погашает одноразовые билеты для подключения к WebSocket
+// Браузер не может передать заголовок Authorization при открытии WebSocket, поэтому вместо JWT
+// в строке запроса передается билет: он живет секунды и принимается один раз
+type WSTicketManager struct {
+	ticketRepo repository.WSTicketRepository
+	tokenRepo  repository.TokenRepository
+	ttl        time.Duration
+	now        func() time.Time
+}
+
+// NewWSTicketManager создает новый экземпляр WSTicketManager
+func NewWSTicketManager(ticketRepo repository.WSTicketRepository, tokenRepo repository.TokenRepository, ttl time.Duration) *WSTicketManager {
+	return &WSTicketManager{ticketRepo: ticketRepo, tokenRepo: tokenRepo, ttl: ttl, now: time.Now}
+}
+
+// Issue выдает билет пользователю; возвращает билет и время его истечения
+func (uc *WSTicketManager) Issue(ctx context.Context, userID string) (string, time.Time, error) {
+	buf := make([]byte, wsTicketSize)
+	if _, err := rand.Read(buf); err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to generate ws ticket: %w", err)
+	}
+	ticket := base64.RawURLEncoding.EncodeToString(buf)
+
+	now := uc.now()
+	record := &models.WSTicket{
+		Hash:      hashWSTicket(ticket),
+		UserID:    userID,
+		IssuedAt:  now,
+		ExpiresAt: now.Add(uc.ttl),
+	}
+	if err := uc.ticketRepo.CreateTicket(ctx, record); err != nil {
+		return "", time.Time{}, err
+	}
+	return ticket, record.ExpiresAt, nil
+}
+
+// Redeem погашает билет и возвращает ID пользователя
+// Билет не принимается, если после его выдачи токены пользователя были отозваны (блокировка, принудительный выход)
+func (uc *WSTicketManager) Redeem(ctx context.Context, ticket string) (string, error) {
+	if ticket == "" {
+		return "", ErrInvalidWSTicket
+	}
+
+	record, err := uc.ticketRepo.ConsumeTicket(ctx, hashWSTicket(ticket), uc.now())
+	if errors.Is(err, repository.ErrTicketNotFound) {
+		return "", ErrInvalidWSTicket
+	}
+	if err != nil {
+		return "", err
+	}
+
+	revoked, err := uc.tokenRepo.IsUserTokenRevoked(ctx, record.UserID, record.IssuedAt)
+	if err != nil {
+		return "", err
+	}
+	if revoked {
+		return "", ErrInvalidWSTicket
+	}
+	return record.UserID, nil
+}
+
+func hashWSTicket(ticket string) string {
+	sum := sha256.Sum256([]byte(ticket))
+	return hex.EncodeToString(sum[:])
+}